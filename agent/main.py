@@ -3,7 +3,7 @@ import time
 import json
 from dotenv import load_dotenv
 from utils.database import get_db_manager
-from utils.mq_client import get_mq_client
+from utils.mq_client import get_mq_client, AI_REPLY_SAVED_QUEUE
 from utils.role_manager import get_role_manager
 from postcard_flow import process_mq_message_with_flow
 import logging
@@ -47,6 +47,12 @@ class PocketFlowMessageProcessor:
             
             if result["success"]:
                 logger.info(f"✅ 明信片生成成功 - 会话: {conversation_id}")
+                # 通知后端回复已保存，由后端通知用户并推送 Webhook
+                self.mq_client.publish_message({
+                    "postcard_id": result.get("postcard_id"),
+                    "reply_to_id": message.get("user_postcard_id"),
+                    "conversation_id": conversation_id
+                }, queue_name=AI_REPLY_SAVED_QUEUE)
             else:
                 logger.error(f"❌ 明信片生成失败 - 会话: {conversation_id}")
                 if "error" in result:
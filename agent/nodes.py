@@ -41,7 +41,8 @@ class MQPostcardGenerationNode(Node):
             "user_message": user_message,
             "character_id": character_id,
             "conversation_id": conversation_id,
-            "user_id": user_id
+            "user_id": user_id,
+            "reply_context": message_data
         }
     
     def exec(self, message_data):
@@ -64,7 +65,8 @@ class MQPostcardGenerationNode(Node):
                 prompt=user_message,
                 character_id=character_id,
                 temperature=0.7,
-                max_tokens=1000
+                max_tokens=1000,
+                reply_context=message_data["reply_context"]
             )
             
             logger.info(f"明信片生成成功 - 角色: {character_info.get('name', character_id)}")
@@ -72,7 +74,8 @@ class MQPostcardGenerationNode(Node):
                 "content": postcard_content,
                 "conversation_id": message_data["conversation_id"],
                 "user_id": message_data["user_id"],
-                "character_id": character_id
+                "character_id": character_id,
+                "message": message_data["reply_context"]
             }
             
         except Exception as e:
@@ -88,7 +91,8 @@ class MQPostcardGenerationNode(Node):
             "content": "抱歉，我暂时无法生成明信片。请稍后再试。",
             "conversation_id": message_data["conversation_id"] if message_data else "unknown",
             "user_id": message_data["user_id"] if message_data else 0,
-            "character_id": message_data["character_id"] if message_data else None
+            "character_id": message_data["character_id"] if message_data else None,
+            "message": message_data["reply_context"] if message_data else {}
         }
     
     def post(self, shared, prep_res, exec_res):
@@ -127,21 +131,12 @@ class MQPostcardSaveNode(Node):
             return False
         
         try:
-            content = postcard_data["content"]
             conversation_id = postcard_data["conversation_id"]
-            user_id = postcard_data["user_id"]
-            character_id = postcard_data["character_id"]
             
-            # 保存到数据库
-            query = """
-            INSERT INTO postcards 
-            (conversation_id, user_id, character_id, type, content, status, created_at, updated_at)
-            VALUES (%s, %s, %s, 'ai', %s, 'sent', NOW(), NOW())
-            """
-            
-            self.db.execute_update(query, (conversation_id, user_id, character_id, content))
-            logger.info(f"明信片已保存到数据库 - 会话: {conversation_id}")
-            return True
+            # 保存到数据库，回复关联到被回复的用户明信片
+            postcard_id = self.db.save_ai_reply(postcard_data["message"], postcard_data["content"])
+            logger.info(f"明信片已保存到数据库 - 会话: {conversation_id}, 明信片: {postcard_id}")
+            return postcard_id
             
         except Exception as e:
             logger.error(f"保存明信片到数据库失败: {e}")
@@ -150,12 +145,13 @@ class MQPostcardSaveNode(Node):
     def exec_fallback(self, postcard_data, exc):
         """处理保存失败的情况"""
         logger.warning(f"明信片保存失败: {exc}")
-        return False
+        return None
     
     def post(self, shared, prep_res, exec_res):
         """后处理：更新保存状态"""
         if exec_res:
             shared["postcard_saved"] = True
+            shared["postcard_data"]["postcard_id"] = exec_res
             logger.info("明信片保存成功")
         else:
             shared["postcard_saved"] = False
@@ -322,16 +318,18 @@ class MQPostcardVoiceUpdateNode(Node):
             return None
         
         conversation_id = postcard_data.get("conversation_id")
+        postcard_id = postcard_data.get("postcard_id")
         voice_url = voice_data.get("voice_url")
         
-        if not conversation_id:
-            logger.warning("会话ID为空")
+        if not postcard_id:
+            logger.warning("明信片ID为空")
             return None
         
-        logger.info(f"准备更新明信片语音 - 会话: {conversation_id}")
+        logger.info(f"准备更新明信片语音 - 会话: {conversation_id}, 明信片: {postcard_id}")
         
         return {
             "conversation_id": conversation_id,
+            "postcard_id": postcard_id,
             "voice_url": voice_url
         }
     
@@ -341,17 +339,18 @@ class MQPostcardVoiceUpdateNode(Node):
             return False
         
         conversation_id = update_data["conversation_id"]
+        postcard_id = update_data["postcard_id"]
         voice_url = update_data["voice_url"]
         
         try:
-            # 更新数据库中的明信片记录，添加语音URL
+            # 更新刚保存的AI回复明信片，添加语音URL
             query = """
             UPDATE postcards 
             SET voice_url = %s, updated_at = NOW()
-            WHERE conversation_id = %s
+            WHERE id = %s
             """
             
-            affected_rows = self.db.execute_update(query, (voice_url, conversation_id))
+            affected_rows = self.db.execute_update(query, (voice_url, postcard_id))
             
             if affected_rows > 0:
                 logger.info(f"明信片语音更新成功 - 会话: {conversation_id}")
@@ -85,6 +85,7 @@ def process_mq_message_with_flow(mq_message):
             "user_id": mq_message.get("user_id"),
             "character_id": mq_message.get("character_id"),
             "postcard_content": shared.get("postcard_data", {}).get("content") if shared.get("postcard_data") else None,
+            "postcard_id": shared.get("postcard_data", {}).get("postcard_id") if shared.get("postcard_data") else None,
             "voice_generated": shared.get("voice_data") is not None,
             "voice_updated": shared.get("voice_updated", False),
             "voice_url": shared.get("voice_data", {}).get("voice_url") if shared.get("voice_data") else None
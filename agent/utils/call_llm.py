@@ -4,7 +4,7 @@ from .prompts import BASE_SYSTEM_PROMPT
 from .role_manager import get_role_manager
 
 # Learn more about calling the LLM: https://the-pocket.github.io/PocketFlow/utility_function/llm.html
-def call_llm(prompt, character_id=None, temperature=0.7, max_tokens=1000):
+def call_llm(prompt, character_id=None, temperature=0.7, max_tokens=1000, reply_context=None):
     """
     调用LLM进行对话，支持角色扮演
     
@@ -13,6 +13,7 @@ def call_llm(prompt, character_id=None, temperature=0.7, max_tokens=1000):
         character_id: 角色ID，从数据库获取角色信息
         temperature: 生成温度，控制随机性
         max_tokens: 最大生成token数
+        reply_context: 后端随MQ消息提供的回复上下文（用户人设、示例对话等）
     
     Returns:
         AI回复内容
@@ -28,12 +29,18 @@ def call_llm(prompt, character_id=None, temperature=0.7, max_tokens=1000):
     # 添加系统提示词（优先使用数据库角色信息）
     if character_id:
         role_manager = get_role_manager()
-        system_prompt = role_manager.get_character_role_prompt(character_id)
+        system_prompt = role_manager.get_character_role_prompt(character_id, reply_context)
     else:
         system_prompt = BASE_SYSTEM_PROMPT
     
     messages.append({"role": "system", "content": system_prompt})
     
+    # 添加示例对话（few-shot），帮助保持角色的说话风格
+    if reply_context:
+        for example in reply_context.get('examples') or []:
+            messages.append({"role": "user", "content": example.get('user', '')})
+            messages.append({"role": "assistant", "content": example.get('character', '')})
+    
     # 添加用户消息
     messages.append({"role": "user", "content": prompt})
     
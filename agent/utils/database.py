@@ -1,4 +1,5 @@
 import mysql.connector
+import json
 import os
 from dotenv import load_dotenv
 import logging
@@ -67,6 +68,54 @@ class DatabaseManager:
             self.connection.rollback()
             raise
     
+    def save_ai_reply(self, message_data, content):
+        """
+        保存AI回复明信片，与后端保存回复的方式保持一致：
+        回复关联到被回复的用户明信片（reply_to_id），成为选定的回复，同一明信片的其他回复变为备选
+        
+        Args:
+            message_data: 后端发送的MQ消息，user_postcard_id 为被回复的用户明信片，
+                recalled_memories 为生成回复时回忆起的明信片
+            content: 回复内容
+        
+        Returns:
+            新明信片的ID
+        """
+        reply_to_id = message_data.get('user_postcard_id') or None
+        recalled_memories = message_data.get('recalled_memories')
+        
+        self.connection.start_transaction()
+        try:
+            cursor = self.connection.cursor()
+            cursor.execute(
+                """
+                INSERT INTO postcards
+                (conversation_id, user_id, character_id, type, reply_to_id, is_canonical, content, status, recalled_memories, created_at, updated_at)
+                VALUES (%s, %s, %s, 'ai', %s, 1, %s, 'sent', %s, NOW(3), NOW(3))
+                """,
+                (
+                    message_data['conversation_id'],
+                    message_data['user_id'],
+                    message_data['character_id'],
+                    reply_to_id,
+                    content,
+                    json.dumps(recalled_memories) if recalled_memories else None,
+                )
+            )
+            postcard_id = cursor.lastrowid
+            if reply_to_id:
+                cursor.execute(
+                    "UPDATE postcards SET is_canonical = 0 WHERE reply_to_id = %s AND id <> %s",
+                    (reply_to_id, postcard_id)
+                )
+            cursor.close()
+            self.connection.commit()
+            return postcard_id
+        except mysql.connector.Error as e:
+            logger.error(f"❌ 保存AI回复失败: {e}")
+            self.connection.rollback()
+            raise
+    
     def get_user_by_id(self, user_id):
         """根据用户ID获取用户信息"""
         query = "SELECT * FROM users WHERE id = %s AND deleted_at IS NULL"
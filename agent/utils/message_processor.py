@@ -29,6 +29,9 @@ class MessageProcessor:
                 - user_id: 用户ID
                 - character_id: 角色ID
                 - user_message: 用户消息
+                - user_role_name/user_role_desc: 用户在对话中使用的人设（可选）
+                - lore: 由关键词触发的世界设定（可选）
+                - memories: 回忆起的过往明信片（可选）
         
         Returns:
             处理结果（成功/失败）
@@ -59,11 +62,12 @@ class MessageProcessor:
                 prompt=user_message,
                 character_id=character_id,
                 temperature=0.7,
-                max_tokens=1000
+                max_tokens=1000,
+                reply_context=message_data
             )
             
             # 直接将回复保存到数据库（Postcard表）
-            success = self._save_postcard_to_db(conversation_id, user_id, character_id, ai_reply)
+            success = self._save_postcard_to_db(message_data, ai_reply)
             if success:
                 logger.info(f"AI回复已保存到数据库 - 会话: {conversation_id}, 角色: {character_info.get('name')}")
             else:
@@ -122,30 +126,21 @@ class MessageProcessor:
         # 这里可以添加用户活动处理逻辑
         return True
     
-    def _save_postcard_to_db(self, conversation_id, user_id, character_id, content):
+    def _save_postcard_to_db(self, message_data, content):
         """
         保存明信片到数据库
         
         Args:
-            conversation_id: 会话ID
-            user_id: 用户ID
-            character_id: 角色ID
+            message_data: 后端发送的MQ消息
             content: 明信片内容
         
         Returns:
             保存结果（成功/失败）
         """
         try:
-            # 使用数据库的NOW()函数，它会使用数据库服务器的时区
-            # 根据Go模型，type字段为枚举('user','ai')，AI回复应该使用'ai'
-            query = """
-            INSERT INTO postcards 
-            (conversation_id, user_id, character_id, type, content, status, created_at, updated_at)
-            VALUES (%s, %s, %s, 'ai', %s, 'sent', NOW(), NOW())
-            """
-            
-            self.db.execute_update(query, (conversation_id, user_id, character_id, content))
-            logger.debug(f"明信片已保存到数据库 - 会话: {conversation_id}, 类型: ai")
+            # 回复关联到被回复的用户明信片，并成为选定的回复
+            postcard_id = self.db.save_ai_reply(message_data, content)
+            logger.debug(f"明信片已保存到数据库 - 会话: {message_data['conversation_id']}, 明信片: {postcard_id}")
             return True
             
         except Exception as e:
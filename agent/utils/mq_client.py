@@ -12,6 +12,9 @@ load_dotenv()
 logging.basicConfig(level=os.getenv('LOG_LEVEL', 'INFO'))
 logger = logging.getLogger(__name__)
 
+# AI 回复保存后发布事件的队列，后端消费后通知用户并推送 Webhook
+AI_REPLY_SAVED_QUEUE = 'ai_reply_saved_queue'
+
 class MQClient:
     """RabbitMQ消息队列客户端"""
     
@@ -45,6 +48,7 @@ class MQClient:
             # 声明队列
             queue_name = os.getenv('RABBITMQ_QUEUE', 'ai_reply_queue')
             self.channel.queue_declare(queue=queue_name, durable=True)
+            self.channel.queue_declare(queue=AI_REPLY_SAVED_QUEUE, durable=True)
             
             logger.info("✅ 成功连接到RabbitMQ消息队列")
             
@@ -14,12 +14,13 @@ class RoleManager:
     def __init__(self):
         self.db = get_db_manager()
     
-    def get_character_role_prompt(self, character_id):
+    def get_character_role_prompt(self, character_id, reply_context=None):
         """
         根据角色ID获取角色扮演提示词
         
         Args:
             character_id: 角色ID
+            reply_context: 后端随MQ消息提供的回复上下文（用户人设等），可为空
             
         Returns:
             角色扮演系统提示词
@@ -32,34 +33,45 @@ class RoleManager:
                 return BASE_SYSTEM_PROMPT
             
             # 构建角色特定的系统提示词
-            role_prompt = self._build_role_prompt(character)
+            role_prompt = self._build_role_prompt(character, reply_context or {})
             return role_prompt
             
         except Exception as e:
             logger.error(f"获取角色提示词失败: {e}")
             return BASE_SYSTEM_PROMPT
     
-    def _build_role_prompt(self, character):
+    def _build_role_prompt(self, character, reply_context=None):
         """
         根据角色信息构建系统提示词
         
         Args:
             character: 角色数据库记录
+            reply_context: 回复上下文，包含 user_role_name/user_role_desc 时优先于角色默认的用户角色设定，
+                lore 为需要注入的世界设定，memories 为回忆起的过往明信片
             
         Returns:
             角色扮演系统提示词
         """
+        reply_context = reply_context or {}
+
         # 基础角色信息（根据实际的GORM模型字段）
         name = character.get('name', 'AI助手')
         description = character.get('description', '一个友好的AI助手')
-        user_role_name = character.get('user_role_name', '助手')
-        user_role_desc = character.get('user_role_desc', '帮助用户创建明信片')
+
+        # 用户在该对话中使用的人设由后端解析后放在消息中，没有时使用角色默认的用户角色设定
+        if reply_context.get('user_role_name'):
+            user_role_name = reply_context['user_role_name']
+            user_role_desc = reply_context.get('user_role_desc', '')
+        else:
+            user_role_name = character.get('user_role_name', '助手')
+            user_role_desc = character.get('user_role_desc', '帮助用户创建明信片')
+        user_role = f"{user_role_name} - {user_role_desc}" if user_role_desc else user_role_name
         
         # 构建角色提示词（明信片格式）
         role_prompt = f"""你正在扮演角色：{name}
 
 角色描述：{description}
-用户角色：{user_role_name} - {user_role_desc}
+用户角色：{user_role}
 
 你的核心任务：
 1. 以明信片的形式进行角色扮演对话，回复要像一封真实的明信片,但是不要使用抬头和署名
@@ -94,6 +106,20 @@ class RoleManager:
 - 绝对不允许使用多余的符号,表情符号或非正式语言
 请记住：你的回复应该像一封充满温度的明信片，让用户感受到被理解和关怀。"""
         
+        # 由关键词触发的世界设定（后端已按角色的 token 预算筛选）
+        lore = reply_context.get('lore') or []
+        if lore:
+            role_prompt += "\n\n以下是与当前对话相关的世界设定，请在回复时保持一致："
+            for entry in lore:
+                role_prompt += f"\n{entry}"
+        
+        # 回忆起的过往明信片（后端按与当前来信的相似度选出）
+        memories = reply_context.get('memories') or []
+        if memories:
+            role_prompt += "\n\n你记得TA以前写给你的这些明信片，可以在合适的时候自然地提起："
+            for memory in memories:
+                role_prompt += f"\n- {memory}"
+        
         return role_prompt
     
     def get_character_info(self, character_id):
@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"time"
 )
 
 type Config struct {
@@ -35,6 +37,8 @@ type Config struct {
 	MinIOBucketName    string
 	MinIOUseSSL        bool
 	MinIOPublicBaseURL string
+	MinIOPresignURL    string // 生成直传地址使用的存储地址（如 https://files.example.com），留空时使用 MinIOEndpoint
+	MinIORegion        string
 
 	// JWT 配置
 	JWTSecret string
@@ -42,6 +46,42 @@ type Config struct {
 	// AI 服务配置
 	OpenAIAPIKey  string
 	OpenAIBaseURL string
+
+	// 记忆检索配置
+	EmbeddingProvider string // local / openai
+	EmbeddingModel    string
+	MemoryRecallTopK  int
+
+	// 情绪分析配置
+	EmotionClassifier string // lexicon / llm
+
+	// 明信片渲染配置
+	RenderFontPath string // TTF/OTF/TTC 字体文件，渲染中文需要配置 CJK 字体
+	RenderFontDir  string // 模板可选字体所在目录，模板的 fonts 填写该目录下的文件名
+
+	// 分享链接配置
+	PublicBaseURL string // 分享链接对外访问的站点地址，留空时根据请求的 Host 生成
+
+	// Webhook 配置
+	WebhookTimeout              time.Duration // 单次推送的超时时间
+	WebhookMaxAttempts          int           // 最多推送次数，超过后标记为失败
+	WebhookAllowPrivateNetworks bool          // 是否允许推送到内网和本机地址，仅用于开发环境
+
+	// 邮件配置，未设置 SMTP_HOST 时不发送邮件
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+
+	// 后台任务配置
+	PopularityJobInterval time.Duration
+	ReminderJobInterval   time.Duration // 检查到期提醒的间隔
+	CheckInJobInterval    time.Duration // 检查角色主动问候的间隔
+	WebhookJobInterval    time.Duration // 重试到期 Webhook 推送的间隔
+	UploadGCInterval      time.Duration // 清理无引用上传文件的间隔
+	UploadGCGracePeriod   time.Duration // 上传后超过该时间仍无引用的文件才会被清理，至少 1 小时，保证直传文件在确认前不会被清理
+	UploadGCDryRun        bool          // 只统计无引用文件，不删除
 }
 
 func Load() *Config {
@@ -71,11 +111,42 @@ func Load() *Config {
 		MinIOBucketName:    getEnv("MINIO_BUCKET_NAME", "memory-postcard"),
 		MinIOUseSSL:        getEnv("MINIO_USE_SSL", "false") == "true",
 		MinIOPublicBaseURL: getEnv("MINIO_PUBLIC_BASE_URL", ""),
+		MinIOPresignURL:    getEnv("MINIO_PRESIGN_URL", ""),
+		MinIORegion:        getEnv("MINIO_REGION", "us-east-1"),
 
 		JWTSecret: getEnv("JWT_SECRET", "your-secret-key"),
 
 		OpenAIAPIKey:  getEnv("OPENAI_API_KEY", ""),
 		OpenAIBaseURL: getEnv("OPENAI_BASE_URL", "https://api.openai.com/v1"),
+
+		EmbeddingProvider: getEnv("EMBEDDING_PROVIDER", "local"),
+		EmbeddingModel:    getEnv("EMBEDDING_MODEL", "text-embedding-3-small"),
+		MemoryRecallTopK:  getEnvInt("MEMORY_RECALL_TOP_K", 3),
+
+		EmotionClassifier: getEnv("EMOTION_CLASSIFIER", "lexicon"),
+
+		RenderFontPath: getEnv("RENDER_FONT_PATH", ""),
+		RenderFontDir:  getEnv("RENDER_FONT_DIR", ""),
+
+		PublicBaseURL: getEnv("PUBLIC_BASE_URL", ""),
+
+		WebhookTimeout:              getEnvPositiveDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		WebhookMaxAttempts:          getEnvInt("WEBHOOK_MAX_ATTEMPTS", 8),
+		WebhookAllowPrivateNetworks: getEnv("WEBHOOK_ALLOW_PRIVATE_NETWORKS", "false") == "true",
+
+		SMTPHost:     getEnv("SMTP_HOST", ""),
+		SMTPPort:     getEnv("SMTP_PORT", "587"),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:     getEnv("SMTP_FROM", ""),
+
+		PopularityJobInterval: getEnvPositiveDuration("POPULARITY_JOB_INTERVAL", time.Hour),
+		ReminderJobInterval:   getEnvPositiveDuration("REMINDER_JOB_INTERVAL", time.Minute),
+		CheckInJobInterval:    getEnvPositiveDuration("CHECK_IN_JOB_INTERVAL", time.Hour),
+		WebhookJobInterval:    getEnvPositiveDuration("WEBHOOK_JOB_INTERVAL", 30*time.Second),
+		UploadGCInterval:      getEnvPositiveDuration("UPLOAD_GC_INTERVAL", 6*time.Hour),
+		UploadGCGracePeriod:   getEnvMinDuration("UPLOAD_GC_GRACE_PERIOD", 24*time.Hour, time.Hour),
+		UploadGCDryRun:        getEnv("UPLOAD_GC_DRY_RUN", "false") == "true",
 	}
 }
 
@@ -85,3 +156,37 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
+
+// getEnvPositiveDuration 读取必须大于 0 的时间间隔（如定时任务的周期），未设置或不大于 0 时使用默认值
+func getEnvPositiveDuration(key string, defaultValue time.Duration) time.Duration {
+	if d := getEnvDuration(key, defaultValue); d > 0 {
+		return d
+	}
+	return defaultValue
+}
+
+// getEnvMinDuration 读取有下限的时长，小于下限时使用下限
+func getEnvMinDuration(key string, defaultValue, minValue time.Duration) time.Duration {
+	if d := getEnvDuration(key, defaultValue); d > minValue {
+		return d
+	}
+	return minValue
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if i, err := strconv.Atoi(value); err == nil {
+			return i
+		}
+	}
+	return defaultValue
+}
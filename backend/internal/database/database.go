@@ -12,6 +12,7 @@ import (
 	"github.com/minio/minio-go/v7/pkg/credentials"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 )
 
@@ -44,10 +45,49 @@ func InitDB(cfg *config.Config) (*gorm.DB, error) {
 		return nil, fmt.Errorf("failed to migrate database: %w", err)
 	}
 
+	// 为引入对话表之前创建的对话补建记录
+	if err := backfillConversations(db); err != nil {
+		return nil, fmt.Errorf("failed to backfill conversations: %w", err)
+	}
+
+	// 写入内置明信片模板
+	if err := seedPostcardTemplates(db); err != nil {
+		return nil, fmt.Errorf("failed to seed postcard templates: %w", err)
+	}
+
 	log.Println("Database connected and migrated successfully")
 	return db, nil
 }
 
+// backfillConversations 根据明信片补建缺失的对话记录
+func backfillConversations(db *gorm.DB) error {
+	return db.Exec(`
+		INSERT INTO conversations (id, user_id, character_id, created_at, updated_at)
+		SELECT p.conversation_id, MIN(p.user_id), MIN(p.character_id), MIN(p.created_at), NOW()
+		FROM postcards p
+		LEFT JOIN conversations c ON c.id = p.conversation_id
+		WHERE c.id IS NULL AND p.deleted_at IS NULL
+		GROUP BY p.conversation_id`).Error
+}
+
+// seedPostcardTemplates 写入内置模板，已存在的模板（包括管理员修改过的）保持不变
+func seedPostcardTemplates(db *gorm.DB) error {
+	templates := []models.PostcardTemplate{
+		{ID: "classic", Name: "经典", Description: "米白纸张搭配朱红邮票", SortOrder: 1,
+			Layout: models.TemplateLayout{PaperColor: "#faf6ee", InkColor: "#332d28", AccentColor: "#b03a2e"}},
+		{ID: "vintage", Name: "复古", Description: "泛黄的旧信纸", SortOrder: 2,
+			Layout: models.TemplateLayout{PaperColor: "#eee0c4", InkColor: "#4a3728", AccentColor: "#785e3c"}},
+		{ID: "night", Name: "夜空", Description: "深蓝底色与金色点缀", SortOrder: 3,
+			Layout: models.TemplateLayout{PaperColor: "#1e2436", InkColor: "#e8e4d8", AccentColor: "#d4af5f"}},
+		{ID: "minimal", Name: "极简", Description: "纯白纸张与灰色线条", SortOrder: 4,
+			Layout: models.TemplateLayout{PaperColor: "#ffffff", InkColor: "#212121", AccentColor: "#787878"}},
+	}
+	for i := range templates {
+		templates[i].IsAvailable = true
+	}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&templates).Error
+}
+
 // InitRedis 初始化 Redis 连接
 func InitRedis(cfg *config.Config) (*redis.Client, error) {
 	rdb := redis.NewClient(&redis.Options{
@@ -138,5 +178,21 @@ func autoMigrate(db *gorm.DB) error {
 		&models.Postcard{},
 		&models.Draft{},
 		&models.Favorite{},
+		&models.CharacterReview{},
+		&models.Report{},
+		&models.UserPersona{},
+		&models.LorebookEntry{},
+		&models.PostcardEmbedding{},
+		&models.Conversation{},
+		&models.PostcardTemplate{},
+		&models.PrintJob{},
+		&models.ShareLink{},
+		&models.PostcardEmotion{},
+		&models.ReminderSchedule{},
+		&models.Notification{},
+		&models.NotificationPreference{},
+		&models.WebhookEndpoint{},
+		&models.WebhookDelivery{},
+		&models.Upload{},
 	)
 }
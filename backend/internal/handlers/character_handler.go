@@ -11,12 +11,18 @@ import (
 )
 
 type CharacterHandler struct {
-	characterService *services.CharacterService
+	characterService      *services.CharacterService
+	popularityService     *services.PopularityService
+	recommendationService *services.RecommendationService
+	policyService         *services.PolicyService
 }
 
-func NewCharacterHandler(characterService *services.CharacterService) *CharacterHandler {
+func NewCharacterHandler(characterService *services.CharacterService, popularityService *services.PopularityService, recommendationService *services.RecommendationService, policyService *services.PolicyService) *CharacterHandler {
 	return &CharacterHandler{
-		characterService: characterService,
+		characterService:      characterService,
+		popularityService:     popularityService,
+		recommendationService: recommendationService,
+		policyService:         policyService,
 	}
 }
 
@@ -96,7 +102,7 @@ func (h *CharacterHandler) GetCharacter(c *gin.Context) {
 // @Param visibility query string false "可见性" Enums(private,public)
 // @Param creator_id query int false "创建者ID"
 // @Param search query string false "搜索关键词"
-// @Param sort_by query string false "排序字段" default(created_at) Enums(created_at,popularity_score,usage_count)
+// @Param sort_by query string false "排序字段" default(created_at) Enums(created_at,popularity_score,usage_count,rating_average)
 // @Param sort_order query string false "排序方向" default(desc) Enums(asc,desc)
 // @Success 200 {object} models.APIResponse{data=models.PaginatedResponse}
 // @Router /api/characters [get]
@@ -123,6 +129,62 @@ func (h *CharacterHandler) ListCharacters(c *gin.Context) {
 	c.JSON(http.StatusOK, models.Success(result))
 }
 
+// GetTrendingCharacters 获取热门角色
+// @Summary 获取热门角色
+// @Description 按时间窗口获取热门公开角色。day/week 按窗口内的明信片量和独立用户数排序，all 按热度分排序
+// @Tags 角色
+// @Produce json
+// @Param window query string false "时间窗口" default(week) Enums(day,week,all)
+// @Param limit query int false "数量" default(20)
+// @Success 200 {object} models.APIResponse{data=[]models.TrendingCharacter}
+// @Router /api/characters/trending [get]
+func (h *CharacterHandler) GetTrendingCharacters(c *gin.Context) {
+	var query models.TrendingQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	trending, err := h.popularityService.GetTrendingCharacters(&query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(trending))
+}
+
+// GetRecommendedCharacters 获取推荐角色
+// @Summary 获取推荐角色
+// @Description 基于用户与角色的互动记录进行协同过滤推荐，数据不足时使用标签相似度和热门角色补足。已经常聊天、已收藏或自己创建的角色不会出现在结果中
+// @Tags 角色
+// @Produce json
+// @Security BearerAuth
+// @Param limit query int false "数量" default(20)
+// @Success 200 {object} models.APIResponse{data=[]models.RecommendedCharacter}
+// @Router /api/characters/recommended [get]
+func (h *CharacterHandler) GetRecommendedCharacters(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	var query models.RecommendationQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	recommendations, err := h.recommendationService.GetRecommendedCharacters(userID, query.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(recommendations))
+}
+
 // UpdateCharacter 更新角色
 // @Summary 更新角色
 // @Description 更新角色信息（仅创建者可操作）
@@ -134,6 +196,7 @@ func (h *CharacterHandler) ListCharacters(c *gin.Context) {
 // @Param request body models.CharacterUpdateRequest true "更新信息"
 // @Success 200 {object} models.APIResponse{data=models.Character}
 // @Failure 400 {object} models.APIResponse
+// @Failure 403 {object} models.APIResponse
 // @Router /api/characters/{id} [put]
 func (h *CharacterHandler) UpdateCharacter(c *gin.Context) {
 	userID, exists := middleware.GetCurrentUserID(c)
@@ -157,7 +220,7 @@ func (h *CharacterHandler) UpdateCharacter(c *gin.Context) {
 
 	character, err := h.characterService.UpdateCharacter(uint(id), userID, &req)
 	if err != nil {
-		if err.Error() == "permission denied" {
+		if err.Error() == "permission denied" || err.Error() == "character hidden by moderator" {
 			c.JSON(http.StatusForbidden, models.Error(403, err.Error()))
 			return
 		}
@@ -263,6 +326,11 @@ func (h *CharacterHandler) ToggleFavorite(c *gin.Context) {
 		return
 	}
 
+	if _, err := h.policyService.AuthorizeCharacterView(&userID, uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+		return
+	}
+
 	isFavorite, err := h.characterService.ToggleFavorite(userID, uint(id))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
@@ -333,6 +401,11 @@ func (h *CharacterHandler) CheckFavoriteStatus(c *gin.Context) {
 		return
 	}
 
+	if _, err := h.policyService.AuthorizeCharacterView(&userID, uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+		return
+	}
+
 	isFavorite, err := h.characterService.IsCharacterFavorite(userID, uint(id))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
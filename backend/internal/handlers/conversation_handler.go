@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"memory-postcard-backend/internal/middleware"
+	"memory-postcard-backend/internal/models"
+	"memory-postcard-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ConversationHandler struct {
+	conversationService *services.ConversationService
+}
+
+func NewConversationHandler(conversationService *services.ConversationService) *ConversationHandler {
+	return &ConversationHandler{
+		conversationService: conversationService,
+	}
+}
+
+// ListConversations 获取对话列表
+// @Summary 获取对话列表
+// @Description 获取当前用户的对话列表，每个对话一行，包含角色、最后一张明信片、最后活动时间、明信片数量和未读回复数量。置顶的对话在前，其余按最后活动时间倒序
+// @Tags 对话
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页数量" default(20)
+// @Param character_id query int false "角色ID"
+// @Param archived query bool false "是否只看已归档的对话" default(false)
+// @Param search query string false "按标题搜索"
+// @Success 200 {object} models.APIResponse{data=models.PaginatedResponse}
+// @Router /api/conversations [get]
+func (h *ConversationHandler) ListConversations(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	var query models.ConversationListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	result, err := h.conversationService.ListConversations(userID, &query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(result))
+}
+
+// GetConversation 获取对话概要
+// @Summary 获取对话概要
+// @Description 获取单个对话的概要信息
+// @Tags 对话
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "对话ID"
+// @Success 200 {object} models.APIResponse{data=models.ConversationSummary}
+// @Failure 404 {object} models.APIResponse
+// @Router /api/conversations/{id} [get]
+func (h *ConversationHandler) GetConversation(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	summary, err := h.conversationService.GetConversationSummary(c.Param("id"), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(summary))
+}
+
+// UpdateConversation 更新对话
+// @Summary 更新对话
+// @Description 重命名、置顶、归档对话，或设置角色主动问候（用户连续 check_in_days 天没有来信时角色主动寄出明信片，0 为关闭），只更新提供的字段
+// @Tags 对话
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "对话ID"
+// @Param request body models.ConversationUpdateRequest true "更新信息"
+// @Success 200 {object} models.APIResponse{data=models.ConversationSummary}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /api/conversations/{id} [patch]
+func (h *ConversationHandler) UpdateConversation(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	var req models.ConversationUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	summary, err := h.conversationService.UpdateConversation(c.Param("id"), userID, &req)
+	if err != nil {
+		if err.Error() == "conversation not found" {
+			c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+			return
+		}
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(summary))
+}
+
+// MarkConversationRead 标记对话已读
+// @Summary 标记对话已读
+// @Description 将对话中的 AI 回复全部标记为已读
+// @Tags 对话
+// @Security BearerAuth
+// @Param id path string true "对话ID"
+// @Success 200 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /api/conversations/{id}/read [post]
+func (h *ConversationHandler) MarkConversationRead(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	if err := h.conversationService.MarkConversationRead(c.Param("id"), userID); err != nil {
+		if err.Error() == "conversation not found" {
+			c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(nil))
+}
+
+// ForkConversation 从明信片分支出新对话
+// @Summary 从明信片分支出新对话
+// @Description 以该明信片为分支点创建新对话，新对话继承原对话截至该明信片（含）的历史，原对话保持不变
+// @Tags 对话
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "明信片ID"
+// @Success 200 {object} models.APIResponse{data=models.ConversationForkResponse}
+// @Failure 404 {object} models.APIResponse
+// @Router /api/postcards/{id}/fork [post]
+func (h *ConversationHandler) ForkConversation(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid postcard ID"))
+		return
+	}
+
+	result, err := h.conversationService.ForkConversation(uint(id), userID)
+	if err != nil {
+		if err.Error() == "postcard not found" || err.Error() == "conversation not found" {
+			c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+			return
+		}
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(result))
+}
+
+// GetConversationTree 获取对话分支树
+// @Summary 获取对话分支树
+// @Description 获取对话所在的分支树，从最初的对话开始，每个节点包含明信片数量、最后一张明信片和分支出的子对话
+// @Tags 对话
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "对话ID"
+// @Success 200 {object} models.APIResponse{data=models.ConversationTreeNode}
+// @Failure 404 {object} models.APIResponse
+// @Router /api/conversations/{id}/tree [get]
+func (h *ConversationHandler) GetConversationTree(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	tree, err := h.conversationService.GetConversationTree(c.Param("id"), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(tree))
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"memory-postcard-backend/internal/middleware"
+	"memory-postcard-backend/internal/models"
+	"memory-postcard-backend/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ExportHandler struct {
+	exportService *services.ExportService
+}
+
+func NewExportHandler(exportService *services.ExportService) *ExportHandler {
+	return &ExportHandler{
+		exportService: exportService,
+	}
+}
+
+// exportContentTypes 各导出格式的 Content-Type
+var exportContentTypes = map[string]string{
+	"md":   "text/markdown; charset=utf-8",
+	"json": "application/json; charset=utf-8",
+	"epub": "application/epub+zip",
+}
+
+// ExportConversation 导出对话
+// @Summary 导出对话
+// @Description 将对话导出为 Markdown、JSON 或 EPUB 文件，包含双方的明信片、时间和角色资料。Markdown 和 JSON 中的图片为链接，EPUB 会嵌入本站存储的图片。内容以流式写出，长对话也不会一次性载入内存
+// @Tags 对话
+// @Produce text/markdown,application/json,application/epub+zip
+// @Security BearerAuth
+// @Param id path string true "对话ID"
+// @Param format query string true "导出格式" Enums(md,json,epub)
+// @Success 200 {file} file
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /api/conversations/{id}/export [get]
+func (h *ExportHandler) ExportConversation(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	var query models.ConversationExportQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	export, err := h.exportService.PrepareExport(c.Param("id"), userID)
+	if err != nil {
+		if err.Error() == "conversation not found" {
+			c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	c.Header("Content-Type", exportContentTypes[query.Format])
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="conversation-%s.%s"`, export.Conversation.ID, query.Format))
+	c.Status(http.StatusOK)
+
+	switch query.Format {
+	case "md":
+		err = h.exportService.WriteMarkdown(c.Writer, export)
+	case "json":
+		err = h.exportService.WriteJSON(c.Writer, export)
+	case "epub":
+		err = h.exportService.WriteEPUB(c.Writer, export)
+	}
+	// 响应头已发出，出错时只能中断连接
+	if err != nil {
+		log.Printf("Failed to export conversation %s: %v", export.Conversation.ID, err)
+		c.Abort()
+	}
+}
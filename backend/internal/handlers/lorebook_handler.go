@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"memory-postcard-backend/internal/middleware"
+	"memory-postcard-backend/internal/models"
+	"memory-postcard-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LorebookHandler struct {
+	lorebookService *services.LorebookService
+	policyService   *services.PolicyService
+}
+
+func NewLorebookHandler(lorebookService *services.LorebookService, policyService *services.PolicyService) *LorebookHandler {
+	return &LorebookHandler{
+		lorebookService: lorebookService,
+		policyService:   policyService,
+	}
+}
+
+// ListEntries 获取角色的世界设定
+// @Summary 获取角色的世界设定
+// @Description 获取角色的全部世界设定条目（仅角色创建者）
+// @Tags 世界设定
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "角色ID"
+// @Success 200 {object} models.APIResponse{data=[]models.LorebookEntry}
+// @Failure 403 {object} models.APIResponse
+// @Router /api/characters/{id}/lorebook [get]
+func (h *LorebookHandler) ListEntries(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid character ID"))
+		return
+	}
+
+	entries, err := h.lorebookService.ListEntries(uint(id), userID)
+	if err != nil {
+		if err.Error() == "permission denied" {
+			c.JSON(http.StatusForbidden, models.Error(403, err.Error()))
+			return
+		}
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(entries))
+}
+
+// CreateEntry 创建世界设定条目
+// @Summary 创建世界设定条目
+// @Description 为角色添加世界设定条目。对话最近的明信片中出现任一关键词时，条目会按优先级在 token 预算内注入 AI 上下文
+// @Tags 世界设定
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "角色ID"
+// @Param request body models.LorebookEntryCreateRequest true "条目信息"
+// @Success 200 {object} models.APIResponse{data=models.LorebookEntry}
+// @Failure 400 {object} models.APIResponse
+// @Failure 403 {object} models.APIResponse
+// @Router /api/characters/{id}/lorebook [post]
+func (h *LorebookHandler) CreateEntry(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid character ID"))
+		return
+	}
+
+	var req models.LorebookEntryCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	entry, err := h.lorebookService.CreateEntry(uint(id), userID, &req)
+	if err != nil {
+		if err.Error() == "permission denied" {
+			c.JSON(http.StatusForbidden, models.Error(403, err.Error()))
+			return
+		}
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(entry))
+}
+
+// UpdateEntry 更新世界设定条目
+// @Summary 更新世界设定条目
+// @Description 更新角色的世界设定条目（仅角色创建者）
+// @Tags 世界设定
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "角色ID"
+// @Param entry_id path int true "条目ID"
+// @Param request body models.LorebookEntryUpdateRequest true "更新信息"
+// @Success 200 {object} models.APIResponse{data=models.LorebookEntry}
+// @Failure 400 {object} models.APIResponse
+// @Failure 403 {object} models.APIResponse
+// @Router /api/characters/{id}/lorebook/{entry_id} [put]
+func (h *LorebookHandler) UpdateEntry(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid character ID"))
+		return
+	}
+
+	entryIDStr := c.Param("entry_id")
+	entryID, err := strconv.ParseUint(entryIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid entry ID"))
+		return
+	}
+
+	var req models.LorebookEntryUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	entry, err := h.lorebookService.UpdateEntry(uint(id), uint(entryID), userID, &req)
+	if err != nil {
+		if err.Error() == "permission denied" {
+			c.JSON(http.StatusForbidden, models.Error(403, err.Error()))
+			return
+		}
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(entry))
+}
+
+// DeleteEntry 删除世界设定条目
+// @Summary 删除世界设定条目
+// @Description 删除角色的世界设定条目（仅角色创建者）
+// @Tags 世界设定
+// @Security BearerAuth
+// @Param id path int true "角色ID"
+// @Param entry_id path int true "条目ID"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.APIResponse
+// @Failure 403 {object} models.APIResponse
+// @Router /api/characters/{id}/lorebook/{entry_id} [delete]
+func (h *LorebookHandler) DeleteEntry(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid character ID"))
+		return
+	}
+
+	entryIDStr := c.Param("entry_id")
+	entryID, err := strconv.ParseUint(entryIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid entry ID"))
+		return
+	}
+
+	if err := h.lorebookService.DeleteEntry(uint(id), uint(entryID), userID); err != nil {
+		if err.Error() == "permission denied" {
+			c.JSON(http.StatusForbidden, models.Error(403, err.Error()))
+			return
+		}
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(nil))
+}
+
+// DryRun 试运行世界设定匹配
+// @Summary 试运行世界设定匹配
+// @Description 查看给定消息会触发哪些条目，以及在 token 预算内实际会注入哪些条目。提供 conversation_id 时连同该对话最近的明信片一起匹配
+// @Tags 世界设定
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "角色ID"
+// @Param request body models.LorebookDryRunRequest true "试运行消息"
+// @Success 200 {object} models.APIResponse{data=models.LorebookDryRunResponse}
+// @Failure 400 {object} models.APIResponse
+// @Failure 403 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /api/characters/{id}/lorebook/dry-run [post]
+func (h *LorebookHandler) DryRun(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid character ID"))
+		return
+	}
+
+	var req models.LorebookDryRunRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	if req.ConversationID != "" {
+		if _, err := h.policyService.AuthorizeConversation(userID, req.ConversationID); err != nil {
+			c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+			return
+		}
+	}
+
+	result, err := h.lorebookService.DryRun(uint(id), userID, &req)
+	if err != nil {
+		if err.Error() == "permission denied" {
+			c.JSON(http.StatusForbidden, models.Error(403, err.Error()))
+			return
+		}
+		if err.Error() == "conversation not found" {
+			c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+			return
+		}
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(result))
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"memory-postcard-backend/internal/middleware"
+	"memory-postcard-backend/internal/models"
+	"memory-postcard-backend/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MoodHandler struct {
+	emotionService *services.EmotionService
+}
+
+func NewMoodHandler(emotionService *services.EmotionService) *MoodHandler {
+	return &MoodHandler{
+		emotionService: emotionService,
+	}
+}
+
+// GetMoodTrend 获取情绪趋势
+// @Summary 获取情绪趋势
+// @Description 按日或按周汇总用户所写明信片的情绪，返回区间内的每个周期（没有明信片的周期 postcards 为 0）。emotions 为各情绪得分之和，valence 为情绪效价均值（-1 到 1）。日期按用户资料中的时区划分，按周统计时以周一为一周的开始，最多 366 个周期
+// @Tags 情绪
+// @Produce json
+// @Security BearerAuth
+// @Param period query string false "统计周期" Enums(day,week) default(day)
+// @Param from query string false "起始日期，格式 2006-01-02，默认按日为最近 30 天、按周为最近 12 周"
+// @Param to query string false "结束日期，格式 2006-01-02，默认为今天"
+// @Param character_id query int false "角色ID"
+// @Success 200 {object} models.APIResponse{data=models.MoodTrendResponse}
+// @Failure 400 {object} models.APIResponse
+// @Router /api/moods/trend [get]
+func (h *MoodHandler) GetMoodTrend(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	var query models.MoodTrendQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	trend, err := h.emotionService.GetMoodTrend(userID, &query)
+	if err != nil {
+		if err.Error() == "invalid date range" || err.Error() == "date range too large" {
+			c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(trend))
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"memory-postcard-backend/internal/middleware"
+	"memory-postcard-backend/internal/models"
+	"memory-postcard-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationHandler struct {
+	notificationService *services.NotificationService
+}
+
+func NewNotificationHandler(notificationService *services.NotificationService) *NotificationHandler {
+	return &NotificationHandler{
+		notificationService: notificationService,
+	}
+}
+
+// ListNotifications 获取我的通知
+// @Summary 获取我的通知
+// @Description 分页获取当前用户的站内通知，从新到旧排列，同时返回未读总数
+// @Tags 通知
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页数量" default(20)
+// @Param type query string false "通知类型"
+// @Param unread_only query bool false "只看未读"
+// @Success 200 {object} models.APIResponse{data=models.NotificationListResponse}
+// @Router /api/notifications [get]
+func (h *NotificationHandler) ListNotifications(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	var query models.NotificationListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	result, err := h.notificationService.ListNotifications(userID, &query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(result))
+}
+
+// GetUnreadCount 获取未读通知数
+// @Summary 获取未读通知数
+// @Description 获取当前用户的未读通知数，用于显示角标
+// @Tags 通知
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.APIResponse
+// @Router /api/notifications/unread-count [get]
+func (h *NotificationHandler) GetUnreadCount(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	count, err := h.notificationService.GetUnreadCount(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(gin.H{"unread_count": count}))
+}
+
+// MarkRead 标记通知已读
+// @Summary 标记通知已读
+// @Description 将一条通知标记为已读
+// @Tags 通知
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "通知ID"
+// @Success 200 {object} models.APIResponse{data=models.Notification}
+// @Failure 404 {object} models.APIResponse
+// @Router /api/notifications/{id}/read [post]
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid notification ID"))
+		return
+	}
+
+	notification, err := h.notificationService.MarkRead(uint(id), userID)
+	if err != nil {
+		if err.Error() == "notification not found" {
+			c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(notification))
+}
+
+// MarkAllRead 全部标记已读
+// @Summary 全部标记已读
+// @Description 将当前用户的未读通知全部标记为已读，指定 type 时只标记该类通知
+// @Tags 通知
+// @Produce json
+// @Security BearerAuth
+// @Param type query string false "通知类型"
+// @Success 200 {object} models.APIResponse
+// @Router /api/notifications/read-all [post]
+func (h *NotificationHandler) MarkAllRead(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	count, err := h.notificationService.MarkAllRead(userID, c.Query("type"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(gin.H{"marked": count}))
+}
+
+// GetPreferences 获取通知设置
+// @Summary 获取通知设置
+// @Description 获取各类通知的开关，未设置过的类型默认开启
+// @Tags 通知
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.APIResponse{data=[]models.NotificationPreferenceItem}
+// @Router /api/notifications/preferences [get]
+func (h *NotificationHandler) GetPreferences(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	preferences, err := h.notificationService.GetPreferences(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(preferences))
+}
+
+// UpdatePreferences 更新通知设置
+// @Summary 更新通知设置
+// @Description 开启或关闭指定类型的通知，关闭后不再产生该类站内通知，未提供的类型保持不变
+// @Tags 通知
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.NotificationPreferencesRequest true "通知设置"
+// @Success 200 {object} models.APIResponse{data=[]models.NotificationPreferenceItem}
+// @Failure 400 {object} models.APIResponse
+// @Router /api/notifications/preferences [put]
+func (h *NotificationHandler) UpdatePreferences(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	var req models.NotificationPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	preferences, err := h.notificationService.UpdatePreferences(userID, &req)
+	if err != nil {
+		if err.Error() == "invalid notification type" {
+			c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(preferences))
+}
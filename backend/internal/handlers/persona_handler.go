@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"memory-postcard-backend/internal/middleware"
+	"memory-postcard-backend/internal/models"
+	"memory-postcard-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PersonaHandler struct {
+	personaService *services.PersonaService
+}
+
+func NewPersonaHandler(personaService *services.PersonaService) *PersonaHandler {
+	return &PersonaHandler{
+		personaService: personaService,
+	}
+}
+
+// ListPersonas 获取我的人设列表
+// @Summary 获取我的人设列表
+// @Description 获取当前用户保存的所有人设
+// @Tags 人设
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.APIResponse{data=[]models.UserPersona}
+// @Router /api/personas [get]
+func (h *PersonaHandler) ListPersonas(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	personas, err := h.personaService.ListPersonas(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(personas))
+}
+
+// CreatePersona 创建人设
+// @Summary 创建人设
+// @Description 保存一个人设，可在与角色交流时选用。is_default 为 true 时作为未单独设置人设的角色的默认人设
+// @Tags 人设
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.PersonaCreateRequest true "人设信息"
+// @Success 200 {object} models.APIResponse{data=models.UserPersona}
+// @Failure 400 {object} models.APIResponse
+// @Router /api/personas [post]
+func (h *PersonaHandler) CreatePersona(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	var req models.PersonaCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	persona, err := h.personaService.CreatePersona(userID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(persona))
+}
+
+// UpdatePersona 更新人设
+// @Summary 更新人设
+// @Description 更新已保存的人设
+// @Tags 人设
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "人设ID"
+// @Param request body models.PersonaUpdateRequest true "更新信息"
+// @Success 200 {object} models.APIResponse{data=models.UserPersona}
+// @Failure 400 {object} models.APIResponse
+// @Router /api/personas/{id} [put]
+func (h *PersonaHandler) UpdatePersona(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid persona ID"))
+		return
+	}
+
+	var req models.PersonaUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	persona, err := h.personaService.UpdatePersona(uint(id), userID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(persona))
+}
+
+// DeletePersona 删除人设
+// @Summary 删除人设
+// @Description 删除已保存的人设，选用了该人设的角色会恢复使用默认人设
+// @Tags 人设
+// @Security BearerAuth
+// @Param id path int true "人设ID"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.APIResponse
+// @Router /api/personas/{id} [delete]
+func (h *PersonaHandler) DeletePersona(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid persona ID"))
+		return
+	}
+
+	if err := h.personaService.DeletePersona(uint(id), userID); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(nil))
+}
+
+// GetCharacterPersona 获取与角色交流时使用的人设
+// @Summary 获取与角色交流时使用的人设
+// @Description 返回实际生效的人设及其来源：custom（为该角色单独填写）、persona（选用的已保存人设）、default（默认人设）、character（角色设定的用户角色）
+// @Tags 人设
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "角色ID"
+// @Success 200 {object} models.APIResponse{data=models.ResolvedPersona}
+// @Failure 404 {object} models.APIResponse
+// @Router /api/characters/{id}/persona [get]
+func (h *PersonaHandler) GetCharacterPersona(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid character ID"))
+		return
+	}
+
+	persona, err := h.personaService.GetCharacterPersona(userID, uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(persona))
+}
+
+// SetCharacterPersona 设置与角色交流时使用的人设
+// @Summary 设置与角色交流时使用的人设
+// @Description 为角色选用一个已保存的人设（persona_id），或直接填写人设名称和描述
+// @Tags 人设
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "角色ID"
+// @Param request body models.CharacterPersonaRequest true "人设信息"
+// @Success 200 {object} models.APIResponse{data=models.ResolvedPersona}
+// @Failure 400 {object} models.APIResponse
+// @Router /api/characters/{id}/persona [put]
+func (h *PersonaHandler) SetCharacterPersona(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid character ID"))
+		return
+	}
+
+	var req models.CharacterPersonaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	persona, err := h.personaService.SetCharacterPersona(userID, uint(id), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(persona))
+}
+
+// ClearCharacterPersona 清除为角色设置的人设
+// @Summary 清除为角色设置的人设
+// @Description 清除后恢复使用默认人设或角色设定的用户角色
+// @Tags 人设
+// @Security BearerAuth
+// @Param id path int true "角色ID"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.APIResponse
+// @Router /api/characters/{id}/persona [delete]
+func (h *PersonaHandler) ClearCharacterPersona(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid character ID"))
+		return
+	}
+
+	if err := h.personaService.ClearCharacterPersona(userID, uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(nil))
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"io"
 	"memory-postcard-backend/internal/middleware"
 	"memory-postcard-backend/internal/models"
 	"memory-postcard-backend/internal/services"
@@ -12,17 +13,21 @@ import (
 
 type PostcardHandler struct {
 	postcardService *services.PostcardService
+	policyService   *services.PolicyService
+	renderService   *services.RenderService
 }
 
-func NewPostcardHandler(postcardService *services.PostcardService) *PostcardHandler {
+func NewPostcardHandler(postcardService *services.PostcardService, policyService *services.PolicyService, renderService *services.RenderService) *PostcardHandler {
 	return &PostcardHandler{
 		postcardService: postcardService,
+		policyService:   policyService,
+		renderService:   renderService,
 	}
 }
 
 // CreatePostcard 创建明信片
 // @Summary 创建明信片
-// @Description 发送明信片给AI角色
+// @Description 发送明信片给AI角色。type 只能为 user，AI 明信片由系统生成
 // @Tags 明信片
 // @Accept json
 // @Produce json
@@ -30,6 +35,8 @@ func NewPostcardHandler(postcardService *services.PostcardService) *PostcardHand
 // @Param request body models.PostcardCreateRequest true "明信片信息"
 // @Success 200 {object} models.APIResponse{data=models.Postcard}
 // @Failure 400 {object} models.APIResponse
+// @Failure 403 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
 // @Router /api/postcards [post]
 func (h *PostcardHandler) CreatePostcard(c *gin.Context) {
 	userID, exists := middleware.GetCurrentUserID(c)
@@ -44,6 +51,19 @@ func (h *PostcardHandler) CreatePostcard(c *gin.Context) {
 		return
 	}
 
+	// 检查角色可见性、对话归属，AI 明信片只能由系统生成
+	if err := h.policyService.AuthorizePostcardCreate(userID, &req); err != nil {
+		switch err.Error() {
+		case "permission denied":
+			c.JSON(http.StatusForbidden, models.Error(403, err.Error()))
+		case "character not found", "conversation not found":
+			c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+		default:
+			c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		}
+		return
+	}
+
 	postcard, err := h.postcardService.CreatePostcard(userID, &req)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
@@ -53,6 +73,44 @@ func (h *PostcardHandler) CreatePostcard(c *gin.Context) {
 	c.JSON(http.StatusOK, models.Success(postcard))
 }
 
+// StartConversation 开始新对话
+// @Summary 开始新对话
+// @Description 与角色开始一段新对话。角色设置了开场白时，会自动收到角色的问候明信片
+// @Tags 明信片
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.ConversationStartRequest true "对话信息"
+// @Success 200 {object} models.APIResponse{data=models.ConversationStartResponse}
+// @Failure 400 {object} models.APIResponse
+// @Router /api/postcards/conversations [post]
+func (h *PostcardHandler) StartConversation(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	var req models.ConversationStartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	if _, err := h.policyService.AuthorizeCharacterChat(userID, req.CharacterID); err != nil {
+		c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+		return
+	}
+
+	result, err := h.postcardService.StartConversation(userID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(result))
+}
+
 // GetPostcard 获取明信片详情
 // @Summary 获取明信片详情
 // @Description 根据ID获取明信片的详细信息
@@ -86,6 +144,251 @@ func (h *PostcardHandler) GetPostcard(c *gin.Context) {
 	c.JSON(http.StatusOK, models.Success(postcard))
 }
 
+// GetRecalledMemories 获取 AI 回复时回忆起的明信片
+// @Summary 获取 AI 回复时回忆起的明信片
+// @Description 返回角色写这张回复时回忆起的过往明信片及相似度，已删除的明信片不会返回
+// @Tags 明信片
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "明信片ID"
+// @Success 200 {object} models.APIResponse{data=[]models.RecalledMemory}
+// @Failure 404 {object} models.APIResponse
+// @Router /api/postcards/{id}/memories [get]
+func (h *PostcardHandler) GetRecalledMemories(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid postcard ID"))
+		return
+	}
+
+	memories, err := h.postcardService.GetRecalledMemories(uint(id), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(memories))
+}
+
+// RenderPostcard 渲染明信片图片
+// @Summary 渲染明信片图片
+// @Description 将明信片渲染为可分享、打印的正反面图片（A6 横版，300 DPI）。正面为配图，背面为正文、邮票、邮戳和收寄件人。结果缓存在对象存储中，明信片内容变化后会重新渲染
+// @Tags 明信片
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "明信片ID"
+// @Param side query string false "渲染哪一面：front / back / both，默认 both"
+// @Param format query string false "图片格式：png / jpeg，默认 png"
+// @Success 200 {object} models.APIResponse{data=models.PostcardRenderResponse}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /api/postcards/{id}/render [get]
+func (h *PostcardHandler) RenderPostcard(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid postcard ID"))
+		return
+	}
+
+	var query models.PostcardRenderQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	result, err := h.renderService.RenderPostcard(uint(id), userID, &query)
+	if err != nil {
+		if err.Error() == "postcard not found" {
+			c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(result))
+}
+
+// StreamReply 流式生成回复
+// @Summary 流式生成回复
+// @Description 以 SSE 方式流式返回角色对用户明信片的回复。事件类型：delta（文本片段）、done（回复已保存，附带 AI 明信片）、error（生成失败）。客户端中途断开时回复仍会生成完毕并保存。创建明信片时需设置 skip_ai_reply 以避免重复生成
+// @Tags 明信片
+// @Produce text/event-stream
+// @Security BearerAuth
+// @Param id path int true "用户明信片ID"
+// @Success 200 {object} models.ReplyStreamEvent
+// @Failure 404 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse
+// @Router /api/postcards/{id}/reply/stream [post]
+func (h *PostcardHandler) StreamReply(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid postcard ID"))
+		return
+	}
+
+	events, err := h.postcardService.StreamReply(c.Request.Context(), uint(id), userID)
+	if err != nil {
+		switch err.Error() {
+		case "postcard not found":
+			c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+		case "reply already exists", "reply is already being generated":
+			c.JSON(http.StatusConflict, models.Error(409, err.Error()))
+		default:
+			c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		}
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	c.Stream(func(w io.Writer) bool {
+		event, ok := <-events
+		if !ok {
+			return false
+		}
+		c.SSEvent(event.Type, event)
+		return true
+	})
+}
+
+// RegenerateReply 重新生成回复
+// @Summary 重新生成回复
+// @Description 为用户明信片重新生成一个回复。id 可以是用户明信片或它的任一 AI 回复。新回复成为选定的回复，原有回复保留为备选
+// @Tags 明信片
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "明信片ID"
+// @Success 200 {object} models.APIResponse{data=models.Postcard}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse
+// @Router /api/postcards/{id}/regenerate [post]
+func (h *PostcardHandler) RegenerateReply(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid postcard ID"))
+		return
+	}
+
+	postcard, err := h.postcardService.RegenerateReply(uint(id), userID)
+	if err != nil {
+		switch err.Error() {
+		case "postcard not found", "replied postcard not found":
+			c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+		case "reply is already being generated":
+			c.JSON(http.StatusConflict, models.Error(409, err.Error()))
+		default:
+			c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(postcard))
+}
+
+// ListAlternatives 获取全部回复
+// @Summary 获取全部回复
+// @Description 获取用户明信片的全部回复（含备选），按生成时间排序。id 可以是用户明信片或它的任一 AI 回复
+// @Tags 明信片
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "明信片ID"
+// @Success 200 {object} models.APIResponse{data=[]models.Postcard}
+// @Failure 404 {object} models.APIResponse
+// @Router /api/postcards/{id}/alternatives [get]
+func (h *PostcardHandler) ListAlternatives(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid postcard ID"))
+		return
+	}
+
+	replies, err := h.postcardService.ListAlternatives(uint(id), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(replies))
+}
+
+// SelectReply 选定回复
+// @Summary 选定回复
+// @Description 将 AI 回复设为选定的回复，同一明信片的其他回复变为备选。只有选定的回复会进入后续对话历史
+// @Tags 明信片
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "AI 回复明信片ID"
+// @Success 200 {object} models.APIResponse{data=models.Postcard}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /api/postcards/{id}/select [put]
+func (h *PostcardHandler) SelectReply(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid postcard ID"))
+		return
+	}
+
+	postcard, err := h.postcardService.SelectReply(uint(id), userID)
+	if err != nil {
+		if err.Error() == "postcard not found" {
+			c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+			return
+		}
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(postcard))
+}
+
 // ListPostcards 获取明信片列表
 // @Summary 获取明信片列表
 // @Description 分页获取用户的明信片列表
@@ -98,6 +401,8 @@ func (h *PostcardHandler) GetPostcard(c *gin.Context) {
 // @Param character_id query int false "角色ID"
 // @Param status query string false "状态" Enums(draft,sent,delivered,read)
 // @Param is_favorite query bool false "是否收藏"
+// @Param emotion query string false "情绪标签" Enums(joy,love,gratitude,calm,surprise,longing,sadness,fear,anger,neutral)
+// @Param include_alternatives query bool false "是否包含未选定的备选回复"
 // @Param sort_by query string false "排序字段" default(created_at) Enums(created_at,updated_at)
 // @Param sort_order query string false "排序方向" default(desc) Enums(asc,desc)
 // @Success 200 {object} models.APIResponse{data=models.PaginatedResponse}
@@ -126,7 +431,7 @@ func (h *PostcardHandler) ListPostcards(c *gin.Context) {
 
 // UpdatePostcard 更新明信片
 // @Summary 更新明信片
-// @Description 更新明信片信息
+// @Description 更新明信片信息。编辑 AI 回复的内容时，原文保存在 original_content 中
 // @Tags 明信片
 // @Accept json
 // @Produce json
@@ -220,6 +525,11 @@ func (h *PostcardHandler) GetConversation(c *gin.Context) {
 		return
 	}
 
+	if _, err := h.policyService.AuthorizeConversation(userID, conversationID); err != nil {
+		c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+		return
+	}
+
 	postcards, err := h.postcardService.GetConversation(conversationID, userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
@@ -240,12 +550,23 @@ func (h *PostcardHandler) GetConversation(c *gin.Context) {
 // @Failure 404 {object} models.APIResponse
 // @Router /api/postcards/conversation/{conversation_id} [get]
 func (h *PostcardHandler) GetPostcardsByConversationID(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
 	conversationID := c.Param("conversation_id")
 	if conversationID == "" {
 		c.JSON(http.StatusBadRequest, models.Error(400, "Conversation ID is required"))
 		return
 	}
 
+	if _, err := h.policyService.AuthorizeConversation(userID, conversationID); err != nil {
+		c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+		return
+	}
+
 	postcards, err := h.postcardService.GetPostcardsByConversationID(conversationID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"memory-postcard-backend/internal/middleware"
+	"memory-postcard-backend/internal/models"
+	"memory-postcard-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PrintHandler struct {
+	printService *services.PrintService
+}
+
+func NewPrintHandler(printService *services.PrintService) *PrintHandler {
+	return &PrintHandler{
+		printService: printService,
+	}
+}
+
+// CreatePostcardPrint 导出明信片印刷版 PDF
+// @Summary 导出明信片印刷版 PDF
+// @Description 异步生成单张明信片的印刷版 PDF：A6 横版（148x105mm），正反两页，带 3mm 出血和裁切线。返回导出任务，通过任务状态接口获取下载地址
+// @Tags 印刷导出
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "明信片ID"
+// @Success 200 {object} models.APIResponse{data=models.PrintJob}
+// @Failure 404 {object} models.APIResponse
+// @Router /api/postcards/{id}/print [post]
+func (h *PrintHandler) CreatePostcardPrint(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid postcard ID"))
+		return
+	}
+
+	job, err := h.printService.CreatePostcardPrint(uint(id), userID)
+	if err != nil {
+		if err.Error() == "postcard not found" {
+			c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(job))
+}
+
+// CreateBookletPrint 导出对话纪念册 PDF
+// @Summary 导出对话纪念册 PDF
+// @Description 异步生成整段对话的纪念册 PDF：A5 竖版，包含封面、目录，之后每页上下排列一张明信片的正反面。返回导出任务，通过任务状态接口获取下载地址
+// @Tags 印刷导出
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "对话ID"
+// @Success 200 {object} models.APIResponse{data=models.PrintJob}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /api/conversations/{id}/print [post]
+func (h *PrintHandler) CreateBookletPrint(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	job, err := h.printService.CreateBookletPrint(c.Param("id"), userID)
+	if err != nil {
+		if err.Error() == "conversation not found" {
+			c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+			return
+		}
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(job))
+}
+
+// GetPrintJob 获取导出任务状态
+// @Summary 获取导出任务状态
+// @Description 获取印刷导出任务状态：pending、processing、completed（file_url 为 PDF 下载地址）、failed（error 为失败原因）
+// @Tags 印刷导出
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "任务ID"
+// @Success 200 {object} models.APIResponse{data=models.PrintJob}
+// @Failure 404 {object} models.APIResponse
+// @Router /api/prints/{id} [get]
+func (h *PrintHandler) GetPrintJob(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid print job ID"))
+		return
+	}
+
+	job, err := h.printService.GetPrintJob(uint(id), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(job))
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"memory-postcard-backend/internal/middleware"
+	"memory-postcard-backend/internal/models"
+	"memory-postcard-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReminderHandler struct {
+	reminderService *services.ReminderService
+}
+
+func NewReminderHandler(reminderService *services.ReminderService) *ReminderHandler {
+	return &ReminderHandler{
+		reminderService: reminderService,
+	}
+}
+
+// GetStreak 获取连续写明信片天数
+// @Summary 获取连续写明信片天数
+// @Description 获取用户连续写明信片的天数、最长记录和累计天数，日期按用户资料中的时区划分。今天还没写时，截至昨天的连续记录仍然有效
+// @Tags 提醒
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.APIResponse{data=models.StreakResponse}
+// @Router /api/streak [get]
+func (h *ReminderHandler) GetStreak(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	streak, err := h.reminderService.GetStreak(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(streak))
+}
+
+// ListReminders 获取我的提醒
+// @Summary 获取我的提醒
+// @Description 获取当前用户设置的写明信片提醒
+// @Tags 提醒
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.APIResponse{data=[]models.ReminderSchedule}
+// @Router /api/reminders [get]
+func (h *ReminderHandler) ListReminders(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	reminders, err := h.reminderService.ListReminders(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(reminders))
+}
+
+// CreateReminder 创建提醒
+// @Summary 创建提醒
+// @Description 在每周指定的几天、用户时区的指定时间发送写明信片提醒，以站内通知发送，可选同时发送邮件。每个用户最多 10 个提醒
+// @Tags 提醒
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.ReminderCreateRequest true "提醒设置"
+// @Success 200 {object} models.APIResponse{data=models.ReminderSchedule}
+// @Failure 400 {object} models.APIResponse
+// @Router /api/reminders [post]
+func (h *ReminderHandler) CreateReminder(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	var req models.ReminderCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	reminder, err := h.reminderService.CreateReminder(userID, &req)
+	if err != nil {
+		if err.Error() == "too many reminders" {
+			c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(reminder))
+}
+
+// UpdateReminder 更新提醒
+// @Summary 更新提醒
+// @Description 更新提醒设置，未提供的字段保持不变
+// @Tags 提醒
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "提醒ID"
+// @Param request body models.ReminderUpdateRequest true "提醒设置"
+// @Success 200 {object} models.APIResponse{data=models.ReminderSchedule}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /api/reminders/{id} [put]
+func (h *ReminderHandler) UpdateReminder(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid reminder ID"))
+		return
+	}
+
+	var req models.ReminderUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	reminder, err := h.reminderService.UpdateReminder(uint(id), userID, &req)
+	if err != nil {
+		if err.Error() == "reminder not found" {
+			c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(reminder))
+}
+
+// DeleteReminder 删除提醒
+// @Summary 删除提醒
+// @Description 删除提醒
+// @Tags 提醒
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "提醒ID"
+// @Success 200 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /api/reminders/{id} [delete]
+func (h *ReminderHandler) DeleteReminder(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid reminder ID"))
+		return
+	}
+
+	if err := h.reminderService.DeleteReminder(uint(id), userID); err != nil {
+		if err.Error() == "reminder not found" {
+			c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(nil))
+}
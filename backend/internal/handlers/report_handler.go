@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"memory-postcard-backend/internal/middleware"
+	"memory-postcard-backend/internal/models"
+	"memory-postcard-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReportHandler struct {
+	moderationService *services.ModerationService
+}
+
+func NewReportHandler(moderationService *services.ModerationService) *ReportHandler {
+	return &ReportHandler{
+		moderationService: moderationService,
+	}
+}
+
+// CreateReport 举报内容
+// @Summary 举报内容
+// @Description 举报角色、明信片、用户或媒体文件。举报媒体文件时需提供 target_url，其他类型需提供 target_id
+// @Tags 举报
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.ReportCreateRequest true "举报信息"
+// @Success 200 {object} models.APIResponse{data=models.Report}
+// @Failure 400 {object} models.APIResponse
+// @Router /api/reports [post]
+func (h *ReportHandler) CreateReport(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	var req models.ReportCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	report, err := h.moderationService.CreateReport(userID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(report))
+}
+
+// ListMyReports 获取我的举报
+// @Summary 获取我的举报
+// @Description 获取当前用户提交的举报及处理结果
+// @Tags 举报
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页数量" default(20)
+// @Param status query string false "状态" Enums(pending,reviewing,resolved,rejected)
+// @Success 200 {object} models.APIResponse{data=models.PaginatedResponse}
+// @Router /api/reports/mine [get]
+func (h *ReportHandler) ListMyReports(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	var query models.ReportListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	result, err := h.moderationService.ListMyReports(userID, &query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(result))
+}
+
+// ListReports 获取审核队列
+// @Summary 获取审核队列
+// @Description 按提交时间先后获取举报列表（仅管理员）
+// @Tags 内容审核
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页数量" default(20)
+// @Param status query string false "状态" Enums(pending,reviewing,resolved,rejected)
+// @Param target_type query string false "举报对象类型" Enums(character,postcard,user,media)
+// @Success 200 {object} models.APIResponse{data=models.PaginatedResponse}
+// @Failure 403 {object} models.APIResponse
+// @Router /api/admin/reports [get]
+func (h *ReportHandler) ListReports(c *gin.Context) {
+	var query models.ReportListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	result, err := h.moderationService.ListReports(&query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(result))
+}
+
+// GetReport 获取举报详情
+// @Summary 获取举报详情
+// @Description 获取举报详情（仅管理员）
+// @Tags 内容审核
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "举报ID"
+// @Success 200 {object} models.APIResponse{data=models.Report}
+// @Failure 404 {object} models.APIResponse
+// @Router /api/admin/reports/{id} [get]
+func (h *ReportHandler) GetReport(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid report ID"))
+		return
+	}
+
+	report, err := h.moderationService.GetReport(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(report))
+}
+
+// ReviewReport 审核举报
+// @Summary 审核举报
+// @Description 更新举报状态（仅管理员）。status 为 resolved 且 action 为 hide 时会隐藏被举报内容，结案后通知举报人
+// @Tags 内容审核
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "举报ID"
+// @Param request body models.ReportReviewRequest true "审核结果"
+// @Success 200 {object} models.APIResponse{data=models.Report}
+// @Failure 400 {object} models.APIResponse
+// @Router /api/admin/reports/{id} [put]
+func (h *ReportHandler) ReviewReport(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid report ID"))
+		return
+	}
+
+	var req models.ReportReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	report, err := h.moderationService.ReviewReport(uint(id), userID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(report))
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"memory-postcard-backend/internal/middleware"
+	"memory-postcard-backend/internal/models"
+	"memory-postcard-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReviewHandler struct {
+	reviewService *services.ReviewService
+}
+
+func NewReviewHandler(reviewService *services.ReviewService) *ReviewHandler {
+	return &ReviewHandler{
+		reviewService: reviewService,
+	}
+}
+
+// ListReviews 获取角色评价列表
+// @Summary 获取角色评价列表
+// @Description 分页获取角色的评分和评价
+// @Tags 角色评价
+// @Produce json
+// @Param id path int true "角色ID"
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页数量" default(20)
+// @Param rating query int false "按评分筛选"
+// @Param sort_by query string false "排序字段" default(created_at) Enums(created_at,rating)
+// @Param sort_order query string false "排序方向" default(desc) Enums(asc,desc)
+// @Success 200 {object} models.APIResponse{data=models.PaginatedResponse}
+// @Failure 404 {object} models.APIResponse
+// @Router /api/characters/{id}/reviews [get]
+func (h *ReviewHandler) ListReviews(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid character ID"))
+		return
+	}
+
+	var query models.ReviewListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	// 获取当前用户ID（可选）
+	userID, _ := middleware.GetCurrentUserID(c)
+	var userIDPtr *uint
+	if userID != 0 {
+		userIDPtr = &userID
+	}
+
+	result, err := h.reviewService.ListReviews(uint(id), userIDPtr, &query)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(result))
+}
+
+// GetMyReview 获取我的评价
+// @Summary 获取我的评价
+// @Description 获取当前用户对角色的评价
+// @Tags 角色评价
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "角色ID"
+// @Success 200 {object} models.APIResponse{data=models.CharacterReview}
+// @Failure 404 {object} models.APIResponse
+// @Router /api/characters/{id}/review [get]
+func (h *ReviewHandler) GetMyReview(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid character ID"))
+		return
+	}
+
+	review, err := h.reviewService.GetMyReview(userID, uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(review))
+}
+
+// UpsertReview 评价角色
+// @Summary 评价角色
+// @Description 为角色打 1-5 星并可附带文字评价。每个用户对每个角色只有一条评价，重复提交会覆盖之前的评价
+// @Tags 角色评价
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "角色ID"
+// @Param request body models.ReviewUpsertRequest true "评价信息"
+// @Success 200 {object} models.APIResponse{data=models.CharacterReview}
+// @Failure 400 {object} models.APIResponse
+// @Router /api/characters/{id}/review [put]
+func (h *ReviewHandler) UpsertReview(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid character ID"))
+		return
+	}
+
+	var req models.ReviewUpsertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	review, err := h.reviewService.UpsertReview(userID, uint(id), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(review))
+}
+
+// DeleteReview 删除我的评价
+// @Summary 删除我的评价
+// @Description 删除当前用户对角色的评价
+// @Tags 角色评价
+// @Security BearerAuth
+// @Param id path int true "角色ID"
+// @Success 200 {object} models.APIResponse
+// @Failure 400 {object} models.APIResponse
+// @Router /api/characters/{id}/review [delete]
+func (h *ReviewHandler) DeleteReview(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid character ID"))
+		return
+	}
+
+	if err := h.reviewService.DeleteReview(userID, uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(nil))
+}
+
+// ReplyToReview 回复评价
+// @Summary 回复评价
+// @Description 角色创建者回复用户的评价（仅创建者可操作），再次回复会覆盖之前的回复
+// @Tags 角色评价
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "角色ID"
+// @Param review_id path int true "评价ID"
+// @Param request body models.ReviewReplyRequest true "回复内容"
+// @Success 200 {object} models.APIResponse{data=models.CharacterReview}
+// @Failure 400 {object} models.APIResponse
+// @Router /api/characters/{id}/reviews/{review_id}/reply [put]
+func (h *ReviewHandler) ReplyToReview(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid character ID"))
+		return
+	}
+
+	reviewIDStr := c.Param("review_id")
+	reviewID, err := strconv.ParseUint(reviewIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid review ID"))
+		return
+	}
+
+	var req models.ReviewReplyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	review, err := h.reviewService.ReplyToReview(userID, uint(id), uint(reviewID), &req)
+	if err != nil {
+		if err.Error() == "permission denied" {
+			c.JSON(http.StatusForbidden, models.Error(403, err.Error()))
+			return
+		}
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(review))
+}
@@ -0,0 +1,341 @@
+package handlers
+
+import (
+	"html/template"
+	"memory-postcard-backend/internal/middleware"
+	"memory-postcard-backend/internal/models"
+	"memory-postcard-backend/internal/services"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// shareDescriptionLength Open Graph 描述截取的字数
+const shareDescriptionLength = 100
+
+type ShareHandler struct {
+	shareService  *services.ShareService
+	publicBaseURL string
+}
+
+func NewShareHandler(shareService *services.ShareService, publicBaseURL string) *ShareHandler {
+	return &ShareHandler{
+		shareService:  shareService,
+		publicBaseURL: strings.TrimRight(publicBaseURL, "/"),
+	}
+}
+
+// CreatePostcardShare 分享明信片
+// @Summary 分享明信片
+// @Description 为单张明信片创建公开分享链接，任何人无需登录即可通过链接查看。可设置有效期，也可随时撤销
+// @Tags 分享
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "明信片ID"
+// @Param request body models.ShareLinkCreateRequest true "分享设置"
+// @Success 200 {object} models.APIResponse{data=models.ShareLink}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /api/postcards/{id}/share [post]
+func (h *ShareHandler) CreatePostcardShare(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid postcard ID"))
+		return
+	}
+
+	var req models.ShareLinkCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	share, err := h.shareService.CreatePostcardShare(uint(id), userID, &req)
+	if err != nil {
+		if err.Error() == "postcard not found" {
+			c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	share.URL = h.shareURL(c, share.Token)
+	c.JSON(http.StatusOK, models.Success(share))
+}
+
+// CreateConversationShare 分享对话中的明信片
+// @Summary 分享对话中的明信片
+// @Description 从对话中选择若干明信片（最多 20 张）创建公开分享链接，分享页按时间顺序展示
+// @Tags 分享
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "对话ID"
+// @Param request body models.ConversationShareCreateRequest true "分享设置"
+// @Success 200 {object} models.APIResponse{data=models.ShareLink}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /api/conversations/{id}/share [post]
+func (h *ShareHandler) CreateConversationShare(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	var req models.ConversationShareCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	share, err := h.shareService.CreateConversationShare(c.Param("id"), userID, &req)
+	if err != nil {
+		if err.Error() == "conversation not found" || err.Error() == "postcard not found" {
+			c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	share.URL = h.shareURL(c, share.Token)
+	c.JSON(http.StatusOK, models.Success(share))
+}
+
+// ListShareLinks 获取我的分享链接
+// @Summary 获取我的分享链接
+// @Description 获取当前用户创建的全部分享链接及浏览次数，包括已撤销和已过期的链接
+// @Tags 分享
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.APIResponse{data=[]models.ShareLink}
+// @Router /api/shares [get]
+func (h *ShareHandler) ListShareLinks(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	shares, err := h.shareService.ListShareLinks(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	for i := range shares {
+		shares[i].URL = h.shareURL(c, shares[i].Token)
+	}
+	c.JSON(http.StatusOK, models.Success(shares))
+}
+
+// RevokeShareLink 撤销分享链接
+// @Summary 撤销分享链接
+// @Description 撤销分享链接，撤销后通过该链接将无法再查看明信片
+// @Tags 分享
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "分享链接ID"
+// @Success 200 {object} models.APIResponse{data=models.ShareLink}
+// @Failure 404 {object} models.APIResponse
+// @Router /api/shares/{id} [delete]
+func (h *ShareHandler) RevokeShareLink(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid share link ID"))
+		return
+	}
+
+	share, err := h.shareService.RevokeShareLink(uint(id), userID)
+	if err != nil {
+		if err.Error() == "share link not found" {
+			c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	share.URL = h.shareURL(c, share.Token)
+	c.JSON(http.StatusOK, models.Success(share))
+}
+
+// ViewShare 查看分享的明信片
+// @Summary 查看分享的明信片
+// @Description 无需登录。默认返回带 Open Graph 元数据的只读 HTML 页面，便于在社交平台预览；请求头 Accept 为 application/json 时返回 JSON。链接已撤销或过期时返回 410
+// @Tags 分享
+// @Produce html,json
+// @Param token path string true "分享 token"
+// @Success 200 {object} models.APIResponse{data=models.SharedView}
+// @Failure 404 {object} models.APIResponse
+// @Failure 410 {object} models.APIResponse
+// @Router /s/{token} [get]
+func (h *ShareHandler) ViewShare(c *gin.Context) {
+	wantJSON := c.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) == gin.MIMEJSON
+
+	view, err := h.shareService.ViewShare(c.Param("token"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch err.Error() {
+		case "share link not found":
+			status = http.StatusNotFound
+		case "share link expired":
+			status = http.StatusGone
+		}
+		if wantJSON {
+			c.JSON(status, models.Error(status, err.Error()))
+			return
+		}
+		h.renderSharePage(c, status, sharePageData{Error: "分享链接不存在或已失效"})
+		return
+	}
+
+	if wantJSON {
+		c.JSON(http.StatusOK, models.Success(view))
+		return
+	}
+
+	data := sharePageData{
+		View:        view,
+		URL:         h.shareURL(c, c.Param("token")),
+		Description: shareDescription(view.Postcards[0].Content),
+	}
+	for _, postcard := range view.Postcards {
+		if postcard.BackImageURL != "" {
+			data.Image = postcard.BackImageURL
+			break
+		}
+	}
+	h.renderSharePage(c, http.StatusOK, data)
+}
+
+// shareURL 分享页的完整地址，未配置 PUBLIC_BASE_URL 时使用当前请求的 Host
+func (h *ShareHandler) shareURL(c *gin.Context, token string) string {
+	base := h.publicBaseURL
+	if base == "" {
+		scheme := "http"
+		if c.Request.TLS != nil {
+			scheme = "https"
+		}
+		if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+			scheme = proto
+		}
+		base = scheme + "://" + c.Request.Host
+	}
+	return base + "/s/" + token
+}
+
+// shareDescription 截取明信片内容作为 Open Graph 描述
+func shareDescription(content string) string {
+	content = strings.Join(strings.Fields(content), " ")
+	runes := []rune(content)
+	if len(runes) <= shareDescriptionLength {
+		return content
+	}
+	return string(runes[:shareDescriptionLength]) + "…"
+}
+
+// sharePageData 分享页模板数据
+type sharePageData struct {
+	View        *models.SharedView
+	URL         string
+	Description string
+	Image       string
+	Error       string
+}
+
+func (h *ShareHandler) renderSharePage(c *gin.Context, status int, data sharePageData) {
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Header("X-Robots-Tag", "noindex")
+	c.Status(status)
+	if err := sharePageTemplate.Execute(c.Writer, data); err != nil {
+		c.Error(err)
+	}
+}
+
+// sharePageTemplate 分享页，html/template 会对所有内容转义
+var sharePageTemplate = template.Must(template.New("share").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+{{- if .View}}
+<title>{{.View.Title}}</title>
+<meta name="description" content="{{.Description}}">
+<meta property="og:type" content="article">
+<meta property="og:site_name" content="Memory Postcard">
+<meta property="og:title" content="{{.View.Title}}">
+<meta property="og:description" content="{{.Description}}">
+<meta property="og:url" content="{{.URL}}">
+{{- if .Image}}
+<meta property="og:image" content="{{.Image}}">
+<meta property="og:image:width" content="1748">
+<meta property="og:image:height" content="1240">
+<meta name="twitter:card" content="summary_large_image">
+{{- else}}
+<meta name="twitter:card" content="summary">
+{{- end}}
+{{- else}}
+<title>Memory Postcard</title>
+{{- end}}
+<style>
+body{margin:0;background:#f3efe6;color:#332d28;font-family:"Noto Serif SC","Songti SC",serif}
+main{max-width:760px;margin:0 auto;padding:32px 16px}
+header{display:flex;align-items:center;gap:12px;margin-bottom:24px}
+header img{width:56px;height:56px;border-radius:50%;object-fit:cover}
+h1{font-size:22px;margin:0}
+.meta{color:#8a7f74;font-size:13px}
+article{background:#faf6ee;border-radius:8px;box-shadow:0 1px 4px rgba(0,0,0,.08);padding:20px;margin-bottom:24px}
+article img{width:100%;border-radius:4px;display:block;margin-bottom:12px}
+.sender{font-weight:bold;margin-bottom:8px}
+.content{white-space:pre-wrap;line-height:1.8}
+.empty{text-align:center;padding:80px 16px;color:#8a7f74}
+</style>
+</head>
+<body>
+<main>
+{{- if .View}}
+<header>
+{{- if .View.Character.AvatarURL}}<img src="{{.View.Character.AvatarURL}}" alt="{{.View.Character.Name}}">{{end}}
+<div>
+<h1>{{.View.Title}}</h1>
+<div class="meta">分享于 {{.View.SharedAt.Format "2006-01-02"}} · {{.View.ViewCount}} 次浏览</div>
+</div>
+</header>
+{{- range .View.Postcards}}
+<article>
+{{- if .FrontImageURL}}<img src="{{.FrontImageURL}}" alt="明信片正面">{{end}}
+{{- if .BackImageURL}}<img src="{{.BackImageURL}}" alt="明信片背面">{{else}}
+<div class="sender">{{.Sender}}</div>
+<div class="content">{{.Content}}</div>
+{{- end}}
+<div class="meta">{{.Sender}} · {{.CreatedAt.Format "2006-01-02 15:04"}}</div>
+</article>
+{{- end}}
+{{- else}}
+<div class="empty">{{.Error}}</div>
+{{- end}}
+</main>
+</body>
+</html>
+`))
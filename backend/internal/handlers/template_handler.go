@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"memory-postcard-backend/internal/models"
+	"memory-postcard-backend/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TemplateHandler struct {
+	templateService *services.TemplateService
+}
+
+func NewTemplateHandler(templateService *services.TemplateService) *TemplateHandler {
+	return &TemplateHandler{
+		templateService: templateService,
+	}
+}
+
+// ListTemplates 获取明信片模板列表
+// @Summary 获取明信片模板列表
+// @Description 获取所有可用的明信片模板，按排序值升序
+// @Tags 明信片模板
+// @Produce json
+// @Success 200 {object} models.APIResponse{data=[]models.PostcardTemplate}
+// @Router /api/templates [get]
+func (h *TemplateHandler) ListTemplates(c *gin.Context) {
+	templates, err := h.templateService.ListTemplates(false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(templates))
+}
+
+// GetTemplate 获取明信片模板详情
+// @Summary 获取明信片模板详情
+// @Description 获取可用模板的布局、字体和文字区域
+// @Tags 明信片模板
+// @Produce json
+// @Param id path string true "模板ID"
+// @Success 200 {object} models.APIResponse{data=models.PostcardTemplate}
+// @Failure 404 {object} models.APIResponse
+// @Router /api/templates/{id} [get]
+func (h *TemplateHandler) GetTemplate(c *gin.Context) {
+	template, err := h.templateService.GetTemplate(c.Param("id"), false)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(template))
+}
+
+// AdminListTemplates 获取全部明信片模板
+// @Summary 获取全部明信片模板
+// @Description 获取包括已下架模板在内的全部模板（仅管理员）
+// @Tags 明信片模板
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.APIResponse{data=[]models.PostcardTemplate}
+// @Failure 403 {object} models.APIResponse
+// @Router /api/admin/templates [get]
+func (h *TemplateHandler) AdminListTemplates(c *gin.Context) {
+	templates, err := h.templateService.ListTemplates(true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(templates))
+}
+
+// CreateTemplate 创建明信片模板
+// @Summary 创建明信片模板
+// @Description 创建明信片模板（仅管理员）。ID 只能包含小写字母、数字、下划线和连字符，颜色格式为 #RRGGBB，文字区域坐标以 1748x1240 画布为准
+// @Tags 明信片模板
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.TemplateCreateRequest true "模板信息"
+// @Success 200 {object} models.APIResponse{data=models.PostcardTemplate}
+// @Failure 400 {object} models.APIResponse
+// @Failure 409 {object} models.APIResponse
+// @Router /api/admin/templates [post]
+func (h *TemplateHandler) CreateTemplate(c *gin.Context) {
+	var req models.TemplateCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	template, err := h.templateService.CreateTemplate(&req)
+	if err != nil {
+		if err.Error() == "template already exists" {
+			c.JSON(http.StatusConflict, models.Error(409, err.Error()))
+			return
+		}
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(template))
+}
+
+// UpdateTemplate 更新明信片模板
+// @Summary 更新明信片模板
+// @Description 更新明信片模板（仅管理员），未提供的字段保持不变。下架后模板不能再用于新明信片，已有明信片不受影响
+// @Tags 明信片模板
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "模板ID"
+// @Param request body models.TemplateUpdateRequest true "模板信息"
+// @Success 200 {object} models.APIResponse{data=models.PostcardTemplate}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /api/admin/templates/{id} [put]
+func (h *TemplateHandler) UpdateTemplate(c *gin.Context) {
+	var req models.TemplateUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	template, err := h.templateService.UpdateTemplate(c.Param("id"), &req)
+	if err != nil {
+		if err.Error() == "template not found" {
+			c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+			return
+		}
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(template))
+}
+
+// DeleteTemplate 删除明信片模板
+// @Summary 删除明信片模板
+// @Description 删除明信片模板（仅管理员），已使用该模板的明信片仍按原模板渲染
+// @Tags 明信片模板
+// @Security BearerAuth
+// @Param id path string true "模板ID"
+// @Success 200 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /api/admin/templates/{id} [delete]
+func (h *TemplateHandler) DeleteTemplate(c *gin.Context) {
+	if err := h.templateService.DeleteTemplate(c.Param("id")); err != nil {
+		if err.Error() == "template not found" {
+			c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(nil))
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"memory-postcard-backend/internal/middleware"
+	"memory-postcard-backend/internal/models"
+	"memory-postcard-backend/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TimelineHandler struct {
+	timelineService *services.TimelineService
+}
+
+func NewTimelineHandler(timelineService *services.TimelineService) *TimelineHandler {
+	return &TimelineHandler{
+		timelineService: timelineService,
+	}
+}
+
+// GetTimeline 获取明信片时间线
+// @Summary 获取明信片时间线
+// @Description 按日、月或年分组获取用户与所有角色往来的明信片，从新到旧排列，可按日期范围和角色筛选。日期按用户资料中的时区划分。分页以明信片为单位，同一时间段可能跨页出现，count 为该时间段的总数
+// @Tags 时间线
+// @Produce json
+// @Security BearerAuth
+// @Param group_by query string false "分组方式" Enums(day,month,year) default(day)
+// @Param from query string false "起始日期（含），格式 2006-01-02"
+// @Param to query string false "结束日期（含），格式 2006-01-02"
+// @Param character_id query int false "角色ID"
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页数量" default(20)
+// @Success 200 {object} models.APIResponse{data=models.TimelineResponse}
+// @Failure 400 {object} models.APIResponse
+// @Router /api/timeline [get]
+func (h *TimelineHandler) GetTimeline(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	var query models.TimelineQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	timeline, err := h.timelineService.GetTimeline(userID, &query)
+	if err != nil {
+		if err.Error() == "invalid date range" {
+			c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(timeline))
+}
+
+// GetOnThisDay 那年今日
+// @Summary 那年今日
+// @Description 获取往年同月同日往来的明信片，按年份从近到远排列。默认日期为用户时区的今天
+// @Tags 时间线
+// @Produce json
+// @Security BearerAuth
+// @Param date query string false "日期，格式 2006-01-02"
+// @Param character_id query int false "角色ID"
+// @Success 200 {object} models.APIResponse{data=models.OnThisDayResponse}
+// @Failure 400 {object} models.APIResponse
+// @Router /api/timeline/on-this-day [get]
+func (h *TimelineHandler) GetOnThisDay(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	var query models.OnThisDayQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	result, err := h.timelineService.GetOnThisDay(userID, &query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(result))
+}
+
+// GetCalendar 获取日历热力图数据
+// @Summary 获取日历热力图数据
+// @Description 获取一年中每天往来的明信片数量，只返回有明信片的日期。默认年份为用户时区的今年
+// @Tags 时间线
+// @Produce json
+// @Security BearerAuth
+// @Param year query int false "年份"
+// @Param character_id query int false "角色ID"
+// @Success 200 {object} models.APIResponse{data=models.CalendarResponse}
+// @Failure 400 {object} models.APIResponse
+// @Router /api/timeline/calendar [get]
+func (h *TimelineHandler) GetCalendar(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	var query models.CalendarQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	calendar, err := h.timelineService.GetCalendar(userID, &query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(calendar))
+}
@@ -31,7 +31,7 @@ func NewUploadHandler(uploadService *services.UploadService) *UploadHandler {
 // @Failure 400 {object} models.APIResponse
 // @Router /api/upload/image [post]
 func (h *UploadHandler) UploadImage(c *gin.Context) {
-	_, exists := middleware.GetCurrentUserID(c)
+	userID, exists := middleware.GetCurrentUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
 		return
@@ -49,7 +49,7 @@ func (h *UploadHandler) UploadImage(c *gin.Context) {
 		return
 	}
 
-	response, err := h.uploadService.UploadImage(file)
+	response, err := h.uploadService.UploadImage(userID, file)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
 		return
@@ -70,7 +70,7 @@ func (h *UploadHandler) UploadImage(c *gin.Context) {
 // @Failure 400 {object} models.APIResponse
 // @Router /api/upload/avatar [post]
 func (h *UploadHandler) UploadAvatar(c *gin.Context) {
-	_, exists := middleware.GetCurrentUserID(c)
+	userID, exists := middleware.GetCurrentUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
 		return
@@ -82,7 +82,7 @@ func (h *UploadHandler) UploadAvatar(c *gin.Context) {
 		return
 	}
 
-	response, err := h.uploadService.UploadAvatar(file)
+	response, err := h.uploadService.UploadAvatar(userID, file)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
 		return
@@ -103,7 +103,7 @@ func (h *UploadHandler) UploadAvatar(c *gin.Context) {
 // @Failure 400 {object} models.APIResponse
 // @Router /api/upload/audio [post]
 func (h *UploadHandler) UploadAudio(c *gin.Context) {
-	_, exists := middleware.GetCurrentUserID(c)
+	userID, exists := middleware.GetCurrentUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
 		return
@@ -121,7 +121,7 @@ func (h *UploadHandler) UploadAudio(c *gin.Context) {
 		return
 	}
 
-	response, err := h.uploadService.UploadAudio(file)
+	response, err := h.uploadService.UploadAudio(userID, file)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
 		return
@@ -142,7 +142,7 @@ func (h *UploadHandler) UploadAudio(c *gin.Context) {
 // @Failure 400 {object} models.APIResponse
 // @Router /api/upload/character-avatar [post]
 func (h *UploadHandler) UploadCharacterAvatar(c *gin.Context) {
-	_, exists := middleware.GetCurrentUserID(c)
+	userID, exists := middleware.GetCurrentUserID(c)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
 		return
@@ -154,7 +154,7 @@ func (h *UploadHandler) UploadCharacterAvatar(c *gin.Context) {
 		return
 	}
 
-	response, err := h.uploadService.UploadAvatar(file)
+	response, err := h.uploadService.UploadAvatar(userID, file)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
 		return
@@ -162,3 +162,115 @@ func (h *UploadHandler) UploadCharacterAvatar(c *gin.Context) {
 
 	c.JSON(http.StatusOK, models.Success(response))
 }
+
+// PresignUpload 申请直传地址
+// @Summary 申请直传地址
+// @Description 返回直传存储的 PUT 地址，文件不经过 API 服务。上传时须带上返回的 Content-Type 请求头，文件大小必须与申请的 size 一致，地址 15 分钟内有效。
+// @Description 上传完成后调用 /api/upload/confirm 校验并登记文件。image 最大 50MB，avatar、character-avatar 最大 10MB，audio 最大 50MB
+// @Tags 文件上传
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.UploadPresignRequest true "文件信息"
+// @Success 200 {object} models.APIResponse{data=models.UploadPresignResponse}
+// @Failure 400 {object} models.APIResponse
+// @Router /api/upload/presign [post]
+func (h *UploadHandler) PresignUpload(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	var req models.UploadPresignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	response, err := h.uploadService.PresignUpload(userID, &req)
+	if err != nil {
+		if err.Error() == "invalid content type" || err.Error() == "file size too large" {
+			c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(response))
+}
+
+// ConfirmUpload 确认直传完成
+// @Summary 确认直传完成
+// @Description 校验直传的文件：大小与申请时一致，文件头与声明的类型一致，图片可以解码且尺寸不超过 10000 像素。校验不通过的文件会被删除
+// @Tags 文件上传
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.UploadConfirmRequest true "对象名称"
+// @Success 200 {object} models.APIResponse{data=models.UploadResponse}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /api/upload/confirm [post]
+func (h *UploadHandler) ConfirmUpload(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	var req models.UploadConfirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	response, err := h.uploadService.ConfirmUpload(userID, &req)
+	if err != nil {
+		switch err.Error() {
+		case "upload not found":
+			c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+		case "upload not completed", "file size does not match", "file content does not match content type",
+			"invalid image", "image dimensions too large":
+			c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		default:
+			c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(response))
+}
+
+// CollectGarbage 清理无引用的上传文件
+// @Summary 清理无引用的上传文件
+// @Description 扫描用户上传的图片、头像和音频，上传超过保留期（UPLOAD_GC_GRACE_PERIOD）且没有被用户、角色、明信片、草稿、模板或举报引用的文件视为无引用。
+// @Description 默认只统计不删除，dry_run=false 时删除这些文件（仅管理员）
+// @Tags 文件上传
+// @Produce json
+// @Security BearerAuth
+// @Param dry_run query bool false "只统计不删除" default(true)
+// @Success 200 {object} models.APIResponse{data=models.UploadGCReport}
+// @Failure 403 {object} models.APIResponse
+// @Router /api/admin/uploads/gc [post]
+func (h *UploadHandler) CollectGarbage(c *gin.Context) {
+	var query models.UploadGCQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	dryRun := true
+	if query.DryRun != nil {
+		dryRun = *query.DryRun
+	}
+
+	report, err := h.uploadService.CollectGarbage(dryRun)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(report))
+}
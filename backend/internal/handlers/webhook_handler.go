@@ -0,0 +1,336 @@
+package handlers
+
+import (
+	"memory-postcard-backend/internal/middleware"
+	"memory-postcard-backend/internal/models"
+	"memory-postcard-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookHandler struct {
+	webhookService *services.WebhookService
+}
+
+func NewWebhookHandler(webhookService *services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+	}
+}
+
+// ListWebhooks 获取我的 Webhook
+// @Summary 获取我的 Webhook
+// @Description 获取当前用户注册的 Webhook，不包含签名密钥
+// @Tags Webhook
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.APIResponse{data=[]models.WebhookEndpoint}
+// @Router /api/webhooks [get]
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	webhooks, err := h.webhookService.ListWebhooks(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(webhooks))
+}
+
+// CreateWebhook 注册 Webhook
+// @Summary 注册 Webhook
+// @Description 注册接收事件推送的地址，可订阅 postcard.created、postcard.ai_replied、character.updated。
+// @Description 推送为 POST JSON 请求，X-Webhook-Signature 头为 t=时间戳,v1=签名，签名是以密钥对「时间戳.请求体」计算的 HMAC-SHA256 十六进制值。
+// @Description 签名密钥只在创建时返回，每个用户最多 10 个 Webhook
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.WebhookCreateRequest true "Webhook 设置"
+// @Success 200 {object} models.APIResponse{data=models.WebhookCreateResponse}
+// @Failure 400 {object} models.APIResponse
+// @Router /api/webhooks [post]
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	var req models.WebhookCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	webhook, err := h.webhookService.CreateWebhook(userID, &req)
+	if err != nil {
+		switch err.Error() {
+		case "invalid webhook url", "invalid webhook event", "too many webhooks":
+			c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		default:
+			c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(webhook))
+}
+
+// UpdateWebhook 更新 Webhook
+// @Summary 更新 Webhook
+// @Description 更新 Webhook 设置，未提供的字段保持不变。停用后不再推送新事件，也不再重试未完成的推送
+// @Tags Webhook
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Webhook ID"
+// @Param request body models.WebhookUpdateRequest true "Webhook 设置"
+// @Success 200 {object} models.APIResponse{data=models.WebhookEndpoint}
+// @Failure 400 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /api/webhooks/{id} [put]
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid webhook ID"))
+		return
+	}
+
+	var req models.WebhookUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	webhook, err := h.webhookService.UpdateWebhook(uint(id), userID, &req)
+	if err != nil {
+		switch err.Error() {
+		case "webhook not found":
+			c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+		case "invalid webhook url", "invalid webhook event":
+			c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		default:
+			c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(webhook))
+}
+
+// DeleteWebhook 删除 Webhook
+// @Summary 删除 Webhook
+// @Description 删除 Webhook 及其推送记录
+// @Tags Webhook
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} models.APIResponse
+// @Failure 404 {object} models.APIResponse
+// @Router /api/webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid webhook ID"))
+		return
+	}
+
+	if err := h.webhookService.DeleteWebhook(uint(id), userID); err != nil {
+		if err.Error() == "webhook not found" {
+			c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(nil))
+}
+
+// RotateSecret 重新生成签名密钥
+// @Summary 重新生成签名密钥
+// @Description 重新生成 Webhook 的签名密钥并返回，旧密钥立即失效
+// @Tags Webhook
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} models.APIResponse{data=models.WebhookCreateResponse}
+// @Failure 404 {object} models.APIResponse
+// @Router /api/webhooks/{id}/secret [post]
+func (h *WebhookHandler) RotateSecret(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid webhook ID"))
+		return
+	}
+
+	webhook, err := h.webhookService.RotateSecret(uint(id), userID)
+	if err != nil {
+		if err.Error() == "webhook not found" {
+			c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(webhook))
+}
+
+// PingWebhook 测试推送
+// @Summary 测试推送
+// @Description 向 Webhook 发送一次 ping 事件并返回推送结果，停用的 Webhook 也可以测试
+// @Tags Webhook
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} models.APIResponse{data=models.WebhookDelivery}
+// @Failure 404 {object} models.APIResponse
+// @Router /api/webhooks/{id}/ping [post]
+func (h *WebhookHandler) PingWebhook(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid webhook ID"))
+		return
+	}
+
+	delivery, err := h.webhookService.PingWebhook(uint(id), userID)
+	if err != nil {
+		if err.Error() == "webhook not found" {
+			c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(delivery))
+}
+
+// ListDeliveries 获取推送记录
+// @Summary 获取推送记录
+// @Description 分页获取 Webhook 的推送记录，包含请求体、响应状态和错误信息，从新到旧排列
+// @Tags Webhook
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Webhook ID"
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页数量" default(20)
+// @Param status query string false "推送状态" Enums(pending, succeeded, failed)
+// @Param event query string false "事件"
+// @Success 200 {object} models.APIResponse{data=models.PaginatedResponse{items=[]models.WebhookDelivery}}
+// @Failure 404 {object} models.APIResponse
+// @Router /api/webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid webhook ID"))
+		return
+	}
+
+	var query models.WebhookDeliveryListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, err.Error()))
+		return
+	}
+
+	result, err := h.webhookService.ListDeliveries(uint(id), userID, &query)
+	if err != nil {
+		if err.Error() == "webhook not found" {
+			c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(result))
+}
+
+// Redeliver 重新推送
+// @Summary 重新推送
+// @Description 以原事件 ID 和请求体重新推送一次，生成新的推送记录并返回推送结果
+// @Tags Webhook
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Webhook ID"
+// @Param delivery_id path int true "推送记录ID"
+// @Success 200 {object} models.APIResponse{data=models.WebhookDelivery}
+// @Failure 404 {object} models.APIResponse
+// @Router /api/webhooks/{id}/deliveries/{delivery_id}/redeliver [post]
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, models.Error(401, "Unauthorized"))
+		return
+	}
+
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid webhook ID"))
+		return
+	}
+
+	deliveryIDStr := c.Param("delivery_id")
+	deliveryID, err := strconv.ParseUint(deliveryIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Error(400, "Invalid delivery ID"))
+		return
+	}
+
+	delivery, err := h.webhookService.Redeliver(uint(id), uint(deliveryID), userID)
+	if err != nil {
+		if err.Error() == "webhook not found" || err.Error() == "webhook delivery not found" {
+			c.JSON(http.StatusNotFound, models.Error(404, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.Error(500, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Success(delivery))
+}
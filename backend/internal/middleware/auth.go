@@ -60,6 +60,19 @@ func OptionalAuthMiddleware(jwtSecret string) gin.HandlerFunc {
 	}
 }
 
+// AdminMiddleware 管理员权限中间件（需在 AuthMiddleware 之后使用）
+func AdminMiddleware(isAdmin func(userID uint) bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := GetCurrentUserID(c)
+		if !exists || !isAdmin(userID) {
+			c.JSON(http.StatusForbidden, models.Error(403, "Admin permission required"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
 // GetCurrentUserID 从上下文中获取当前用户 ID
 func GetCurrentUserID(c *gin.Context) (uint, bool) {
 	userID, exists := c.Get("user_id")
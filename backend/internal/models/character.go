@@ -7,22 +7,33 @@ import (
 )
 
 type Character struct {
-	ID              uint    `json:"id" gorm:"primaryKey"`
-	CreatorID       uint    `json:"creator_id" gorm:"index"`
-	Name            string  `json:"name" gorm:"size:100;not null"`
-	Description     string  `json:"description" gorm:"type:text;not null"`
-	AvatarURL       string  `json:"avatar_url" gorm:"size:255"`
-	VoiceURL        string  `json:"voice_url" gorm:"size:255"` // 音色URL字段
-	VoiceID         string  `json:"voice_id" gorm:"size:100"`  // 音色ID字段，用于AI生成声音
-	Visibility      string  `json:"visibility" gorm:"type:enum('private','public');default:'public'"`
-	IsActive        bool    `json:"is_active" gorm:"default:true"`
-	UsageCount      int     `json:"usage_count" gorm:"default:0"`
-	PopularityScore float64 `json:"popularity_score" gorm:"type:decimal(3,2);default:0.00"`
+	ID              uint       `json:"id" gorm:"primaryKey"`
+	CreatorID       uint       `json:"creator_id" gorm:"index"`
+	Name            string     `json:"name" gorm:"size:100;not null"`
+	Description     string     `json:"description" gorm:"type:text;not null"`
+	AvatarURL       string     `json:"avatar_url" gorm:"size:255"`
+	VoiceURL        string     `json:"voice_url" gorm:"size:255"` // 音色URL字段
+	VoiceID         string     `json:"voice_id" gorm:"size:100"`  // 音色ID字段，用于AI生成声音
+	Tags            []string   `json:"tags" gorm:"type:json;serializer:json"`
+	Visibility      string     `json:"visibility" gorm:"type:enum('private','public');default:'public'"`
+	IsActive        bool       `json:"is_active" gorm:"default:true"`
+	HiddenAt        *time.Time `json:"hidden_at" gorm:"index"` // 被管理员隐藏的时间，隐藏后只有创建者可见，且不能再写明信片，创建者无法自行恢复
+	UsageCount      int        `json:"usage_count" gorm:"default:0"`
+	PopularityScore float64    `json:"popularity_score" gorm:"type:decimal(10,4);default:0.0000"`  // 由热度任务定期计算
+	RatingAverage   float64    `json:"rating_average" gorm:"type:decimal(3,2);default:0.00;index"` // 用户评价的平均分，评价增删改时更新
+	RatingCount     int        `json:"rating_count" gorm:"default:0"`
 
 	// 用户角色设定
 	UserRoleName string `json:"user_role_name" gorm:"size:50;not null"`
 	UserRoleDesc string `json:"user_role_desc" gorm:"size:400;not null"`
 
+	// 开场白与示例对话
+	Greeting         string            `json:"greeting" gorm:"type:text"` // 新对话开始时自动发送的问候明信片，支持 {{user}} 和 {{char}} 占位符
+	ExampleDialogues []ExampleDialogue `json:"example_dialogues" gorm:"type:json;serializer:json"`
+
+	// 世界设定注入 AI 上下文时的 token 上限，为 0 时不注入世界设定
+	LorebookTokenBudget int `json:"lorebook_token_budget"`
+
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
@@ -32,6 +43,14 @@ type Character struct {
 	Relations []UserCharacterRelation `json:"relations,omitempty" gorm:"foreignKey:CharacterID"`
 	Postcards []Postcard              `json:"postcards,omitempty" gorm:"foreignKey:CharacterID"`
 	Drafts    []Draft                 `json:"drafts,omitempty" gorm:"foreignKey:CharacterID"`
+	Reviews   []CharacterReview       `json:"reviews,omitempty" gorm:"foreignKey:CharacterID"`
+	Lorebook  []LorebookEntry         `json:"lorebook,omitempty" gorm:"foreignKey:CharacterID"`
+}
+
+// ExampleDialogue 示例对话，作为 few-shot 示例帮助 AI 保持角色的说话风格
+type ExampleDialogue struct {
+	User      string `json:"user" binding:"required,max=1000"`
+	Character string `json:"character" binding:"required,max=1000"`
 }
 
 type UserCharacterRelation struct {
@@ -42,34 +61,52 @@ type UserCharacterRelation struct {
 	LastInteractionAt *time.Time `json:"last_interaction_at"`
 	InteractionCount  int        `json:"interaction_count" gorm:"default:0"`
 	IsFavorite        bool       `json:"is_favorite" gorm:"default:false"`
-	CreatedAt         time.Time  `json:"created_at"`
-	UpdatedAt         time.Time  `json:"updated_at"`
+
+	// 用户为该角色设置的人设，优先于角色默认的用户角色设定
+	PersonaID   *uint  `json:"persona_id"`
+	PersonaName string `json:"persona_name" gorm:"size:50"`
+	PersonaDesc string `json:"persona_desc" gorm:"size:400"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 
 	User      User      `json:"user,omitempty" gorm:"foreignKey:UserID"`
 	Character Character `json:"character,omitempty" gorm:"foreignKey:CharacterID"`
 }
 
 type CharacterCreateRequest struct {
-	Name         string `json:"name" binding:"required,max=100"`
-	Description  string `json:"description" binding:"required"`
-	AvatarURL    string `json:"avatar_url" binding:"max=255"`
-	VoiceURL     string `json:"voice_url" binding:"max=255"`
-	VoiceID      string `json:"voice_id" binding:"max=100"` // 音色ID字段，用于AI生成声音
-	Visibility   string `json:"visibility" binding:"oneof=private public"`
-	UserRoleName string `json:"user_role_name" binding:"required,max=50"`
-	UserRoleDesc string `json:"user_role_desc" binding:"required,max=400"`
+	Name         string   `json:"name" binding:"required,max=100"`
+	Description  string   `json:"description" binding:"required"`
+	AvatarURL    string   `json:"avatar_url" binding:"max=255"`
+	VoiceURL     string   `json:"voice_url" binding:"max=255"`
+	VoiceID      string   `json:"voice_id" binding:"max=100"` // 音色ID字段，用于AI生成声音
+	Tags         []string `json:"tags" binding:"omitempty,max=10,dive,max=20"`
+	Visibility   string   `json:"visibility" binding:"oneof=private public"`
+	UserRoleName string   `json:"user_role_name" binding:"required,max=50"`
+	UserRoleDesc string   `json:"user_role_desc" binding:"required,max=400"`
+
+	Greeting         string            `json:"greeting" binding:"max=2000"`
+	ExampleDialogues []ExampleDialogue `json:"example_dialogues" binding:"omitempty,max=10,dive"`
+
+	LorebookTokenBudget *int `json:"lorebook_token_budget" binding:"omitempty,min=0,max=4000"` // 不填时默认 500，为 0 时不注入世界设定
 }
 
 type CharacterUpdateRequest struct {
-	Name         string `json:"name" binding:"max=100"`
-	Description  string `json:"description"`
-	AvatarURL    string `json:"avatar_url" binding:"max=255"`
-	VoiceURL     string `json:"voice_url" binding:"max=255"` // 音色URL字段
-	VoiceID      string `json:"voice_id" binding:"max=100"`  // 音色ID字段，用于AI生成声音
-	Visibility   string `json:"visibility" binding:"oneof=private public"`
-	IsActive     *bool  `json:"is_active"`
-	UserRoleName string `json:"user_role_name" binding:"max=50"`
-	UserRoleDesc string `json:"user_role_desc" binding:"max=400"`
+	Name         string   `json:"name" binding:"max=100"`
+	Description  string   `json:"description"`
+	AvatarURL    string   `json:"avatar_url" binding:"max=255"`
+	VoiceURL     string   `json:"voice_url" binding:"max=255"` // 音色URL字段
+	VoiceID      string   `json:"voice_id" binding:"max=100"`  // 音色ID字段，用于AI生成声音
+	Tags         []string `json:"tags" binding:"omitempty,max=10,dive,max=20"`
+	Visibility   string   `json:"visibility" binding:"oneof=private public"`
+	IsActive     *bool    `json:"is_active"`
+	UserRoleName string   `json:"user_role_name" binding:"max=50"`
+	UserRoleDesc string   `json:"user_role_desc" binding:"max=400"`
+
+	Greeting         string            `json:"greeting" binding:"max=2000"`
+	ExampleDialogues []ExampleDialogue `json:"example_dialogues" binding:"omitempty,max=10,dive"`
+
+	LorebookTokenBudget *int `json:"lorebook_token_budget" binding:"omitempty,min=0,max=4000"`
 }
 
 type CharacterListQuery struct {
@@ -78,6 +115,30 @@ type CharacterListQuery struct {
 	Visibility string `form:"visibility" binding:"omitempty,oneof=private public"`
 	CreatorID  uint   `form:"creator_id"`
 	Search     string `form:"search"`
-	SortBy     string `form:"sort_by,default=created_at" binding:"oneof=created_at popularity_score usage_count"`
+	SortBy     string `form:"sort_by,default=created_at" binding:"oneof=created_at popularity_score usage_count rating_average"`
 	SortOrder  string `form:"sort_order,default=desc" binding:"oneof=asc desc"`
 }
+
+type TrendingQuery struct {
+	Window string `form:"window,default=week" binding:"oneof=day week all"`
+	Limit  int    `form:"limit,default=20" binding:"min=1,max=50"`
+}
+
+// TrendingCharacter 热门角色，附带统计窗口内的数据
+type TrendingCharacter struct {
+	Character
+	WindowPostcards int64   `json:"window_postcards"`
+	WindowUsers     int64   `json:"window_users"`
+	TrendingScore   float64 `json:"trending_score"`
+}
+
+type RecommendationQuery struct {
+	Limit int `form:"limit,default=20" binding:"min=1,max=50"`
+}
+
+// RecommendedCharacter 推荐角色，附带推荐分数和来源
+type RecommendedCharacter struct {
+	Character
+	Score  float64 `json:"score"`
+	Reason string  `json:"reason"` // collaborative / tags / trending
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+)
+
+// Conversation 对话，ID 即明信片的 conversation_id
+// 从其他对话的某张明信片分支出来的对话会记录父对话和分支点
+type Conversation struct {
+	ID                   string     `json:"id" gorm:"primaryKey;size:36"`
+	UserID               uint       `json:"user_id" gorm:"not null;index"`
+	CharacterID          uint       `json:"character_id" gorm:"not null;index"`
+	ParentID             *string    `json:"parent_id" gorm:"size:36;index"`
+	ForkedFromPostcardID *uint      `json:"forked_from_postcard_id"`
+	Title                string     `json:"title" gorm:"size:100"`
+	IsPinned             bool       `json:"is_pinned" gorm:"default:false"`
+	IsArchived           bool       `json:"is_archived" gorm:"default:false;index"`
+	CheckInDays          int        `json:"check_in_days" gorm:"default:0"` // 用户连续多少天没有来信时角色主动寄出明信片，0 表示关闭
+	LastCheckInAt        *time.Time `json:"last_check_in_at"`
+	CreatedAt            time.Time  `json:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at"`
+
+	Character *Character `json:"character,omitempty" gorm:"foreignKey:CharacterID"`
+}
+
+// ConversationSummary 对话列表中的一行
+type ConversationSummary struct {
+	Conversation
+	MessageCount   int64      `json:"message_count"`
+	UnreadCount    int64      `json:"unread_count"` // 未读的 AI 回复数量
+	LastActivityAt *time.Time `json:"last_activity_at"`
+	LastPostcard   *Postcard  `json:"last_postcard,omitempty"`
+}
+
+type ConversationListQuery struct {
+	Page        int    `form:"page,default=1" binding:"min=1"`
+	PageSize    int    `form:"page_size,default=20" binding:"min=1,max=100"`
+	CharacterID uint   `form:"character_id"`
+	Archived    bool   `form:"archived"` // 为 true 时只返回已归档的对话
+	Search      string `form:"search"`   // 按标题搜索
+}
+
+type ConversationUpdateRequest struct {
+	Title      *string `json:"title" binding:"omitempty,max=100"`
+	IsPinned   *bool   `json:"is_pinned"`
+	IsArchived *bool   `json:"is_archived"`
+	// 开启角色主动问候：用户连续 N 天没有来信时，角色主动寄出一张明信片，0 表示关闭
+	CheckInDays *int `json:"check_in_days" binding:"omitempty,min=0,max=30"`
+}
+
+// ConversationForkResponse 分支出的新对话及继承的明信片
+type ConversationForkResponse struct {
+	Conversation Conversation `json:"conversation"`
+	Postcards    []Postcard   `json:"postcards"`
+}
+
+// ConversationTreeNode 对话树中的一个节点
+type ConversationTreeNode struct {
+	Conversation
+	PostcardCount int64                   `json:"postcard_count"`
+	LastPostcard  *Postcard               `json:"last_postcard,omitempty"`
+	Children      []*ConversationTreeNode `json:"children"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+)
+
+// Emotions 情绪分类的全部标签，neutral 表示没有识别出明显情绪
+var Emotions = []string{"joy", "love", "gratitude", "calm", "surprise", "longing", "sadness", "fear", "anger", "neutral"}
+
+// PostcardEmotion 用户明信片的一个情绪标签，每张明信片最多三个
+type PostcardEmotion struct {
+	ID         uint      `json:"-" gorm:"primaryKey"`
+	PostcardID uint      `json:"-" gorm:"not null;uniqueIndex:idx_postcard_emotion"`
+	UserID     uint      `json:"-" gorm:"not null;index:idx_emotion_user"`
+	Emotion    string    `json:"emotion" gorm:"size:20;not null;uniqueIndex:idx_postcard_emotion;index:idx_emotion_user"`
+	Score      float64   `json:"score"`                 // 0-1，同一明信片的各标签之和为 1
+	Source     string    `json:"source" gorm:"size:20"` // 分类器：lexicon 或 llm
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// EmotionScore 分类器输出的一个情绪及其强度
+type EmotionScore struct {
+	Emotion string  `json:"emotion"`
+	Score   float64 `json:"score"`
+}
+
+// MoodTrendQuery 情绪趋势查询参数，日期按用户时区解释
+type MoodTrendQuery struct {
+	Period      string `form:"period,default=day" binding:"oneof=day week"`
+	From        string `form:"from" binding:"omitempty,datetime=2006-01-02"` // 默认按日统计最近 30 天，按周统计最近 12 周
+	To          string `form:"to" binding:"omitempty,datetime=2006-01-02"`   // 默认为今天
+	CharacterID uint   `form:"character_id"`
+}
+
+// MoodTrendPoint 一个统计周期内的情绪汇总
+type MoodTrendPoint struct {
+	Period    string             `json:"period"`    // 按日为当天日期，按周为周一的日期
+	Postcards int                `json:"postcards"` // 已分类的明信片数
+	Emotions  map[string]float64 `json:"emotions"`  // 各情绪的得分之和
+	Dominant  string             `json:"dominant"`  // 得分最高的情绪，没有明信片时为空
+	Valence   float64            `json:"valence"`   // 情绪效价均值，-1 最消极，1 最积极
+}
+
+// MoodTrendResponse 情绪趋势，包含区间内的每个周期，没有明信片的周期也会返回
+type MoodTrendResponse struct {
+	Period   string           `json:"period"`
+	From     string           `json:"from"` // 第一个周期的起始日期
+	To       string           `json:"to"`   // 最后一个周期的起始日期
+	TimeZone string           `json:"time_zone"`
+	Points   []MoodTrendPoint `json:"points"`
+}
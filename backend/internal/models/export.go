@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+)
+
+// ConversationExportQuery 对话导出参数
+type ConversationExportQuery struct {
+	Format string `form:"format" binding:"required,oneof=md json epub"`
+}
+
+// ExportedConversation JSON 导出的对话信息
+type ExportedConversation struct {
+	ID         string            `json:"id"`
+	Title      string            `json:"title"`
+	UserName   string            `json:"user_name"`
+	Character  ExportedCharacter `json:"character"`
+	CreatedAt  time.Time         `json:"created_at"`
+	ExportedAt time.Time         `json:"exported_at"`
+}
+
+// ExportedCharacter 导出文件中的角色信息
+type ExportedCharacter struct {
+	ID          uint     `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	AvatarURL   string   `json:"avatar_url"`
+	Tags        []string `json:"tags"`
+}
+
+// ExportedPostcard 导出文件中的一张明信片
+type ExportedPostcard struct {
+	ID                  uint       `json:"id"`
+	Type                string     `json:"type"`
+	Sender              string     `json:"sender"`
+	Content             string     `json:"content"`
+	ImageURL            string     `json:"image_url,omitempty"`
+	AIGeneratedImageURL string     `json:"ai_generated_image_url,omitempty"`
+	VoiceURL            string     `json:"voice_url,omitempty"`
+	PostcardTemplate    string     `json:"postcard_template,omitempty"`
+	ReplyToID           *uint      `json:"reply_to_id,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	EditedAt            *time.Time `json:"edited_at,omitempty"`
+}
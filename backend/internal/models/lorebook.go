@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+)
+
+// LorebookEntry 角色的世界设定条目，对话中出现触发关键词时注入到 AI 上下文
+type LorebookEntry struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	CharacterID uint      `json:"character_id" gorm:"not null;index"`
+	Title       string    `json:"title" gorm:"size:100;not null"`
+	Keywords    []string  `json:"keywords" gorm:"type:json;serializer:json"`
+	Content     string    `json:"content" gorm:"type:text;not null"`
+	Priority    int       `json:"priority" gorm:"default:0"` // 数值越大越优先注入
+	Enabled     bool      `json:"enabled" gorm:"default:true"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type LorebookEntryCreateRequest struct {
+	Title    string   `json:"title" binding:"required,max=100"`
+	Keywords []string `json:"keywords" binding:"required,min=1,max=20,dive,required,max=50"`
+	Content  string   `json:"content" binding:"required,max=4000"`
+	Priority int      `json:"priority" binding:"min=0,max=1000"`
+	Enabled  *bool    `json:"enabled"`
+}
+
+type LorebookEntryUpdateRequest struct {
+	Title    string   `json:"title" binding:"max=100"`
+	Keywords []string `json:"keywords" binding:"omitempty,min=1,max=20,dive,required,max=50"`
+	Content  string   `json:"content" binding:"max=4000"`
+	Priority *int     `json:"priority" binding:"omitempty,min=0,max=1000"`
+	Enabled  *bool    `json:"enabled"`
+}
+
+// LorebookDryRunRequest 试运行：查看给定消息会触发哪些条目
+// 提供 conversation_id 时，会连同该对话最近的明信片一起匹配
+type LorebookDryRunRequest struct {
+	Message        string `json:"message" binding:"required"`
+	ConversationID string `json:"conversation_id"`
+}
+
+// LorebookMatch 被触发的条目及命中的关键词
+type LorebookMatch struct {
+	Entry           LorebookEntry `json:"entry"`
+	MatchedKeywords []string      `json:"matched_keywords"`
+	Tokens          int           `json:"tokens"`
+	Injected        bool          `json:"injected"` // 超出 token 预算的条目不会注入
+}
+
+type LorebookDryRunResponse struct {
+	Matches     []LorebookMatch `json:"matches"`
+	TokenBudget int             `json:"token_budget"`
+	UsedTokens  int             `json:"used_tokens"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+// PostcardEmbedding 明信片内容的向量，用于检索用户过去写过的明信片
+type PostcardEmbedding struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	PostcardID  uint      `json:"postcard_id" gorm:"not null;uniqueIndex"`
+	UserID      uint      `json:"user_id" gorm:"not null;index:idx_embedding_user_character"`
+	CharacterID uint      `json:"character_id" gorm:"not null;index:idx_embedding_user_character"`
+	Model       string    `json:"model" gorm:"size:100;not null"`
+	Dimensions  int       `json:"dimensions" gorm:"not null"`
+	Vector      []byte    `json:"-" gorm:"type:blob;not null"` // 小端序 float32
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// MemoryRecall 生成 AI 回复时回忆起的一张明信片
+type MemoryRecall struct {
+	PostcardID uint    `json:"postcard_id"`
+	Score      float32 `json:"score"`
+}
+
+// RecalledMemory 回忆起的明信片及相似度
+type RecalledMemory struct {
+	Postcard Postcard `json:"postcard"`
+	Score    float32  `json:"score"`
+}
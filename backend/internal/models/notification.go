@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+)
+
+// Notification 站内通知
+type Notification struct {
+	ID        uint                   `json:"id" gorm:"primaryKey"`
+	UserID    uint                   `json:"user_id" gorm:"not null;index:idx_notification_user"`
+	Type      string                 `json:"type" gorm:"size:50;not null"`
+	Title     string                 `json:"title" gorm:"size:200"`
+	Body      string                 `json:"body" gorm:"type:text"`
+	Payload   map[string]interface{} `json:"payload" gorm:"type:json;serializer:json"` // 与通知相关的对象 ID 等，供客户端跳转
+	ReadAt    *time.Time             `json:"read_at" gorm:"index:idx_notification_user"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// NotificationPreference 用户对某类通知的设置，没有记录时默认接收
+type NotificationPreference struct {
+	ID        uint      `json:"-" gorm:"primaryKey"`
+	UserID    uint      `json:"-" gorm:"not null;uniqueIndex:idx_notification_preference"`
+	Type      string    `json:"type" gorm:"size:50;not null;uniqueIndex:idx_notification_preference"`
+	Enabled   bool      `json:"enabled"`
+	UpdatedAt time.Time `json:"-"`
+}
+
+// NotificationPreferenceItem 通知设置中的一类通知
+type NotificationPreferenceItem struct {
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+}
+
+type NotificationListQuery struct {
+	Page       int    `form:"page,default=1" binding:"min=1"`
+	PageSize   int    `form:"page_size,default=20" binding:"min=1,max=100"`
+	Type       string `form:"type" binding:"max=50"`
+	UnreadOnly bool   `form:"unread_only"`
+}
+
+// NotificationListResponse 通知列表及未读总数
+type NotificationListResponse struct {
+	PaginatedResponse
+	UnreadCount int64 `json:"unread_count"`
+}
+
+// NotificationPreferenceUpdate 一类通知的开关
+type NotificationPreferenceUpdate struct {
+	Type    string `json:"type" binding:"required,max=50"`
+	Enabled bool   `json:"enabled"`
+}
+
+type NotificationPreferencesRequest struct {
+	Preferences []NotificationPreferenceUpdate `json:"preferences" binding:"required,min=1,dive"`
+}
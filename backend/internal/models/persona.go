@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserPersona 用户保存的人设，可在与不同角色交流时选用
+type UserPersona struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	UserID      uint           `json:"user_id" gorm:"not null;index"`
+	Name        string         `json:"name" gorm:"size:50;not null"`
+	Description string         `json:"description" gorm:"size:400"`
+	IsDefault   bool           `json:"is_default" gorm:"default:false"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+type PersonaCreateRequest struct {
+	Name        string `json:"name" binding:"required,max=50"`
+	Description string `json:"description" binding:"max=400"`
+	IsDefault   bool   `json:"is_default"`
+}
+
+type PersonaUpdateRequest struct {
+	Name        string `json:"name" binding:"max=50"`
+	Description string `json:"description" binding:"max=400"`
+	IsDefault   *bool  `json:"is_default"`
+}
+
+// CharacterPersonaRequest 为某个角色设置人设：选用已保存的人设，或直接填写名称和描述
+type CharacterPersonaRequest struct {
+	PersonaID   *uint  `json:"persona_id"`
+	Name        string `json:"name" binding:"required_without=PersonaID,max=50"`
+	Description string `json:"description" binding:"max=400"`
+}
+
+// ResolvedPersona 与角色交流时实际生效的用户人设
+type ResolvedPersona struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Source      string `json:"source"` // custom / persona / default / character
+	PersonaID   *uint  `json:"persona_id,omitempty"`
+}
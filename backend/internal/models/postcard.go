@@ -12,6 +12,9 @@ type Postcard struct {
 	UserID              uint           `json:"user_id" gorm:"not null;index"`
 	CharacterID         uint           `json:"character_id" gorm:"not null;index"`
 	Type                string         `json:"type" gorm:"type:enum('user','ai');default:'user';not null"`
+	ReplyToID           *uint          `json:"reply_to_id" gorm:"index"`         // AI 回复所回复的用户明信片
+	IsCanonical         bool           `json:"is_canonical" gorm:"default:true"` // 同一明信片的多个回复中，只有选定的回复会进入后续对话历史
+	CopiedFromID        *uint          `json:"copied_from_id"`                   // 分支对话时从原对话复制而来的明信片
 	Content             string         `json:"content" gorm:"type:text;not null"`
 	ImageURL            string         `json:"image_url" gorm:"size:255"`
 	AIGeneratedImageURL string         `json:"ai_generated_image_url" gorm:"size:255"`
@@ -19,13 +22,17 @@ type Postcard struct {
 	PostcardTemplate    string         `json:"postcard_template" gorm:"size:100"`
 	Status              string         `json:"status" gorm:"type:enum('draft','sent','delivered','read');default:'sent'"`
 	IsFavorite          bool           `json:"is_favorite" gorm:"default:false"`
+	OriginalContent     string         `json:"original_content,omitempty" gorm:"type:text"` // 编辑 AI 回复前的原文
+	EditedAt            *time.Time     `json:"edited_at"`
+	RecalledMemories    []MemoryRecall `json:"recalled_memories,omitempty" gorm:"type:json;serializer:json"` // AI 回复时回忆起的明信片
 	CreatedAt           time.Time      `json:"created_at"`
 	UpdatedAt           time.Time      `json:"updated_at"`
 	DeletedAt           gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// 关联关系
-	User      User      `json:"user,omitempty" gorm:"foreignKey:UserID"`
-	Character Character `json:"character,omitempty" gorm:"foreignKey:CharacterID"`
+	User      User              `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Character Character         `json:"character,omitempty" gorm:"foreignKey:CharacterID"`
+	Emotions  []PostcardEmotion `json:"emotions,omitempty" gorm:"foreignKey:PostcardID"` // 用户明信片的情绪标签
 }
 
 type Draft struct {
@@ -61,6 +68,26 @@ type PostcardCreateRequest struct {
 	VoiceURL         string `json:"voice_url" binding:"max=255"`
 	PostcardTemplate string `json:"postcard_template" binding:"max=100"`
 	ConversationID   string `json:"conversation_id" binding:"max=36"`
+	SkipAIReply      bool   `json:"skip_ai_reply"` // 为 true 时不自动生成回复，由客户端调用流式回复接口
+}
+
+// ReplyStreamEvent 流式回复事件
+// delta：新生成的文本片段；done：回复已保存；error：生成失败
+type ReplyStreamEvent struct {
+	Type     string    `json:"type"`
+	Delta    string    `json:"delta,omitempty"`
+	Postcard *Postcard `json:"postcard,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+type ConversationStartRequest struct {
+	CharacterID uint `json:"character_id" binding:"required"`
+}
+
+// ConversationStartResponse 新对话，附带角色自动发送的问候明信片（角色未设置开场白时为空）
+type ConversationStartResponse struct {
+	ConversationID string    `json:"conversation_id"`
+	Greeting       *Postcard `json:"greeting,omitempty"`
 }
 
 type PostcardUpdateRequest struct {
@@ -73,15 +100,17 @@ type PostcardUpdateRequest struct {
 }
 
 type PostcardListQuery struct {
-	Page           int    `form:"page,default=1" binding:"min=1"`
-	PageSize       int    `form:"page_size,default=20" binding:"min=1,max=100"`
-	ConversationID string `form:"conversation_id"`
-	CharacterID    uint   `form:"character_id"`
-	Type           string `form:"type" binding:"omitempty,oneof=all user ai"`
-	Status         string `form:"status" binding:"omitempty,oneof=draft sent delivered read"`
-	IsFavorite     *bool  `form:"is_favorite"`
-	SortBy         string `form:"sort_by,default=created_at" binding:"oneof=created_at updated_at"`
-	SortOrder      string `form:"sort_order,default=desc" binding:"oneof=asc desc"`
+	Page                int    `form:"page,default=1" binding:"min=1"`
+	PageSize            int    `form:"page_size,default=20" binding:"min=1,max=100"`
+	ConversationID      string `form:"conversation_id"`
+	CharacterID         uint   `form:"character_id"`
+	Type                string `form:"type" binding:"omitempty,oneof=all user ai"`
+	Status              string `form:"status" binding:"omitempty,oneof=draft sent delivered read"`
+	IsFavorite          *bool  `form:"is_favorite"`
+	Emotion             string `form:"emotion" binding:"omitempty,oneof=joy love gratitude calm surprise longing sadness fear anger neutral"`
+	IncludeAlternatives bool   `form:"include_alternatives"` // 为 true 时同时返回未选定的备选回复
+	SortBy              string `form:"sort_by,default=created_at" binding:"oneof=created_at updated_at"`
+	SortOrder           string `form:"sort_order,default=desc" binding:"oneof=asc desc"`
 }
 
 type DraftCreateRequest struct {
@@ -98,3 +127,17 @@ type DraftUpdateRequest struct {
 	EmotionTags       string `json:"emotion_tags"`
 	TemplateID        string `json:"template_id" binding:"max=100"`
 }
+
+// PostcardRenderQuery 明信片渲染参数
+type PostcardRenderQuery struct {
+	Side   string `form:"side" binding:"omitempty,oneof=front back both"` // 默认 both
+	Format string `form:"format" binding:"omitempty,oneof=png jpeg"`      // 默认 png
+}
+
+// PostcardRenderResponse 明信片渲染结果，未请求的一面 URL 为空
+type PostcardRenderResponse struct {
+	PostcardID uint   `json:"postcard_id"`
+	Format     string `json:"format"`
+	FrontURL   string `json:"front_url,omitempty"`
+	BackURL    string `json:"back_url,omitempty"`
+}
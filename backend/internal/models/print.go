@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// PrintJob 印刷版 PDF 导出任务，后台异步生成后上传到对象存储
+type PrintJob struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	UserID         uint       `json:"user_id" gorm:"not null;index"`
+	Kind           string     `json:"kind" gorm:"type:enum('postcard','booklet');not null"` // postcard：单张明信片正反两页；booklet：整段对话的纪念册
+	PostcardID     *uint      `json:"postcard_id"`
+	ConversationID string     `json:"conversation_id" gorm:"size:36"`
+	Status         string     `json:"status" gorm:"type:enum('pending','processing','completed','failed');default:'pending';index"`
+	FileURL        string     `json:"file_url" gorm:"size:255"`
+	PageCount      int        `json:"page_count"`
+	Error          string     `json:"error,omitempty" gorm:"size:500"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	CompletedAt    *time.Time `json:"completed_at"`
+}
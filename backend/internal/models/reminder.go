@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+)
+
+// ReminderSchedule 写明信片提醒，按用户时区在指定的星期几和时间发送
+type ReminderSchedule struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	UserID        uint       `json:"user_id" gorm:"not null;index"`
+	TimeOfDay     string     `json:"time_of_day" gorm:"size:5;not null"`            // HH:MM
+	DaysOfWeek    []int      `json:"days_of_week" gorm:"type:json;serializer:json"` // 0 为周日，6 为周六
+	Message       string     `json:"message" gorm:"size:200"`                       // 自定义提醒文案，为空时使用默认文案
+	Email         bool       `json:"email"`                                         // 同时发送邮件
+	SkipIfWritten bool       `json:"skip_if_written"`                               // 当天已经写过明信片时不提醒
+	Enabled       bool       `json:"enabled"`
+	NextRunAt     *time.Time `json:"next_run_at" gorm:"index"`
+	LastSentAt    *time.Time `json:"last_sent_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+type ReminderCreateRequest struct {
+	TimeOfDay     string `json:"time_of_day" binding:"required,datetime=15:04"`
+	DaysOfWeek    []int  `json:"days_of_week" binding:"required,min=1,max=7,dive,min=0,max=6"`
+	Message       string `json:"message" binding:"max=200"`
+	Email         bool   `json:"email"`
+	SkipIfWritten *bool  `json:"skip_if_written"` // 默认为 true
+	Enabled       *bool  `json:"enabled"`         // 默认为 true
+}
+
+type ReminderUpdateRequest struct {
+	TimeOfDay     *string `json:"time_of_day" binding:"omitempty,datetime=15:04"`
+	DaysOfWeek    []int   `json:"days_of_week" binding:"omitempty,min=1,max=7,dive,min=0,max=6"`
+	Message       *string `json:"message" binding:"omitempty,max=200"`
+	Email         *bool   `json:"email"`
+	SkipIfWritten *bool   `json:"skip_if_written"`
+	Enabled       *bool   `json:"enabled"`
+}
+
+// StreakResponse 连续写明信片的天数，按用户时区划分日期
+type StreakResponse struct {
+	CurrentStreak   int    `json:"current_streak"` // 截至今天（今天还没写时截至昨天）的连续天数
+	LongestStreak   int    `json:"longest_streak"`
+	TotalDays       int    `json:"total_days"` // 写过明信片的总天数
+	WrittenToday    bool   `json:"written_today"`
+	LastWrittenDate string `json:"last_written_date,omitempty"`
+	TimeZone        string `json:"time_zone"`
+}
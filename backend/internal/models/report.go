@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+)
+
+type Report struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	ReporterID    uint       `json:"reporter_id" gorm:"not null;index"`
+	TargetType    string     `json:"target_type" gorm:"type:enum('character','postcard','user','media');not null;index:idx_report_target"`
+	TargetID      uint       `json:"target_id" gorm:"index:idx_report_target"`
+	TargetURL     string     `json:"target_url" gorm:"size:255"` // 被举报的媒体文件 URL
+	Reason        string     `json:"reason" gorm:"type:enum('spam','harassment','sexual','violence','hate','illegal','copyright','impersonation','other');not null"`
+	Detail        string     `json:"detail" gorm:"type:text"`
+	Status        string     `json:"status" gorm:"type:enum('pending','reviewing','resolved','rejected');default:'pending';index"`
+	Action        string     `json:"action" gorm:"type:enum('none','hide');default:'none'"`
+	ModeratorID   *uint      `json:"moderator_id"`
+	ModeratorNote string     `json:"moderator_note" gorm:"size:500"`
+	ResolvedAt    *time.Time `json:"resolved_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+
+	Reporter User `json:"reporter,omitempty" gorm:"foreignKey:ReporterID"`
+}
+
+type ReportCreateRequest struct {
+	TargetType string `json:"target_type" binding:"required,oneof=character postcard user media"`
+	TargetID   uint   `json:"target_id" binding:"required_unless=TargetType media"`
+	TargetURL  string `json:"target_url" binding:"required_if=TargetType media,max=255"`
+	Reason     string `json:"reason" binding:"required,oneof=spam harassment sexual violence hate illegal copyright impersonation other"`
+	Detail     string `json:"detail" binding:"max=1000"`
+}
+
+type ReportReviewRequest struct {
+	Status string `json:"status" binding:"required,oneof=reviewing resolved rejected"`
+	Action string `json:"action" binding:"omitempty,oneof=none hide"`
+	Note   string `json:"note" binding:"max=500"`
+}
+
+type ReportListQuery struct {
+	Page       int    `form:"page,default=1" binding:"min=1"`
+	PageSize   int    `form:"page_size,default=20" binding:"min=1,max=100"`
+	Status     string `form:"status" binding:"omitempty,oneof=pending reviewing resolved rejected"`
+	TargetType string `form:"target_type" binding:"omitempty,oneof=character postcard user media"`
+}
@@ -20,10 +20,13 @@ type LoginResponse struct {
 }
 
 type UploadResponse struct {
-	URL        string `json:"url"`
-	Filename   string `json:"filename"`
-	Size       int64  `json:"size"`
-	Compressed bool   `json:"compressed,omitempty"`
+	URL         string `json:"url"`
+	Filename    string `json:"filename"`
+	Size        int64  `json:"size"`
+	Compressed  bool   `json:"compressed,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Width       int    `json:"width,omitempty"`
+	Height      int    `json:"height,omitempty"`
 }
 
 // 成功响应
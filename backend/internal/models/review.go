@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+type CharacterReview struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	CharacterID  uint       `json:"character_id" gorm:"not null;uniqueIndex:idx_review_character_user"`
+	UserID       uint       `json:"user_id" gorm:"not null;uniqueIndex:idx_review_character_user;index"`
+	Rating       int        `json:"rating" gorm:"not null"`
+	Content      string     `json:"content" gorm:"type:text"`
+	CreatorReply string     `json:"creator_reply" gorm:"type:text"` // 角色创建者的回复
+	RepliedAt    *time.Time `json:"replied_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+
+	User User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+type ReviewUpsertRequest struct {
+	Rating  int    `json:"rating" binding:"required,min=1,max=5"`
+	Content string `json:"content" binding:"max=2000"`
+}
+
+type ReviewReplyRequest struct {
+	Reply string `json:"reply" binding:"required,max=1000"`
+}
+
+type ReviewListQuery struct {
+	Page      int    `form:"page,default=1" binding:"min=1"`
+	PageSize  int    `form:"page_size,default=20" binding:"min=1,max=100"`
+	Rating    int    `form:"rating" binding:"omitempty,min=1,max=5"`
+	SortBy    string `form:"sort_by,default=created_at" binding:"oneof=created_at rating"`
+	SortOrder string `form:"sort_order,default=desc" binding:"oneof=asc desc"`
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+)
+
+// ShareLink 明信片公开分享链接，持有 token 的人无需登录即可查看
+type ShareLink struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	Token          string     `json:"token" gorm:"size:32;uniqueIndex;not null"`
+	UserID         uint       `json:"user_id" gorm:"not null;index"`
+	ConversationID string     `json:"conversation_id" gorm:"size:36;not null"`
+	PostcardIDs    []uint     `json:"postcard_ids" gorm:"type:json;serializer:json"` // 分享的明信片，按对话中的时间顺序展示
+	Title          string     `json:"title" gorm:"size:100"`
+	ExpiresAt      *time.Time `json:"expires_at"` // 为空表示永不过期
+	RevokedAt      *time.Time `json:"revoked_at"`
+	ViewCount      int        `json:"view_count" gorm:"default:0"`
+	LastViewedAt   *time.Time `json:"last_viewed_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	URL string `json:"url" gorm:"-"` // 分享页地址，由接口层填充
+}
+
+// ShareLinkCreateRequest 分享单张明信片的请求
+type ShareLinkCreateRequest struct {
+	Title          string `json:"title" binding:"max=100"`
+	ExpiresInHours int    `json:"expires_in_hours" binding:"omitempty,min=1,max=8760"` // 不填表示永不过期
+}
+
+// ConversationShareCreateRequest 分享对话中若干明信片的请求
+type ConversationShareCreateRequest struct {
+	PostcardIDs    []uint `json:"postcard_ids" binding:"required,min=1,max=20,dive,min=1"`
+	Title          string `json:"title" binding:"max=100"`
+	ExpiresInHours int    `json:"expires_in_hours" binding:"omitempty,min=1,max=8760"`
+}
+
+// SharedView 分享页展示的内容，只包含可以公开的字段
+type SharedView struct {
+	Title     string           `json:"title"`
+	Character SharedCharacter  `json:"character"`
+	Postcards []SharedPostcard `json:"postcards"`
+	ViewCount int              `json:"view_count"`
+	SharedAt  time.Time        `json:"shared_at"`
+	ExpiresAt *time.Time       `json:"expires_at"`
+}
+
+// SharedCharacter 分享页中的角色信息
+type SharedCharacter struct {
+	Name      string `json:"name"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+// SharedPostcard 分享页中的一张明信片
+type SharedPostcard struct {
+	Type                string    `json:"type"`
+	Sender              string    `json:"sender"`
+	Content             string    `json:"content"`
+	ImageURL            string    `json:"image_url,omitempty"`
+	AIGeneratedImageURL string    `json:"ai_generated_image_url,omitempty"`
+	FrontImageURL       string    `json:"front_image_url,omitempty"` // 渲染后的正面图片
+	BackImageURL        string    `json:"back_image_url,omitempty"`  // 渲染后的背面图片
+	CreatedAt           time.Time `json:"created_at"`
+}
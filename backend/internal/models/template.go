@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PostcardTemplate 明信片模板，由管理员维护；Postcard.PostcardTemplate 和 Draft.TemplateID 引用其 ID
+type PostcardTemplate struct {
+	ID            string               `json:"id" gorm:"primaryKey;size:100"` // 如 classic、vintage
+	Name          string               `json:"name" gorm:"size:100;not null"`
+	Description   string               `json:"description" gorm:"size:500"`
+	Layout        TemplateLayout       `json:"layout" gorm:"type:json;serializer:json"`
+	BackgroundURL string               `json:"background_url" gorm:"size:255"`         // 背面纸张底图
+	Fonts         []string             `json:"fonts" gorm:"type:json;serializer:json"` // RENDER_FONT_DIR 下的字体文件名，渲染时使用第一个可用的
+	TextRegions   []TemplateTextRegion `json:"text_regions" gorm:"type:json;serializer:json"`
+	PreviewURL    string               `json:"preview_url" gorm:"size:255"`
+	IsAvailable   bool                 `json:"is_available" gorm:"default:true"` // 下架的模板不能再用于新明信片
+	SortOrder     int                  `json:"sort_order" gorm:"default:0"`
+	CreatedAt     time.Time            `json:"created_at"`
+	UpdatedAt     time.Time            `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt       `json:"-" gorm:"index"`
+}
+
+// TemplateLayout 模板配色，颜色均为 #RRGGBB
+type TemplateLayout struct {
+	PaperColor  string `json:"paper_color"`
+	InkColor    string `json:"ink_color"`
+	AccentColor string `json:"accent_color"`
+}
+
+// TemplateTextRegion 文字区域，坐标以 1748x1240 的背面画布为准
+type TemplateTextRegion struct {
+	Name     string  `json:"name" binding:"required,oneof=body signature address"`
+	X        int     `json:"x" binding:"min=0"`
+	Y        int     `json:"y" binding:"min=0"`
+	Width    int     `json:"width" binding:"min=1"`
+	Height   int     `json:"height" binding:"min=1"`
+	FontSize float64 `json:"font_size" binding:"omitempty,min=8,max=200"`
+	Color    string  `json:"color"`
+	Align    string  `json:"align" binding:"omitempty,oneof=left center right"`
+}
+
+type TemplateCreateRequest struct {
+	ID            string               `json:"id" binding:"required,max=100"`
+	Name          string               `json:"name" binding:"required,max=100"`
+	Description   string               `json:"description" binding:"max=500"`
+	Layout        TemplateLayout       `json:"layout"`
+	BackgroundURL string               `json:"background_url" binding:"max=255"`
+	Fonts         []string             `json:"fonts"`
+	TextRegions   []TemplateTextRegion `json:"text_regions" binding:"dive"`
+	PreviewURL    string               `json:"preview_url" binding:"max=255"`
+	IsAvailable   *bool                `json:"is_available"`
+	SortOrder     int                  `json:"sort_order"`
+}
+
+type TemplateUpdateRequest struct {
+	Name          string               `json:"name" binding:"max=100"`
+	Description   *string              `json:"description" binding:"omitempty,max=500"`
+	Layout        *TemplateLayout      `json:"layout"`
+	BackgroundURL *string              `json:"background_url" binding:"omitempty,max=255"`
+	Fonts         []string             `json:"fonts"` // 为 null 时不修改，空数组表示清空
+	TextRegions   []TemplateTextRegion `json:"text_regions" binding:"dive"`
+	PreviewURL    *string              `json:"preview_url" binding:"omitempty,max=255"`
+	IsAvailable   *bool                `json:"is_available"`
+	SortOrder     *int                 `json:"sort_order"`
+}
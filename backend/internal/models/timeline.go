@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+)
+
+// TimelineQuery 时间线查询参数，日期均按用户时区解释
+type TimelineQuery struct {
+	GroupBy     string `form:"group_by,default=day" binding:"oneof=day month year"`
+	From        string `form:"from" binding:"omitempty,datetime=2006-01-02"` // 起始日期（含）
+	To          string `form:"to" binding:"omitempty,datetime=2006-01-02"`   // 结束日期（含）
+	CharacterID uint   `form:"character_id"`
+	Page        int    `form:"page,default=1" binding:"min=1"`
+	PageSize    int    `form:"page_size,default=20" binding:"min=1,max=100"`
+}
+
+// TimelineGroup 时间线中的一个时间段
+type TimelineGroup struct {
+	Period    string     `json:"period"` // day 为 2006-01-02，month 为 2006-01，year 为 2006
+	Start     time.Time  `json:"start"`  // 时间段在用户时区的起始时刻
+	Count     int64      `json:"count"`  // 时间段内的明信片总数，不受分页影响
+	Postcards []Postcard `json:"postcards"`
+}
+
+// TimelineResponse 时间线，按明信片分页，跨页的时间段会在相邻两页各出现一次
+type TimelineResponse struct {
+	TimeZone string          `json:"time_zone"`
+	GroupBy  string          `json:"group_by"`
+	Groups   []TimelineGroup `json:"groups"`
+	Total    int64           `json:"total"`
+	Page     int             `json:"page"`
+	PageSize int             `json:"page_size"`
+}
+
+// OnThisDayQuery "那年今日"查询参数
+type OnThisDayQuery struct {
+	Date        string `form:"date" binding:"omitempty,datetime=2006-01-02"` // 默认为用户时区的今天
+	CharacterID uint   `form:"character_id"`
+}
+
+// OnThisDayYear 往年同一天的明信片
+type OnThisDayYear struct {
+	Year      int        `json:"year"`
+	YearsAgo  int        `json:"years_ago"`
+	Postcards []Postcard `json:"postcards"`
+}
+
+// OnThisDayResponse "那年今日"结果，按年份从近到远排列
+type OnThisDayResponse struct {
+	Date     string          `json:"date"`
+	TimeZone string          `json:"time_zone"`
+	Years    []OnThisDayYear `json:"years"`
+}
+
+// CalendarQuery 日历热力图查询参数
+type CalendarQuery struct {
+	Year        int  `form:"year" binding:"omitempty,min=1970,max=9999"` // 默认为用户时区的今年
+	CharacterID uint `form:"character_id"`
+}
+
+// CalendarDay 日历中有明信片的一天
+type CalendarDay struct {
+	Date  string `json:"date"`
+	Count int    `json:"count"`
+}
+
+// CalendarResponse 日历热力图数据，只包含有明信片的日期
+type CalendarResponse struct {
+	Year     int           `json:"year"`
+	TimeZone string        `json:"time_zone"`
+	Total    int           `json:"total"`
+	MaxCount int           `json:"max_count"` // 单日最多的明信片数，便于计算热力图色阶
+	Days     []CalendarDay `json:"days"`
+}
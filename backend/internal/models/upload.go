@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+)
+
+// Upload 用户上传到存储桶的文件，记录上传者，供引用校验和清理无引用文件使用
+type Upload struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	UserID      uint      `json:"user_id" gorm:"not null;index"`
+	ObjectKey   string    `json:"object_key" gorm:"size:255;not null;uniqueIndex"`
+	Kind        string    `json:"kind" gorm:"size:30"`
+	ContentType string    `json:"content_type" gorm:"size:100"`
+	Size        int64     `json:"size"`
+	Hash        string    `json:"hash" gorm:"size:100"` // 存储返回的 ETag，单段上传时为文件内容的 MD5
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// UploadGCQuery 手动运行无引用文件清理的参数
+type UploadGCQuery struct {
+	DryRun *bool `form:"dry_run"` // 默认为 true，只统计不删除
+}
+
+// UploadGCObject 清理时发现的无引用文件
+type UploadGCObject struct {
+	ObjectKey    string    `json:"object_key"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"last_modified"`
+	OwnerID      *uint     `json:"owner_id"` // 没有上传记录（如未确认的直传文件）时为空
+}
+
+// UploadGCReport 一次无引用文件清理的结果
+type UploadGCReport struct {
+	DryRun     bool             `json:"dry_run"`
+	Scanned    int              `json:"scanned"`     // 扫描的文件数
+	Orphaned   int              `json:"orphaned"`    // 超过保留期且无引用的文件数
+	Deleted    int              `json:"deleted"`     // 实际删除的文件数，试运行时为 0
+	OrphanSize int64            `json:"orphan_size"` // 无引用文件的总字节数
+	Failed     int              `json:"failed"`
+	Objects    []UploadGCObject `json:"objects"` // 最多列出 1000 个
+	StartedAt  time.Time        `json:"started_at"`
+	FinishedAt time.Time        `json:"finished_at"`
+}
+
+// UploadPresignRequest 申请直传存储的上传地址
+type UploadPresignRequest struct {
+	Kind        string `json:"kind" binding:"required,oneof=image avatar character-avatar audio"`
+	ContentType string `json:"content_type" binding:"required,max=100"`
+	Size        int64  `json:"size" binding:"required,min=1"` // 文件字节数，上传时 Content-Length 必须与之一致
+}
+
+// UploadPresignResponse 直传地址，客户端需带上 Headers 中的请求头以 PUT 方式上传，完成后调用确认接口
+type UploadPresignResponse struct {
+	UploadURL string            `json:"upload_url"`
+	Method    string            `json:"method"`
+	Headers   map[string]string `json:"headers"`
+	ObjectKey string            `json:"object_key"`
+	MaxSize   int64             `json:"max_size"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+type UploadConfirmRequest struct {
+	ObjectKey string `json:"object_key" binding:"required,max=255"`
+}
@@ -15,8 +15,10 @@ type User struct {
 	AvatarURL    string         `json:"avatar_url" gorm:"size:255"`
 	Signature    string         `json:"signature" gorm:"size:200"`
 	Language     string         `json:"language" gorm:"size:10;default:'zh-CN'"`
+	TimeZone     string         `json:"time_zone" gorm:"size:64;default:'Asia/Shanghai'"` // IANA 时区名，时间线等按日期统计的功能按此时区划分日期
 	FontSize     string         `json:"font_size" gorm:"type:enum('small','medium','large');default:'medium'"`
 	DarkMode     bool           `json:"dark_mode" gorm:"default:false"`
+	Role         string         `json:"role" gorm:"type:enum('user','admin');default:'user'"`
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
@@ -45,6 +47,7 @@ type UserUpdateRequest struct {
 	AvatarURL string `json:"avatar_url" binding:"max=255"`
 	Signature string `json:"signature" binding:"max=200"`
 	Language  string `json:"language" binding:"max=10"`
+	TimeZone  string `json:"time_zone" binding:"max=64"` // IANA 时区名，如 Asia/Shanghai
 	FontSize  string `json:"font_size" binding:"oneof=small medium large"`
 	DarkMode  *bool  `json:"dark_mode"`
 }
@@ -57,8 +60,10 @@ type UserResponse struct {
 	AvatarURL string    `json:"avatar_url"`
 	Signature string    `json:"signature"`
 	Language  string    `json:"language"`
+	TimeZone  string    `json:"time_zone"`
 	FontSize  string    `json:"font_size"`
 	DarkMode  bool      `json:"dark_mode"`
+	Role      string    `json:"role"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+)
+
+// WebhookEndpoint 用户注册的 Webhook 地址，订阅的事件发生时向其推送
+type WebhookEndpoint struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	UserID      uint      `json:"user_id" gorm:"not null;index"`
+	URL         string    `json:"url" gorm:"size:500;not null"`
+	Secret      string    `json:"-" gorm:"size:64;not null"`               // 用于 HMAC 签名，只在创建时返回
+	Events      []string  `json:"events" gorm:"type:json;serializer:json"` // 订阅的事件
+	Description string    `json:"description" gorm:"size:200"`
+	Enabled     bool      `json:"enabled"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery 一次事件推送，失败后按指数退避重试
+type WebhookDelivery struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	EndpointID     uint       `json:"endpoint_id" gorm:"not null;index"`
+	UserID         uint       `json:"user_id" gorm:"not null;index"`
+	EventID        string     `json:"event_id" gorm:"size:36;not null;index"` // 重新推送时沿用原事件 ID，接收方可据此去重
+	Event          string     `json:"event" gorm:"size:50;not null"`
+	Payload        string     `json:"payload" gorm:"type:mediumtext"` // 推送的请求体
+	Status         string     `json:"status" gorm:"type:enum('pending','succeeded','failed');default:'pending';index"`
+	Attempts       int        `json:"attempts" gorm:"default:0"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at" gorm:"index"`
+	ResponseStatus int        `json:"response_status"`
+	ResponseBody   string     `json:"response_body" gorm:"type:text"` // 最后一次请求的响应，最多保留 2KB
+	Error          string     `json:"error" gorm:"size:500"`
+	DurationMs     int64      `json:"duration_ms"`
+	RedeliveryOf   *uint      `json:"redelivery_of"` // 手动重新推送时指向原推送记录
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+type WebhookCreateRequest struct {
+	URL         string   `json:"url" binding:"required,url,max=500"`
+	Events      []string `json:"events" binding:"required,min=1,dive,required,max=50"`
+	Description string   `json:"description" binding:"max=200"`
+}
+
+type WebhookUpdateRequest struct {
+	URL         *string  `json:"url" binding:"omitempty,url,max=500"`
+	Events      []string `json:"events" binding:"omitempty,min=1,dive,required,max=50"`
+	Description *string  `json:"description" binding:"omitempty,max=200"`
+	Enabled     *bool    `json:"enabled"`
+}
+
+// WebhookCreateResponse 创建 Webhook 的结果，签名密钥只在此时返回
+type WebhookCreateResponse struct {
+	WebhookEndpoint
+	Secret string `json:"secret"`
+}
+
+type WebhookDeliveryListQuery struct {
+	Page     int    `form:"page,default=1" binding:"min=1"`
+	PageSize int    `form:"page_size,default=20" binding:"min=1,max=100"`
+	Status   string `form:"status" binding:"omitempty,oneof=pending succeeded failed"`
+	Event    string `form:"event" binding:"max=50"`
+}
@@ -16,9 +16,23 @@ func SetupRoutes(r *gin.Engine, services *services.Services, cfg *config.Config)
 	jwtSecret := cfg.JWTSecret
 	// 创建处理器
 	userHandler := handlers.NewUserHandler(services.User)
-	characterHandler := handlers.NewCharacterHandler(services.Character)
-	postcardHandler := handlers.NewPostcardHandler(services.Postcard)
+	characterHandler := handlers.NewCharacterHandler(services.Character, services.Popularity, services.Recommend, services.Policy)
+	postcardHandler := handlers.NewPostcardHandler(services.Postcard, services.Policy, services.Render)
 	uploadHandler := handlers.NewUploadHandler(services.Upload)
+	reviewHandler := handlers.NewReviewHandler(services.Review)
+	reportHandler := handlers.NewReportHandler(services.Moderation)
+	personaHandler := handlers.NewPersonaHandler(services.Persona)
+	lorebookHandler := handlers.NewLorebookHandler(services.Lorebook, services.Policy)
+	conversationHandler := handlers.NewConversationHandler(services.Conversation)
+	templateHandler := handlers.NewTemplateHandler(services.Template)
+	printHandler := handlers.NewPrintHandler(services.Print)
+	exportHandler := handlers.NewExportHandler(services.Export)
+	shareHandler := handlers.NewShareHandler(services.Share, cfg.PublicBaseURL)
+	timelineHandler := handlers.NewTimelineHandler(services.Timeline)
+	moodHandler := handlers.NewMoodHandler(services.Emotion)
+	reminderHandler := handlers.NewReminderHandler(services.Reminder)
+	notificationHandler := handlers.NewNotificationHandler(services.Notification)
+	webhookHandler := handlers.NewWebhookHandler(services.Webhook)
 
 	// 健康检查
 	r.GET("/health", func(c *gin.Context) {
@@ -54,8 +68,11 @@ func SetupRoutes(r *gin.Engine, services *services.Services, cfg *config.Config)
 		// 角色路由
 		characters := api.Group("/characters")
 		{
-			characters.GET("", characterHandler.ListCharacters)   // 公开接口
-			characters.GET("/:id", characterHandler.GetCharacter) // 公开接口
+			characters.GET("", characterHandler.ListCharacters)                 // 公开接口
+			characters.GET("/trending", characterHandler.GetTrendingCharacters) // 公开接口
+			characters.GET("/:id", characterHandler.GetCharacter)               // 公开接口
+			// 公开接口，登录后可查看自己私有角色的评价
+			characters.GET("/:id/reviews", middleware.OptionalAuthMiddleware(jwtSecret), reviewHandler.ListReviews)
 
 			// 需要认证的角色路由
 			authenticated := characters.Use(middleware.AuthMiddleware(jwtSecret))
@@ -65,22 +82,135 @@ func SetupRoutes(r *gin.Engine, services *services.Services, cfg *config.Config)
 				authenticated.DELETE("/:id", characterHandler.DeleteCharacter)
 				authenticated.GET("/my", characterHandler.GetMyCharacters)
 				authenticated.GET("/favorites", characterHandler.GetFavoriteCharacters)
+				authenticated.GET("/recommended", characterHandler.GetRecommendedCharacters)
 				authenticated.POST("/:id/favorite", characterHandler.ToggleFavorite)
 				authenticated.GET("/:id/favorite", characterHandler.CheckFavoriteStatus)
+				authenticated.GET("/:id/review", reviewHandler.GetMyReview)
+				authenticated.PUT("/:id/review", reviewHandler.UpsertReview)
+				authenticated.DELETE("/:id/review", reviewHandler.DeleteReview)
+				authenticated.PUT("/:id/reviews/:review_id/reply", reviewHandler.ReplyToReview)
+				authenticated.GET("/:id/persona", personaHandler.GetCharacterPersona)
+				authenticated.PUT("/:id/persona", personaHandler.SetCharacterPersona)
+				authenticated.DELETE("/:id/persona", personaHandler.ClearCharacterPersona)
+				authenticated.GET("/:id/lorebook", lorebookHandler.ListEntries)
+				authenticated.POST("/:id/lorebook", lorebookHandler.CreateEntry)
+				authenticated.POST("/:id/lorebook/dry-run", lorebookHandler.DryRun)
+				authenticated.PUT("/:id/lorebook/:entry_id", lorebookHandler.UpdateEntry)
+				authenticated.DELETE("/:id/lorebook/:entry_id", lorebookHandler.DeleteEntry)
 			}
 		}
 
+		// 人设路由（需要认证）
+		personas := api.Group("/personas").Use(middleware.AuthMiddleware(jwtSecret))
+		{
+			personas.GET("", personaHandler.ListPersonas)
+			personas.POST("", personaHandler.CreatePersona)
+			personas.PUT("/:id", personaHandler.UpdatePersona)
+			personas.DELETE("/:id", personaHandler.DeletePersona)
+		}
+
 		// 明信片路由（全部需要认证）
 		postcards := api.Group("/postcards").Use(middleware.AuthMiddleware(jwtSecret))
 		{
 			postcards.POST("", postcardHandler.CreatePostcard)
 			postcards.GET("", postcardHandler.ListPostcards)
 			postcards.GET("/:id", postcardHandler.GetPostcard)
+			postcards.GET("/:id/memories", postcardHandler.GetRecalledMemories)
+			postcards.GET("/:id/render", postcardHandler.RenderPostcard)
+			postcards.POST("/:id/print", printHandler.CreatePostcardPrint)
+			postcards.POST("/:id/share", shareHandler.CreatePostcardShare)
+			postcards.POST("/:id/reply/stream", postcardHandler.StreamReply)
+			postcards.POST("/:id/regenerate", postcardHandler.RegenerateReply)
+			postcards.GET("/:id/alternatives", postcardHandler.ListAlternatives)
+			postcards.PUT("/:id/select", postcardHandler.SelectReply)
+			postcards.POST("/:id/fork", conversationHandler.ForkConversation)
 			postcards.PUT("/:id", postcardHandler.UpdatePostcard)
 			postcards.DELETE("/:id", postcardHandler.DeletePostcard)
+			postcards.POST("/conversations", postcardHandler.StartConversation)
 			postcards.GET("/conversations/:conversation_id", postcardHandler.GetConversation)
 		}
 
+		// 对话路由（需要认证）
+		conversations := api.Group("/conversations").Use(middleware.AuthMiddleware(jwtSecret))
+		{
+			conversations.GET("", conversationHandler.ListConversations)
+			conversations.GET("/:id", conversationHandler.GetConversation)
+			conversations.PATCH("/:id", conversationHandler.UpdateConversation)
+			conversations.POST("/:id/read", conversationHandler.MarkConversationRead)
+			conversations.GET("/:id/tree", conversationHandler.GetConversationTree)
+			conversations.POST("/:id/print", printHandler.CreateBookletPrint)
+			conversations.GET("/:id/export", exportHandler.ExportConversation)
+			conversations.POST("/:id/share", shareHandler.CreateConversationShare)
+		}
+
+		// 分享链接路由（需要认证）
+		shares := api.Group("/shares").Use(middleware.AuthMiddleware(jwtSecret))
+		{
+			shares.GET("", shareHandler.ListShareLinks)
+			shares.DELETE("/:id", shareHandler.RevokeShareLink)
+		}
+
+		// 时间线路由（需要认证）
+		timeline := api.Group("/timeline").Use(middleware.AuthMiddleware(jwtSecret))
+		{
+			timeline.GET("", timelineHandler.GetTimeline)
+			timeline.GET("/on-this-day", timelineHandler.GetOnThisDay)
+			timeline.GET("/calendar", timelineHandler.GetCalendar)
+		}
+
+		// 情绪路由（需要认证）
+		moods := api.Group("/moods").Use(middleware.AuthMiddleware(jwtSecret))
+		{
+			moods.GET("/trend", moodHandler.GetMoodTrend)
+		}
+
+		// 连续记录与提醒路由（需要认证）
+		api.GET("/streak", middleware.AuthMiddleware(jwtSecret), reminderHandler.GetStreak)
+		reminders := api.Group("/reminders").Use(middleware.AuthMiddleware(jwtSecret))
+		{
+			reminders.GET("", reminderHandler.ListReminders)
+			reminders.POST("", reminderHandler.CreateReminder)
+			reminders.PUT("/:id", reminderHandler.UpdateReminder)
+			reminders.DELETE("/:id", reminderHandler.DeleteReminder)
+		}
+
+		// 站内通知路由（需要认证）
+		notifications := api.Group("/notifications").Use(middleware.AuthMiddleware(jwtSecret))
+		{
+			notifications.GET("", notificationHandler.ListNotifications)
+			notifications.GET("/unread-count", notificationHandler.GetUnreadCount)
+			notifications.POST("/read-all", notificationHandler.MarkAllRead)
+			notifications.POST("/:id/read", notificationHandler.MarkRead)
+			notifications.GET("/preferences", notificationHandler.GetPreferences)
+			notifications.PUT("/preferences", notificationHandler.UpdatePreferences)
+		}
+
+		// Webhook 路由（需要认证）
+		webhooks := api.Group("/webhooks").Use(middleware.AuthMiddleware(jwtSecret))
+		{
+			webhooks.GET("", webhookHandler.ListWebhooks)
+			webhooks.POST("", webhookHandler.CreateWebhook)
+			webhooks.PUT("/:id", webhookHandler.UpdateWebhook)
+			webhooks.DELETE("/:id", webhookHandler.DeleteWebhook)
+			webhooks.POST("/:id/secret", webhookHandler.RotateSecret)
+			webhooks.POST("/:id/ping", webhookHandler.PingWebhook)
+			webhooks.GET("/:id/deliveries", webhookHandler.ListDeliveries)
+			webhooks.POST("/:id/deliveries/:delivery_id/redeliver", webhookHandler.Redeliver)
+		}
+
+		// 印刷导出任务路由（需要认证）
+		prints := api.Group("/prints").Use(middleware.AuthMiddleware(jwtSecret))
+		{
+			prints.GET("/:id", printHandler.GetPrintJob)
+		}
+
+		// 明信片模板路由（公开接口）
+		templates := api.Group("/templates")
+		{
+			templates.GET("", templateHandler.ListTemplates)
+			templates.GET("/:id", templateHandler.GetTemplate)
+		}
+
 		// 文件上传路由（需要认证）
 		upload := api.Group("/upload").Use(middleware.AuthMiddleware(jwtSecret))
 		{
@@ -88,9 +218,34 @@ func SetupRoutes(r *gin.Engine, services *services.Services, cfg *config.Config)
 			upload.POST("/avatar", uploadHandler.UploadAvatar)
 			upload.POST("/character-avatar", uploadHandler.UploadCharacterAvatar)
 			upload.POST("/audio", uploadHandler.UploadAudio)
+			upload.POST("/presign", uploadHandler.PresignUpload)
+			upload.POST("/confirm", uploadHandler.ConfirmUpload)
+		}
+
+		// 举报路由（需要认证）
+		reports := api.Group("/reports").Use(middleware.AuthMiddleware(jwtSecret))
+		{
+			reports.POST("", reportHandler.CreateReport)
+			reports.GET("/mine", reportHandler.ListMyReports)
+		}
+
+		// 管理后台路由（需要管理员权限）
+		admin := api.Group("/admin").Use(middleware.AuthMiddleware(jwtSecret), middleware.AdminMiddleware(services.User.IsAdmin))
+		{
+			admin.GET("/reports", reportHandler.ListReports)
+			admin.GET("/reports/:id", reportHandler.GetReport)
+			admin.PUT("/reports/:id", reportHandler.ReviewReport)
+			admin.GET("/templates", templateHandler.AdminListTemplates)
+			admin.POST("/templates", templateHandler.CreateTemplate)
+			admin.PUT("/templates/:id", templateHandler.UpdateTemplate)
+			admin.DELETE("/templates/:id", templateHandler.DeleteTemplate)
+			admin.POST("/uploads/gc", uploadHandler.CollectGarbage)
 		}
 	}
 
+	// 公开分享页（无需认证）
+	r.GET("/s/:token", shareHandler.ViewShare)
+
 	// Swagger 文档路由
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
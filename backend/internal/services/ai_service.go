@@ -1,7 +1,9 @@
 package services
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"memory-postcard-backend/config"
@@ -12,8 +14,9 @@ import (
 )
 
 type AIService struct {
-	config *config.Config
-	client *http.Client
+	config       *config.Config
+	client       *http.Client
+	streamClient *http.Client // 流式请求不设整体超时，由 context 控制
 }
 
 type OpenAIRequest struct {
@@ -21,6 +24,7 @@ type OpenAIRequest struct {
 	Messages    []Message `json:"messages"`
 	MaxTokens   int       `json:"max_tokens"`
 	Temperature float64   `json:"temperature"`
+	Stream      bool      `json:"stream,omitempty"`
 }
 
 type Message struct {
@@ -42,23 +46,57 @@ type APIError struct {
 	Type    string `json:"type"`
 }
 
+// openAIStreamChunk 流式响应中的一个数据块
+type openAIStreamChunk struct {
+	Choices []struct {
+		Delta Message `json:"delta"`
+	} `json:"choices"`
+	Error *APIError `json:"error,omitempty"`
+}
+
+// streamReplyTimeout 流式生成回复的最长时间
+const streamReplyTimeout = 2 * time.Minute
+
 func NewAIService(cfg *config.Config) *AIService {
 	return &AIService{
 		config: cfg,
 		client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		streamClient: &http.Client{},
+	}
+}
+
+// ReplyContext 生成 AI 回复所需的上下文
+type ReplyContext struct {
+	Character   *models.Character
+	Persona     *models.ResolvedPersona // 用户在对话中扮演的人设
+	Lore        []models.LorebookEntry  // 由关键词触发的世界设定
+	Memories    []models.RecalledMemory // 回忆起的过往明信片
+	History     []models.Postcard
+	UserMessage string
+}
+
+// RecalledMemories 返回回忆起的明信片 ID 及相似度，用于记录到 AI 回复明信片上
+func (rc *ReplyContext) RecalledMemories() []models.MemoryRecall {
+	var recalls []models.MemoryRecall
+	for _, memory := range rc.Memories {
+		recalls = append(recalls, models.MemoryRecall{PostcardID: memory.Postcard.ID, Score: memory.Score})
 	}
+	return recalls
 }
 
+// replyHistoryWindow 生成回复时带上的最近对话条数
+const replyHistoryWindow = 5
+
 // GenerateReply 生成 AI 回复
-func (s *AIService) GenerateReply(character *models.Character, history []models.Postcard, userMessage string) (string, error) {
+func (s *AIService) GenerateReply(rc *ReplyContext) (string, error) {
 	if s.config.OpenAIAPIKey == "" {
-		return s.generateMockReply(character, userMessage), nil
+		return s.generateMockReply(rc.Character, rc.UserMessage), nil
 	}
 
 	// 构建对话上下文
-	messages := s.buildConversationContext(character, history, userMessage)
+	messages := s.buildConversationContext(rc)
 
 	// 调用 OpenAI API
 	request := OpenAIRequest{
@@ -71,42 +109,139 @@ func (s *AIService) GenerateReply(character *models.Character, history []models.
 	response, err := s.callOpenAI(request)
 	if err != nil {
 		// 如果 API 调用失败，返回模拟回复
-		return s.generateMockReply(character, userMessage), nil
+		return s.generateMockReply(rc.Character, rc.UserMessage), nil
 	}
 
 	if len(response.Choices) == 0 {
-		return s.generateMockReply(character, userMessage), nil
+		return s.generateMockReply(rc.Character, rc.UserMessage), nil
+	}
+
+	return response.Choices[0].Message.Content, nil
+}
+
+// checkInInstruction 角色主动问候时代替用户来信的提示
+const checkInInstruction = "（TA已经%d天没有给你写明信片了。请以角色的身份主动给TA写一张明信片，自然地问候TA的近况，可以分享你最近的事情，不要责备TA没有来信。）"
+
+// GenerateCheckIn 生成角色主动寄出的问候明信片，rc.UserMessage 会被替换为问候提示
+func (s *AIService) GenerateCheckIn(rc *ReplyContext, silentDays int) (string, error) {
+	if s.config.OpenAIAPIKey == "" {
+		return s.generateMockCheckIn(rc.Character), nil
+	}
+
+	rc.UserMessage = fmt.Sprintf(checkInInstruction, silentDays)
+	response, err := s.callOpenAI(OpenAIRequest{
+		Model:       "gpt-3.5-turbo",
+		Messages:    s.buildConversationContext(rc),
+		MaxTokens:   500,
+		Temperature: 0.8,
+	})
+	if err != nil || len(response.Choices) == 0 {
+		return s.generateMockCheckIn(rc.Character), nil
 	}
 
 	return response.Choices[0].Message.Content, nil
 }
 
+// StreamReply 流式生成 AI 回复，每生成一段文本调用一次 onDelta，返回已生成的完整回复
+// 尚未生成任何内容时调用失败会回退到模拟回复；生成到一半失败时返回已生成的部分和错误
+func (s *AIService) StreamReply(ctx context.Context, rc *ReplyContext, onDelta func(delta string)) (string, error) {
+	if s.config.OpenAIAPIKey == "" {
+		return s.streamMockReply(rc, onDelta), nil
+	}
+
+	request := OpenAIRequest{
+		Model:       "gpt-3.5-turbo",
+		Messages:    s.buildConversationContext(rc),
+		MaxTokens:   500,
+		Temperature: 0.8,
+		Stream:      true,
+	}
+
+	var reply strings.Builder
+	err := s.callOpenAIStream(ctx, request, func(delta string) {
+		reply.WriteString(delta)
+		onDelta(delta)
+	})
+	if err != nil && reply.Len() == 0 {
+		return s.streamMockReply(rc, onDelta), nil
+	}
+
+	return reply.String(), err
+}
+
+// streamMockReply 以流式方式输出模拟回复
+func (s *AIService) streamMockReply(rc *ReplyContext, onDelta func(delta string)) string {
+	reply := s.generateMockReply(rc.Character, rc.UserMessage)
+	runes := []rune(reply)
+	for i := 0; i < len(runes); i += 4 {
+		end := i + 4
+		if end > len(runes) {
+			end = len(runes)
+		}
+		onDelta(string(runes[i:end]))
+	}
+	return reply
+}
+
 // buildConversationContext 构建对话上下文
-func (s *AIService) buildConversationContext(character *models.Character, history []models.Postcard, userMessage string) []Message {
-	messages := []Message{
-		{
-			Role: "system",
-			Content: fmt.Sprintf(`你是一个名叫"%s"的角色。角色描述：%s
+func (s *AIService) buildConversationContext(rc *ReplyContext) []Message {
+	character := rc.Character
+	systemPrompt := fmt.Sprintf(`你是一个名叫"%s"的角色。角色描述：%s
 
 请以这个角色的身份回复用户的明信片。回复应该：
 1. 符合角色的性格特点
 2. 语气自然、温暖
 3. 长度适中（100-300字）
 4. 体现明信片交流的温馨感觉
-5. 可以适当询问用户的近况或分享角色的想法`, character.Name, character.Description),
+5. 可以适当询问用户的近况或分享角色的想法`, character.Name, character.Description)
+
+	// 用户扮演的人设
+	if rc.Persona != nil && rc.Persona.Name != "" {
+		systemPrompt += fmt.Sprintf("\n\n给你写明信片的人是\"%s\"。", rc.Persona.Name)
+		if rc.Persona.Description != "" {
+			systemPrompt += fmt.Sprintf("关于TA：%s", rc.Persona.Description)
+		}
+	}
+
+	// 触发的世界设定
+	if len(rc.Lore) > 0 {
+		systemPrompt += "\n\n以下是与当前对话相关的世界设定，请在回复时保持一致："
+		for _, entry := range rc.Lore {
+			systemPrompt += fmt.Sprintf("\n【%s】%s", entry.Title, entry.Content)
+		}
+	}
+
+	// 回忆起的过往明信片
+	if len(rc.Memories) > 0 {
+		systemPrompt += "\n\n你记得TA以前写给你的这些明信片，可以在合适的时候自然地提起："
+		for _, memory := range rc.Memories {
+			systemPrompt += fmt.Sprintf("\n- （%s）%s", memory.Postcard.CreatedAt.Format("2006-01-02"), memory.Postcard.Content)
+		}
+	}
+
+	messages := []Message{
+		{
+			Role:    "system",
+			Content: systemPrompt,
 		},
 	}
 
-	// 添加历史对话（最近5条）
-	start := 0
-	if len(history) > 5 {
-		start = len(history) - 5
+	// 添加示例对话（few-shot），帮助保持角色的说话风格
+	userName := ""
+	if rc.Persona != nil {
+		userName = rc.Persona.Name
+	}
+	for _, example := range character.ExampleDialogues {
+		messages = append(messages,
+			Message{Role: "user", Content: ApplyPlaceholders(example.User, character.Name, userName)},
+			Message{Role: "assistant", Content: ApplyPlaceholders(example.Character, character.Name, userName)},
+		)
 	}
 
-	for i := start; i < len(history); i++ {
-		postcard := history[i]
+	// 添加历史对话（最近5条）
+	for _, postcard := range recentHistory(rc.History) {
 		role := "user"
-		if postcard.UserID == 0 { // 假设系统回复的 UserID 为 0
+		if postcard.Type == "ai" {
 			role = "assistant"
 		}
 		messages = append(messages, Message{
@@ -118,12 +253,28 @@ func (s *AIService) buildConversationContext(character *models.Character, histor
 	// 添加当前用户消息
 	messages = append(messages, Message{
 		Role:    "user",
-		Content: userMessage,
+		Content: rc.UserMessage,
 	})
 
 	return messages
 }
 
+// recentHistory 返回生成回复时使用的最近对话
+func recentHistory(history []models.Postcard) []models.Postcard {
+	if len(history) > replyHistoryWindow {
+		return history[len(history)-replyHistoryWindow:]
+	}
+	return history
+}
+
+// ApplyPlaceholders 替换文本中的 {{char}} 和 {{user}} 占位符
+func ApplyPlaceholders(text, characterName, userName string) string {
+	if userName == "" {
+		userName = "你"
+	}
+	return strings.NewReplacer("{{char}}", characterName, "{{user}}", userName).Replace(text)
+}
+
 // callOpenAI 调用 OpenAI API
 func (s *AIService) callOpenAI(request OpenAIRequest) (*OpenAIResponse, error) {
 	jsonData, err := json.Marshal(request)
@@ -157,6 +308,66 @@ func (s *AIService) callOpenAI(request OpenAIRequest) (*OpenAIResponse, error) {
 	return &response, nil
 }
 
+// callOpenAIStream 以流式方式调用 OpenAI API，逐段回调生成的文本
+func (s *AIService) callOpenAIStream(ctx context.Context, request OpenAIRequest, onDelta func(delta string)) error {
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.config.OpenAIBaseURL+"/chat/completions", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Authorization", "Bearer "+s.config.OpenAIAPIKey)
+
+	resp, err := s.streamClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var response OpenAIResponse
+		if err := json.NewDecoder(resp.Body).Decode(&response); err == nil && response.Error != nil {
+			return fmt.Errorf("OpenAI API error: %s", response.Error.Message)
+		}
+		return fmt.Errorf("OpenAI API returned status %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			return nil
+		}
+
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("failed to decode stream chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return fmt.Errorf("OpenAI API error: %s", chunk.Error.Message)
+		}
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			onDelta(chunk.Choices[0].Delta.Content)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read stream: %w", err)
+	}
+	return nil
+}
+
 // generateMockReply 生成模拟回复（当 AI 服务不可用时）
 func (s *AIService) generateMockReply(character *models.Character, userMessage string) string {
 	templates := []string{
@@ -166,13 +377,15 @@ func (s *AIService) generateMockReply(character *models.Character, userMessage s
 		"你的明信片就像一缕阳光照进了我的心里。%s 让我们继续保持这样美好的联系吧。",
 	}
 
-	// 简单的情感分析
+	// 根据本地情绪词典判断来信的情绪倾向
 	sentiment := "neutral"
-	lowerMessage := strings.ToLower(userMessage)
-	if strings.Contains(lowerMessage, "开心") || strings.Contains(lowerMessage, "高兴") || strings.Contains(lowerMessage, "快乐") {
-		sentiment = "happy"
-	} else if strings.Contains(lowerMessage, "难过") || strings.Contains(lowerMessage, "伤心") || strings.Contains(lowerMessage, "沮丧") {
-		sentiment = "sad"
+	emotions, _ := (&LexiconEmotionClassifier{}).Classify(userMessage)
+	if len(emotions) > 0 {
+		if valence := emotionValence[emotions[0].Emotion]; valence > 0.3 {
+			sentiment = "happy"
+		} else if valence < -0.3 {
+			sentiment = "sad"
+		}
 	}
 
 	var contextualResponse string
@@ -192,6 +405,16 @@ func (s *AIService) generateMockReply(character *models.Character, userMessage s
 	return fmt.Sprintf(template, contextualResponse)
 }
 
+// generateMockCheckIn 生成模拟的问候明信片（当 AI 服务不可用时）
+func (s *AIService) generateMockCheckIn(character *models.Character) string {
+	templates := []string{
+		"好久没有收到你的明信片了，最近过得怎么样？不管忙还是闲，都记得照顾好自己。我在这里，等你有空的时候再聊聊。",
+		"今天路过邮筒的时候突然想起了你。最近还好吗？有什么开心或烦恼的事，都可以写信告诉我。",
+		"这几天一直没有你的消息，有点想念我们之间的通信了。希望你一切都好，期待你的下一张明信片。",
+	}
+	return templates[int(time.Now().Unix())%len(templates)]
+}
+
 // GenerateImage 生成角色自拍图片（占位符实现）
 func (s *AIService) GenerateImage(character *models.Character, context string) (string, error) {
 	// 这里可以集成 DALL-E 或其他图像生成 API
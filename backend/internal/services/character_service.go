@@ -13,19 +13,29 @@ import (
 )
 
 type CharacterService struct {
-	db    *gorm.DB
-	redis *redis.Client
+	db       *gorm.DB
+	redis    *redis.Client
+	uploads  *UploadService
+	webhooks *WebhookService
+	notifier Notifier
 }
 
-func NewCharacterService(db *gorm.DB, redis *redis.Client) *CharacterService {
+func NewCharacterService(db *gorm.DB, redis *redis.Client, uploads *UploadService, webhooks *WebhookService, notifier Notifier) *CharacterService {
 	return &CharacterService{
-		db:    db,
-		redis: redis,
+		db:       db,
+		redis:    redis,
+		uploads:  uploads,
+		webhooks: webhooks,
+		notifier: notifier,
 	}
 }
 
 // CreateCharacter 创建角色
 func (s *CharacterService) CreateCharacter(userID uint, req *models.CharacterCreateRequest) (*models.Character, error) {
+	if err := s.uploads.VerifyOwnership(userID, req.AvatarURL, req.VoiceURL); err != nil {
+		return nil, err
+	}
+
 	character := models.Character{
 		CreatorID:    userID,
 		Name:         req.Name,
@@ -33,14 +43,23 @@ func (s *CharacterService) CreateCharacter(userID uint, req *models.CharacterCre
 		AvatarURL:    req.AvatarURL,
 		VoiceURL:     req.VoiceURL, // 音色URL字段
 		VoiceID:      req.VoiceID,  // 音色ID字段，用于AI生成声音
+		Tags:         req.Tags,
 		Visibility:   req.Visibility,
 		UserRoleName: req.UserRoleName,
 		UserRoleDesc: req.UserRoleDesc,
+
+		Greeting:         req.Greeting,
+		ExampleDialogues: req.ExampleDialogues,
+
+		LorebookTokenBudget: defaultLorebookTokenBudget,
 	}
 
 	if character.Visibility == "" {
 		character.Visibility = "public"
 	}
+	if req.LorebookTokenBudget != nil {
+		character.LorebookTokenBudget = *req.LorebookTokenBudget
+	}
 
 	if err := s.db.Create(&character).Error; err != nil {
 		return nil, fmt.Errorf("failed to create character: %w", err)
@@ -60,7 +79,7 @@ func (s *CharacterService) GetCharacter(id uint, userID *uint) (*models.Characte
 	// 先从缓存获取
 	if character := s.getCharacterFromCache(id); character != nil {
 		// 检查权限
-		if character.Visibility == "private" && (userID == nil || character.CreatorID != *userID) {
+		if (character.Visibility == "private" || character.HiddenAt != nil) && (userID == nil || character.CreatorID != *userID) {
 			return nil, errors.New("character not found")
 		}
 		return character, nil
@@ -77,7 +96,7 @@ func (s *CharacterService) GetCharacter(id uint, userID *uint) (*models.Characte
 	}
 
 	// 检查权限
-	if character.Visibility == "private" && (userID == nil || character.CreatorID != *userID) {
+	if (character.Visibility == "private" || character.HiddenAt != nil) && (userID == nil || character.CreatorID != *userID) {
 		return nil, errors.New("character not found")
 	}
 
@@ -121,8 +140,8 @@ func (s *CharacterService) ListCharacters(query *models.CharacterListQuery, user
 		db = db.Where("name LIKE ? OR description LIKE ?", "%"+query.Search+"%", "%"+query.Search+"%")
 	}
 
-	// 只显示激活的角色
-	db = db.Where("is_active = ?", true)
+	// 只显示激活且未被管理员隐藏的角色
+	db = db.Where("is_active = ? AND hidden_at IS NULL", true)
 
 	// 计算总数
 	if err := db.Count(&total).Error; err != nil {
@@ -175,19 +194,32 @@ func (s *CharacterService) UpdateCharacter(id uint, userID uint, req *models.Cha
 	if req.Description != "" {
 		character.Description = req.Description
 	}
-	if req.AvatarURL != "" {
+	if req.AvatarURL != "" && req.AvatarURL != character.AvatarURL {
+		if err := s.uploads.VerifyOwnership(userID, req.AvatarURL); err != nil {
+			return nil, err
+		}
 		character.AvatarURL = req.AvatarURL
 	}
-	if req.VoiceURL != "" {
+	if req.VoiceURL != "" && req.VoiceURL != character.VoiceURL {
+		if err := s.uploads.VerifyOwnership(userID, req.VoiceURL); err != nil {
+			return nil, err
+		}
 		character.VoiceURL = req.VoiceURL
 	}
 	if req.VoiceID != "" {
 		character.VoiceID = req.VoiceID
 	}
+	if req.Tags != nil {
+		character.Tags = req.Tags
+	}
 	if req.Visibility != "" {
 		character.Visibility = req.Visibility
 	}
 	if req.IsActive != nil {
+		// 被管理员隐藏的角色不能由创建者重新启用
+		if *req.IsActive && character.HiddenAt != nil {
+			return nil, errors.New("character hidden by moderator")
+		}
 		character.IsActive = *req.IsActive
 	}
 	if req.UserRoleName != "" {
@@ -196,6 +228,15 @@ func (s *CharacterService) UpdateCharacter(id uint, userID uint, req *models.Cha
 	if req.UserRoleDesc != "" {
 		character.UserRoleDesc = req.UserRoleDesc
 	}
+	if req.Greeting != "" {
+		character.Greeting = req.Greeting
+	}
+	if req.ExampleDialogues != nil {
+		character.ExampleDialogues = req.ExampleDialogues
+	}
+	if req.LorebookTokenBudget != nil {
+		character.LorebookTokenBudget = *req.LorebookTokenBudget
+	}
 
 	if err := s.db.Save(&character).Error; err != nil {
 		return nil, fmt.Errorf("failed to update character: %w", err)
@@ -208,6 +249,8 @@ func (s *CharacterService) UpdateCharacter(id uint, userID uint, req *models.Cha
 	// 重新加载数据
 	s.db.Preload("Creator").First(&character, character.ID)
 
+	go s.webhooks.Publish(userID, WebhookCharacterUpdated, character)
+
 	return &character, nil
 }
 
@@ -267,6 +310,7 @@ func (s *CharacterService) ToggleFavorite(userID uint, characterID uint) (bool,
 		if err := s.db.Create(&relation).Error; err != nil {
 			return false, fmt.Errorf("failed to create favorite relation: %w", err)
 		}
+		s.notifyFavorited(userID, characterID)
 		return true, nil
 	} else if result.Error != nil {
 		return false, fmt.Errorf("failed to get relation: %w", result.Error)
@@ -282,9 +326,40 @@ func (s *CharacterService) ToggleFavorite(userID uint, characterID uint) (bool,
 	s.clearCharacterCache(characterID)
 	s.clearCharacterListCache()
 
+	if relation.IsFavorite {
+		s.notifyFavorited(userID, characterID)
+	}
+
 	return relation.IsFavorite, nil
 }
 
+// notifyFavorited 通知角色创建者角色被收藏，收藏自己创建的角色时不通知
+func (s *CharacterService) notifyFavorited(userID, characterID uint) {
+	var character models.Character
+	if err := s.db.Select("id", "name", "creator_id").First(&character, characterID).Error; err != nil {
+		return
+	}
+	if character.CreatorID == 0 || character.CreatorID == userID {
+		return
+	}
+
+	var user models.User
+	s.db.Select("id", "username", "nickname").First(&user, userID)
+	name := user.Nickname
+	if name == "" {
+		name = user.Username
+	}
+	s.notifier.Notify(NotificationEvent{
+		UserID: character.CreatorID,
+		Type:   NotificationCharacterFavorited,
+		Title:  fmt.Sprintf("%s 收藏了你创建的角色「%s」", name, character.Name),
+		Payload: map[string]interface{}{
+			"character_id": character.ID,
+			"user_id":      userID,
+		},
+	})
+}
+
 // GetFavoriteCharacters 获取用户收藏的角色列表
 func (s *CharacterService) GetFavoriteCharacters(userID uint, page int, pageSize int) (*models.PaginatedResponse, error) {
 	var relations []models.UserCharacterRelation
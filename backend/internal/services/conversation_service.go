@@ -0,0 +1,416 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"memory-postcard-backend/internal/models"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ConversationService struct {
+	db *gorm.DB
+}
+
+func NewConversationService(db *gorm.DB) *ConversationService {
+	return &ConversationService{
+		db: db,
+	}
+}
+
+// EnsureConversation 确保对话记录存在，不存在时创建
+func (s *ConversationService) EnsureConversation(id string, userID, characterID uint) (*models.Conversation, error) {
+	conversation := models.Conversation{
+		ID:          id,
+		UserID:      userID,
+		CharacterID: characterID,
+	}
+	if err := s.db.Where(models.Conversation{ID: id}).FirstOrCreate(&conversation).Error; err != nil {
+		return nil, fmt.Errorf("failed to ensure conversation: %w", err)
+	}
+
+	return &conversation, nil
+}
+
+// ListConversations 获取用户的对话列表，置顶的对话在前，其余按最后活动时间倒序
+func (s *ConversationService) ListConversations(userID uint, query *models.ConversationListQuery) (*models.PaginatedResponse, error) {
+	var total int64
+
+	db := s.summaryQuery(userID).Where("conversations.is_archived = ?", query.Archived)
+
+	// 构建查询条件
+	if query.CharacterID != 0 {
+		db = db.Where("conversations.character_id = ?", query.CharacterID)
+	}
+
+	if query.Search != "" {
+		db = db.Where("conversations.title LIKE ?", "%"+query.Search+"%")
+	}
+
+	// 计算总数
+	if err := db.Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count conversations: %w", err)
+	}
+
+	// 分页
+	var summaries []models.ConversationSummary
+	offset := (query.Page - 1) * query.PageSize
+	if err := db.Select(summaryColumns).
+		Order("conversations.is_pinned DESC, last_activity_at DESC").
+		Offset(offset).Limit(query.PageSize).
+		Scan(&summaries).Error; err != nil {
+		return nil, fmt.Errorf("failed to get conversations: %w", err)
+	}
+
+	if err := s.fillSummaries(summaries); err != nil {
+		return nil, err
+	}
+
+	return &models.PaginatedResponse{
+		Items:      summaries,
+		Total:      total,
+		Page:       query.Page,
+		PageSize:   query.PageSize,
+		TotalPages: int((total + int64(query.PageSize) - 1) / int64(query.PageSize)),
+	}, nil
+}
+
+// GetConversationSummary 获取单个对话的概要
+func (s *ConversationService) GetConversationSummary(id string, userID uint) (*models.ConversationSummary, error) {
+	if _, err := s.getOwnConversation(id, userID); err != nil {
+		return nil, err
+	}
+
+	var summaries []models.ConversationSummary
+	if err := s.summaryQuery(userID).
+		Where("conversations.id = ?", id).
+		Select(summaryColumns).
+		Scan(&summaries).Error; err != nil {
+		return nil, fmt.Errorf("failed to get conversation: %w", err)
+	}
+	if len(summaries) == 0 {
+		return nil, errors.New("conversation not found")
+	}
+
+	if err := s.fillSummaries(summaries); err != nil {
+		return nil, err
+	}
+
+	return &summaries[0], nil
+}
+
+// UpdateConversation 重命名、置顶或归档对话
+func (s *ConversationService) UpdateConversation(id string, userID uint, req *models.ConversationUpdateRequest) (*models.ConversationSummary, error) {
+	conversation, err := s.getOwnConversation(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	if req.Title != nil {
+		updates["title"] = strings.TrimSpace(*req.Title)
+	}
+	if req.IsPinned != nil {
+		updates["is_pinned"] = *req.IsPinned
+	}
+	if req.IsArchived != nil {
+		updates["is_archived"] = *req.IsArchived
+	}
+	if req.CheckInDays != nil {
+		updates["check_in_days"] = *req.CheckInDays
+	}
+
+	if len(updates) > 0 {
+		if err := s.db.Model(conversation).Updates(updates).Error; err != nil {
+			return nil, fmt.Errorf("failed to update conversation: %w", err)
+		}
+	}
+
+	return s.GetConversationSummary(id, userID)
+}
+
+// MarkConversationRead 将对话中的 AI 回复全部标记为已读
+func (s *ConversationService) MarkConversationRead(id string, userID uint) error {
+	if _, err := s.getOwnConversation(id, userID); err != nil {
+		return err
+	}
+
+	if err := s.db.Model(&models.Postcard{}).
+		Where("conversation_id = ? AND user_id = ? AND type = ? AND status <> ?", id, userID, "ai", "read").
+		UpdateColumn("status", "read").Error; err != nil {
+		return fmt.Errorf("failed to mark conversation read: %w", err)
+	}
+
+	return nil
+}
+
+// summaryColumns 对话概要查询的字段，对话中还没有明信片时以创建时间作为最后活动时间
+const summaryColumns = "conversations.*, " +
+	"COALESCE(stats.message_count, 0) AS message_count, " +
+	"COALESCE(stats.unread_count, 0) AS unread_count, " +
+	"COALESCE(stats.last_activity_at, conversations.created_at) AS last_activity_at"
+
+// summaryQuery 构建对话概要查询：关联每个对话的明信片统计（只统计选定的回复）
+func (s *ConversationService) summaryQuery(userID uint) *gorm.DB {
+	stats := s.db.Model(&models.Postcard{}).
+		Select("conversation_id, COUNT(*) AS message_count, MAX(created_at) AS last_activity_at, "+
+			"SUM(CASE WHEN type = 'ai' AND status <> 'read' THEN 1 ELSE 0 END) AS unread_count").
+		Where("user_id = ? AND is_canonical = ?", userID, true).
+		Group("conversation_id")
+
+	return s.db.Table("conversations").
+		Joins("LEFT JOIN (?) AS stats ON stats.conversation_id = conversations.id", stats).
+		Where("conversations.user_id = ?", userID)
+}
+
+// fillSummaries 填充对话概要的角色和最后一张明信片
+func (s *ConversationService) fillSummaries(summaries []models.ConversationSummary) error {
+	if len(summaries) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(summaries))
+	characterIDs := make([]uint, len(summaries))
+	for i, summary := range summaries {
+		ids[i] = summary.ID
+		characterIDs[i] = summary.CharacterID
+	}
+
+	var characters []models.Character
+	if err := s.db.Unscoped().Where("id IN ?", characterIDs).Find(&characters).Error; err != nil {
+		return fmt.Errorf("failed to get characters: %w", err)
+	}
+	characterMap := make(map[uint]*models.Character, len(characters))
+	for i := range characters {
+		characterMap[characters[i].ID] = &characters[i]
+	}
+
+	lastPostcards, err := s.lastPostcards(ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range summaries {
+		summaries[i].Character = characterMap[summaries[i].CharacterID]
+		summaries[i].LastPostcard = lastPostcards[summaries[i].ID]
+	}
+
+	return nil
+}
+
+// lastPostcards 获取每个对话的最后一张明信片（只取选定的回复）
+func (s *ConversationService) lastPostcards(ids []string) (map[string]*models.Postcard, error) {
+	var postcards []models.Postcard
+	if err := s.db.Where("id IN (?)", s.db.Model(&models.Postcard{}).
+		Select("MAX(id)").
+		Where("conversation_id IN ? AND is_canonical = ?", ids, true).
+		Group("conversation_id")).
+		Find(&postcards).Error; err != nil {
+		return nil, fmt.Errorf("failed to get last postcards: %w", err)
+	}
+
+	result := make(map[string]*models.Postcard, len(postcards))
+	for i := range postcards {
+		result[postcards[i].ConversationID] = &postcards[i]
+	}
+
+	return result, nil
+}
+
+// ForkConversation 从某张明信片分支出新对话
+// 新对话继承原对话中截至该明信片（含）的历史，备选回复中只继承选定的回复；从备选回复分支时继承该备选回复
+func (s *ConversationService) ForkConversation(postcardID, userID uint) (*models.ConversationForkResponse, error) {
+	var forkPoint models.Postcard
+	if err := s.db.Where("id = ? AND user_id = ?", postcardID, userID).First(&forkPoint).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("postcard not found")
+		}
+		return nil, fmt.Errorf("failed to get postcard: %w", err)
+	}
+
+	parent, err := s.getOwnConversation(forkPoint.ConversationID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 按对话顺序取分支点之前的明信片，重新生成的回复仍排在所回复的明信片之后
+	history, err := canonicalHistory(s.db, forkPoint.ConversationID, &forkPoint)
+	if err != nil {
+		return nil, err
+	}
+	if !forkPoint.IsCanonical && forkPoint.ReplyToID != nil {
+		// 从备选回复分支时，去掉同一明信片的选定回复
+		filtered := history[:0]
+		for _, postcard := range history {
+			if postcard.ReplyToID == nil || *postcard.ReplyToID != *forkPoint.ReplyToID {
+				filtered = append(filtered, postcard)
+			}
+		}
+		history = filtered
+	}
+	history = append(history, forkPoint)
+
+	conversation := models.Conversation{
+		ID:                   uuid.New().String(),
+		UserID:               userID,
+		CharacterID:          parent.CharacterID,
+		ParentID:             &parent.ID,
+		ForkedFromPostcardID: &forkPoint.ID,
+	}
+	copies := make([]models.Postcard, 0, len(history))
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&conversation).Error; err != nil {
+			return err
+		}
+
+		// 原明信片 ID -> 副本 ID，用于重建回复关系
+		idMap := make(map[uint]uint, len(history))
+		for _, original := range history {
+			copied := original
+			copied.ID = 0
+			copied.ConversationID = conversation.ID
+			copied.IsCanonical = true
+			copied.CopiedFromID = &original.ID
+			if original.CopiedFromID != nil {
+				copied.CopiedFromID = original.CopiedFromID
+			}
+			copied.ReplyToID = nil
+			if original.ReplyToID != nil {
+				if newID, ok := idMap[*original.ReplyToID]; ok {
+					copied.ReplyToID = &newID
+				}
+			}
+
+			if err := tx.Create(&copied).Error; err != nil {
+				return err
+			}
+			idMap[original.ID] = copied.ID
+			copies = append(copies, copied)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fork conversation: %w", err)
+	}
+
+	return &models.ConversationForkResponse{
+		Conversation: conversation,
+		Postcards:    copies,
+	}, nil
+}
+
+// GetConversationTree 获取对话所在的分支树，从根对话开始
+func (s *ConversationService) GetConversationTree(conversationID string, userID uint) (*models.ConversationTreeNode, error) {
+	conversation, err := s.getOwnConversation(conversationID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 同一角色下的所有对话，分支只会出现在同一用户与同一角色之间
+	var conversations []models.Conversation
+	if err := s.db.Where("user_id = ? AND character_id = ?", userID, conversation.CharacterID).
+		Order("created_at ASC").
+		Find(&conversations).Error; err != nil {
+		return nil, fmt.Errorf("failed to get conversations: %w", err)
+	}
+
+	nodes := make(map[string]*models.ConversationTreeNode, len(conversations))
+	for _, c := range conversations {
+		nodes[c.ID] = &models.ConversationTreeNode{Conversation: c, Children: []*models.ConversationTreeNode{}}
+	}
+
+	// 向上找到根对话
+	root := nodes[conversation.ID]
+	visited := map[string]bool{root.ID: true}
+	for root.ParentID != nil {
+		parent, ok := nodes[*root.ParentID]
+		if !ok || visited[parent.ID] {
+			break
+		}
+		visited[parent.ID] = true
+		root = parent
+	}
+
+	for _, c := range conversations {
+		if c.ParentID == nil || c.ID == root.ID {
+			continue
+		}
+		if parent, ok := nodes[*c.ParentID]; ok {
+			parent.Children = append(parent.Children, nodes[c.ID])
+		}
+	}
+
+	// 只统计树中的对话
+	var ids []string
+	var collect func(node *models.ConversationTreeNode)
+	collect = func(node *models.ConversationTreeNode) {
+		ids = append(ids, node.ID)
+		for _, child := range node.Children {
+			collect(child)
+		}
+	}
+	collect(root)
+
+	if err := s.fillTreeStats(nodes, ids); err != nil {
+		return nil, err
+	}
+
+	return root, nil
+}
+
+// fillTreeStats 填充对话树节点的明信片数量和最后一张明信片
+func (s *ConversationService) fillTreeStats(nodes map[string]*models.ConversationTreeNode, ids []string) error {
+	var counts []struct {
+		ConversationID string
+		Count          int64
+	}
+	if err := s.db.Model(&models.Postcard{}).
+		Select("conversation_id, COUNT(*) AS count").
+		Where("conversation_id IN ? AND is_canonical = ?", ids, true).
+		Group("conversation_id").
+		Scan(&counts).Error; err != nil {
+		return fmt.Errorf("failed to count postcards: %w", err)
+	}
+	for _, c := range counts {
+		nodes[c.ConversationID].PostcardCount = c.Count
+	}
+
+	lastPostcards, err := s.lastPostcards(ids)
+	if err != nil {
+		return err
+	}
+	for id, postcard := range lastPostcards {
+		nodes[id].LastPostcard = postcard
+	}
+
+	return nil
+}
+
+// getOwnConversation 获取用户的对话
+// 在引入对话记录之前创建的对话没有记录，根据其中的明信片补建
+func (s *ConversationService) getOwnConversation(id string, userID uint) (*models.Conversation, error) {
+	var conversation models.Conversation
+	err := s.db.Where("id = ?", id).First(&conversation).Error
+	if err == nil {
+		if conversation.UserID != userID {
+			return nil, errors.New("conversation not found")
+		}
+		return &conversation, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	var postcard models.Postcard
+	if err := s.db.Where("conversation_id = ? AND user_id = ?", id, userID).Order("id ASC").First(&postcard).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("conversation not found")
+		}
+		return nil, fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	return s.EnsureConversation(id, userID, postcard.CharacterID)
+}
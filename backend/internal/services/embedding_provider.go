@@ -0,0 +1,174 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"memory-postcard-backend/config"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// EmbeddingProvider 文本向量化服务
+type EmbeddingProvider interface {
+	// Name 返回模型标识，用于区分不同模型生成的向量
+	Name() string
+	Embed(texts []string) ([][]float32, error)
+}
+
+// NewEmbeddingProvider 根据配置创建向量化服务
+// 配置为 openai 但未设置 API Key 时回退到本地实现
+func NewEmbeddingProvider(cfg *config.Config) EmbeddingProvider {
+	if cfg.EmbeddingProvider == "openai" && cfg.OpenAIAPIKey != "" {
+		return &OpenAIEmbeddingProvider{
+			config: cfg,
+			client: &http.Client{
+				Timeout: 30 * time.Second,
+			},
+		}
+	}
+	return NewHashEmbeddingProvider(512)
+}
+
+// OpenAIEmbeddingProvider 调用 OpenAI 兼容的 /embeddings 接口
+type OpenAIEmbeddingProvider struct {
+	config *config.Config
+	client *http.Client
+}
+
+type openAIEmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type openAIEmbeddingResponse struct {
+	Data []struct {
+		Embedding []float32 `json:"embedding"`
+		Index     int       `json:"index"`
+	} `json:"data"`
+	Error *APIError `json:"error,omitempty"`
+}
+
+func (p *OpenAIEmbeddingProvider) Name() string {
+	return p.config.EmbeddingModel
+}
+
+func (p *OpenAIEmbeddingProvider) Embed(texts []string) ([][]float32, error) {
+	jsonData, err := json.Marshal(openAIEmbeddingRequest{
+		Model: p.config.EmbeddingModel,
+		Input: texts,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", p.config.OpenAIBaseURL+"/embeddings", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.config.OpenAIAPIKey)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var response openAIEmbeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if response.Error != nil {
+		return nil, fmt.Errorf("OpenAI API error: %s", response.Error.Message)
+	}
+	if len(response.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(response.Data))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, item := range response.Data {
+		if item.Index < 0 || item.Index >= len(vectors) {
+			return nil, fmt.Errorf("invalid embedding index %d", item.Index)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+
+	return vectors, nil
+}
+
+// HashEmbeddingProvider 本地向量化实现，无需外部服务
+// 使用特征哈希：中日韩文字取单字和相邻两字，其他文字按单词切分
+type HashEmbeddingProvider struct {
+	dimensions int
+}
+
+func NewHashEmbeddingProvider(dimensions int) *HashEmbeddingProvider {
+	return &HashEmbeddingProvider{
+		dimensions: dimensions,
+	}
+}
+
+func (p *HashEmbeddingProvider) Name() string {
+	return fmt.Sprintf("local-hash-%d", p.dimensions)
+}
+
+func (p *HashEmbeddingProvider) Embed(texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vector := make([]float32, p.dimensions)
+		for _, feature := range hashFeatures(text) {
+			h := fnv.New32a()
+			h.Write([]byte(feature))
+			sum := h.Sum32()
+			// 最高位决定符号，减少哈希冲突带来的偏差
+			sign := float32(1)
+			if sum&0x80000000 != 0 {
+				sign = -1
+			}
+			vector[int(sum%uint32(p.dimensions))] += sign
+		}
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
+
+// hashFeatures 提取文本特征
+func hashFeatures(text string) []string {
+	var features []string
+	var word []rune
+	var prevCJK rune
+
+	flushWord := func() {
+		if len(word) > 0 {
+			features = append(features, string(word))
+			word = word[:0]
+		}
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			flushWord()
+			features = append(features, string(r))
+			if prevCJK != 0 {
+				features = append(features, string([]rune{prevCJK, r}))
+			}
+			prevCJK = r
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			prevCJK = 0
+			word = append(word, r)
+		default:
+			prevCJK = 0
+			flushWord()
+		}
+	}
+	flushWord()
+
+	return features
+}
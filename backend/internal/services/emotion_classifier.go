@@ -0,0 +1,250 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"memory-postcard-backend/config"
+	"memory-postcard-backend/internal/models"
+	"sort"
+	"strings"
+)
+
+const (
+	// maxEmotionTags 每张明信片最多保留的情绪标签数
+	maxEmotionTags = 3
+	// minEmotionShare 情绪占比低于该值时不作为标签
+	minEmotionShare = 0.2
+)
+
+// emotionValence 各情绪的效价，用于计算情绪趋势中的积极程度
+var emotionValence = map[string]float64{
+	"joy":       1,
+	"love":      1,
+	"gratitude": 0.8,
+	"calm":      0.5,
+	"surprise":  0.2,
+	"neutral":   0,
+	"longing":   -0.3,
+	"fear":      -0.8,
+	"sadness":   -1,
+	"anger":     -1,
+}
+
+// EmotionClassifier 明信片情绪分类器
+type EmotionClassifier interface {
+	// Name 返回分类器标识，记录在情绪标签上
+	Name() string
+	Classify(text string) ([]models.EmotionScore, error)
+}
+
+// NewEmotionClassifier 根据配置创建情绪分类器
+// 配置为 llm 但未设置 API Key 时回退到本地词典实现
+func NewEmotionClassifier(cfg *config.Config, aiService *AIService) EmotionClassifier {
+	if cfg.EmotionClassifier == "llm" && cfg.OpenAIAPIKey != "" {
+		return &LLMEmotionClassifier{ai: aiService}
+	}
+	return &LexiconEmotionClassifier{}
+}
+
+// LLMEmotionClassifier 调用对话模型判断情绪
+type LLMEmotionClassifier struct {
+	ai *AIService
+}
+
+const emotionClassifierPrompt = `你是情绪分析助手。分析用户写的明信片表达的情绪，只能从以下标签中选择最多 3 个：
+joy（快乐）、love（爱意）、gratitude（感激）、calm（平静）、surprise（惊讶）、longing（思念）、sadness（悲伤）、fear（担忧）、anger（愤怒）、neutral（没有明显情绪）。
+为每个标签给出 0 到 1 的强度，只输出 JSON，格式为：{"emotions":[{"emotion":"joy","score":0.7}]}`
+
+func (c *LLMEmotionClassifier) Name() string {
+	return "llm"
+}
+
+func (c *LLMEmotionClassifier) Classify(text string) ([]models.EmotionScore, error) {
+	response, err := c.ai.callOpenAI(OpenAIRequest{
+		Model: "gpt-3.5-turbo",
+		Messages: []Message{
+			{Role: "system", Content: emotionClassifierPrompt},
+			{Role: "user", Content: text},
+		},
+		MaxTokens:   100,
+		Temperature: 0,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Choices) == 0 {
+		return nil, fmt.Errorf("empty response")
+	}
+
+	// 模型有时会用代码块包裹 JSON
+	content := strings.TrimSpace(response.Choices[0].Message.Content)
+	if start, end := strings.Index(content, "{"), strings.LastIndex(content, "}"); start >= 0 && end > start {
+		content = content[start : end+1]
+	}
+
+	var result struct {
+		Emotions []models.EmotionScore `json:"emotions"`
+	}
+	if err := json.Unmarshal([]byte(content), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse emotions: %w", err)
+	}
+
+	scores := make(map[string]float64)
+	for _, e := range result.Emotions {
+		if _, ok := emotionValence[e.Emotion]; ok && e.Score > 0 {
+			scores[e.Emotion] += e.Score
+		}
+	}
+	return topEmotions(scores), nil
+}
+
+// LexiconEmotionClassifier 基于中英文情绪词典的本地分类器，无需外部服务
+// 中文按子串匹配，英文按单词匹配，前面紧跟否定词的情绪词不计入
+type LexiconEmotionClassifier struct{}
+
+// zhEmotionLexicon 中文情绪词典
+var zhEmotionLexicon = map[string][]string{
+	"joy":       {"开心", "高兴", "快乐", "愉快", "幸福", "兴奋", "太好了", "哈哈", "满足", "喜悦", "欣喜", "美好", "不错", "顺利", "期待", "棒"},
+	"love":      {"喜欢", "爱你", "我爱", "深爱", "心动", "温柔", "亲爱", "甜蜜", "拥抱", "陪伴"},
+	"gratitude": {"谢谢", "感谢", "感激", "多亏", "感恩"},
+	"calm":      {"平静", "安静", "放松", "悠闲", "惬意", "宁静", "舒服", "安心", "自在"},
+	"surprise":  {"惊讶", "没想到", "竟然", "居然", "意外", "吃惊", "震惊", "惊喜"},
+	"longing":   {"想念", "思念", "怀念", "想你", "好想", "回忆", "小时候", "远方"},
+	"sadness":   {"难过", "伤心", "沮丧", "失落", "悲伤", "遗憾", "心痛", "不开心", "孤单", "寂寞", "失望", "痛苦", "委屈", "低落", "郁闷", "哭"},
+	"fear":      {"害怕", "担心", "焦虑", "紧张", "不安", "恐惧", "担忧", "压力", "慌"},
+	"anger":     {"生气", "愤怒", "讨厌", "气死", "可恶", "恼火", "受不了", "抓狂", "烦"},
+}
+
+// zhNegations 中文否定词，出现在情绪词前两个字以内时视为否定
+var zhNegations = []rune("不没别未无")
+
+// enEmotionLexicon 英文情绪词典，以 * 结尾的词按前缀匹配
+var enEmotionLexicon = map[string][]string{
+	"joy":       {"happy", "happiness", "glad", "joy*", "excit*", "great", "wonderful", "awesome", "fun", "delight*", "cheer*", "smil*", "laugh*"},
+	"love":      {"love*", "lovely", "ador*", "darling", "sweetheart", "hug", "hugs", "hugged", "romantic", "crush"},
+	"gratitude": {"thank*", "grateful", "gratitude", "appreciat*"},
+	"calm":      {"calm*", "peace*", "relax*", "quiet", "serene", "cozy", "comfortable"},
+	"surprise":  {"surpris*", "amaz*", "unexpected*", "shock*", "wow"},
+	"longing":   {"miss", "missing", "missed", "nostalgi*", "remember*", "memories", "longing", "homesick"},
+	"sadness":   {"sad", "sadly", "sadness", "unhappy", "cry", "cries", "crying", "cried", "tears", "lonely", "loneliness", "depress*", "upset", "heartbroken", "disappoint*", "sorrow*", "grief", "hurt*"},
+	"fear":      {"afraid", "scared", "fear*", "anxi*", "worr*", "nervous", "panic*", "stress*", "terrif*"},
+	"anger":     {"angry", "anger", "mad", "furious", "annoy*", "hate*", "irritat*", "frustrat*", "rage"},
+}
+
+// enNegations 英文否定词，出现在情绪词前三个单词以内时视为否定
+var enNegations = map[string]bool{
+	"not": true, "no": true, "never": true, "dont": true, "don't": true, "didnt": true, "didn't": true,
+	"isnt": true, "isn't": true, "wasnt": true, "wasn't": true, "cant": true, "can't": true, "without": true,
+}
+
+func (c *LexiconEmotionClassifier) Name() string {
+	return "lexicon"
+}
+
+func (c *LexiconEmotionClassifier) Classify(text string) ([]models.EmotionScore, error) {
+	text = strings.ToLower(text)
+	scores := make(map[string]float64)
+
+	runes := []rune(text)
+	for emotion, words := range zhEmotionLexicon {
+		for _, word := range words {
+			pattern := []rune(word)
+			for i := 0; i+len(pattern) <= len(runes); i++ {
+				if string(runes[i:i+len(pattern)]) != word {
+					continue
+				}
+				if !zhNegated(runes, i) {
+					scores[emotion]++
+				}
+				i += len(pattern) - 1
+			}
+		}
+	}
+
+	tokens := strings.FieldsFunc(text, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r == '\'')
+	})
+	for i, token := range tokens {
+		emotion := enEmotion(token)
+		if emotion == "" || enNegated(tokens, i) {
+			continue
+		}
+		scores[emotion]++
+	}
+
+	return topEmotions(scores), nil
+}
+
+// zhNegated 判断位置 i 处的情绪词前两个字内是否有否定词
+func zhNegated(runes []rune, i int) bool {
+	for j := i - 1; j >= 0 && j >= i-2; j-- {
+		for _, neg := range zhNegations {
+			if runes[j] == neg {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// enNegated 判断第 i 个单词前三个单词内是否有否定词
+func enNegated(tokens []string, i int) bool {
+	for j := i - 1; j >= 0 && j >= i-3; j-- {
+		if enNegations[tokens[j]] || strings.HasSuffix(tokens[j], "n't") {
+			return true
+		}
+	}
+	return false
+}
+
+// enEmotion 返回英文单词对应的情绪，不是情绪词时返回空字符串
+func enEmotion(token string) string {
+	for emotion, words := range enEmotionLexicon {
+		for _, word := range words {
+			if prefix, ok := strings.CutSuffix(word, "*"); ok {
+				if strings.HasPrefix(token, prefix) {
+					return emotion
+				}
+			} else if token == word {
+				return emotion
+			}
+		}
+	}
+	return ""
+}
+
+// topEmotions 取占比最高的几个情绪并归一化，没有情绪时返回 neutral
+// 占比最高的情绪总会保留（多个情绪并列时按名称取第一个），之后占比过低的情绪不作为标签
+func topEmotions(scores map[string]float64) []models.EmotionScore {
+	var total float64
+	result := make([]models.EmotionScore, 0, len(scores))
+	for emotion, score := range scores {
+		total += score
+		result = append(result, models.EmotionScore{Emotion: emotion, Score: score})
+	}
+	if total == 0 {
+		return []models.EmotionScore{{Emotion: "neutral", Score: 1}}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+		return result[i].Emotion < result[j].Emotion
+	})
+
+	kept := result[:0]
+	var keptTotal float64
+	for _, e := range result {
+		if len(kept) == maxEmotionTags || len(kept) > 0 && e.Score/total < minEmotionShare {
+			break
+		}
+		kept = append(kept, e)
+		keptTotal += e.Score
+	}
+	for i := range kept {
+		kept[i].Score = math.Round(kept[i].Score/keptTotal*100) / 100
+	}
+	return kept
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"testing"
+
+	"memory-postcard-backend/internal/models"
+)
+
+func TestTopEmotions(t *testing.T) {
+	tests := []struct {
+		name   string
+		scores map[string]float64
+		want   []models.EmotionScore
+	}{
+		{"no emotions", map[string]float64{}, []models.EmotionScore{{Emotion: "neutral", Score: 1}}},
+		{"single emotion", map[string]float64{"joy": 2}, []models.EmotionScore{{Emotion: "joy", Score: 1}}},
+		{"minor emotion dropped", map[string]float64{"joy": 5, "sadness": 1}, []models.EmotionScore{{Emotion: "joy", Score: 1}}},
+		{"at most three tags", map[string]float64{"joy": 3, "love": 3, "calm": 3, "anger": 3},
+			[]models.EmotionScore{{Emotion: "anger", Score: 0.33}, {Emotion: "calm", Score: 0.33}, {Emotion: "joy", Score: 0.33}}},
+		{"six-way tie keeps the top emotion",
+			map[string]float64{"joy": 1, "love": 1, "gratitude": 1, "calm": 1, "surprise": 1, "longing": 1},
+			[]models.EmotionScore{{Emotion: "calm", Score: 1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := topEmotions(tt.scores)
+			if len(got) != len(tt.want) {
+				t.Fatalf("topEmotions() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("topEmotions()[%d] = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLexiconEmotionClassifier(t *testing.T) {
+	c := &LexiconEmotionClassifier{}
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"empty text", "", "neutral"},
+		{"chinese", "今天真开心", "joy"},
+		{"chinese negation", "我不开心", "sadness"},
+		{"negated word ignored", "一点也不害怕，只是想念你", "longing"},
+		{"english", "I miss you so much", "longing"},
+		{"english negation", "I'm not happy, just tired and sad", "sadness"},
+		{"six-way tie", "开心 喜欢 谢谢 平静 惊讶 想念", "calm"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.Classify(tt.text)
+			if err != nil {
+				t.Fatalf("Classify() error = %v", err)
+			}
+			if len(got) == 0 {
+				t.Fatalf("Classify() returned no emotions")
+			}
+			if got[0].Emotion != tt.want {
+				t.Errorf("Classify() top emotion = %s, want %s", got[0].Emotion, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"memory-postcard-backend/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// emotionBackfillBatchSize 补全历史明信片情绪时每批处理的数量
+	emotionBackfillBatchSize = 100
+
+	// 情绪趋势的默认区间和最大周期数
+	moodTrendDefaultDays  = 30
+	moodTrendDefaultWeeks = 12
+	moodTrendMaxPoints    = 366
+)
+
+type EmotionService struct {
+	db          *gorm.DB
+	classifier  EmotionClassifier
+	lexicon     *LexiconEmotionClassifier
+	userService *UserService
+}
+
+func NewEmotionService(db *gorm.DB, classifier EmotionClassifier, userService *UserService) *EmotionService {
+	return &EmotionService{
+		db:          db,
+		classifier:  classifier,
+		lexicon:     &LexiconEmotionClassifier{},
+		userService: userService,
+	}
+}
+
+// ClassifyPostcard 分析用户明信片的情绪并保存标签，重新分类时替换原有标签
+// AI 回复不参与情绪统计
+func (s *EmotionService) ClassifyPostcard(postcard *models.Postcard) error {
+	if postcard.Type != "user" {
+		return nil
+	}
+
+	source := s.classifier.Name()
+	scores, err := s.classifier.Classify(postcard.Content)
+	if err != nil {
+		// 模型调用失败时使用本地词典，避免明信片缺少情绪标签
+		log.Printf("Failed to classify postcard %d with %s, falling back to lexicon: %v", postcard.ID, source, err)
+		source = s.lexicon.Name()
+		if scores, err = s.lexicon.Classify(postcard.Content); err != nil {
+			return err
+		}
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("postcard_id = ?", postcard.ID).Delete(&models.PostcardEmotion{}).Error; err != nil {
+			return fmt.Errorf("failed to clear emotions: %w", err)
+		}
+		emotions := make([]models.PostcardEmotion, 0, len(scores))
+		for _, e := range scores {
+			emotions = append(emotions, models.PostcardEmotion{
+				PostcardID: postcard.ID,
+				UserID:     postcard.UserID,
+				Emotion:    e.Emotion,
+				Score:      e.Score,
+				Source:     source,
+			})
+		}
+		if err := tx.Create(&emotions).Error; err != nil {
+			return fmt.Errorf("failed to save emotions: %w", err)
+		}
+		return nil
+	})
+}
+
+// BackfillEmotions 为引入情绪分析之前的用户明信片补充情绪标签
+func (s *EmotionService) BackfillEmotions() {
+	var lastID uint
+	classified := 0
+	for {
+		var postcards []models.Postcard
+		if err := s.db.Where("type = ? AND id > ?", "user", lastID).
+			Where("id NOT IN (?)", s.db.Model(&models.PostcardEmotion{}).Select("postcard_id")).
+			Order("id ASC").Limit(emotionBackfillBatchSize).
+			Find(&postcards).Error; err != nil {
+			log.Printf("Failed to load postcards for emotion backfill: %v", err)
+			return
+		}
+		if len(postcards) == 0 {
+			break
+		}
+
+		for i := range postcards {
+			if err := s.ClassifyPostcard(&postcards[i]); err != nil {
+				log.Printf("Failed to classify postcard %d: %v", postcards[i].ID, err)
+				continue
+			}
+			classified++
+		}
+		lastID = postcards[len(postcards)-1].ID
+	}
+
+	if classified > 0 {
+		log.Printf("Classified emotions for %d existing postcards", classified)
+	}
+}
+
+// GetMoodTrend 按日或按周汇总用户明信片的情绪
+func (s *EmotionService) GetMoodTrend(userID uint, query *models.MoodTrendQuery) (*models.MoodTrendResponse, error) {
+	loc := s.userService.GetLocation(userID)
+
+	now := time.Now().In(loc)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	if query.To != "" {
+		to, _ = time.ParseInLocation(timelineDateLayout, query.To, loc)
+	}
+
+	step := 1
+	if query.Period == "week" {
+		step = 7
+		to = weekStart(to)
+	}
+
+	var from time.Time
+	if query.From != "" {
+		from, _ = time.ParseInLocation(timelineDateLayout, query.From, loc)
+		if query.Period == "week" {
+			from = weekStart(from)
+		}
+	} else if query.Period == "week" {
+		from = to.AddDate(0, 0, -7*(moodTrendDefaultWeeks-1))
+	} else {
+		from = to.AddDate(0, 0, -(moodTrendDefaultDays - 1))
+	}
+
+	if from.After(to) {
+		return nil, errors.New("invalid date range")
+	}
+
+	// 按日期逐个生成周期，避免夏令时切换造成的偏差
+	var periods []time.Time
+	for t := from; !t.After(to); t = t.AddDate(0, 0, step) {
+		if len(periods) == moodTrendMaxPoints {
+			return nil, errors.New("date range too large")
+		}
+		periods = append(periods, t)
+	}
+	end := periods[len(periods)-1].AddDate(0, 0, step)
+
+	var rows []struct {
+		PostcardID uint
+		Emotion    string
+		Score      float64
+		CreatedAt  time.Time
+	}
+	db := s.db.Table("postcard_emotions").
+		Select("postcard_emotions.postcard_id, postcard_emotions.emotion, postcard_emotions.score, postcards.created_at").
+		Joins("JOIN postcards ON postcards.id = postcard_emotions.postcard_id").
+		Where("postcard_emotions.user_id = ? AND postcards.deleted_at IS NULL", userID).
+		Where("postcards.created_at >= ? AND postcards.created_at < ?", from, end)
+	if query.CharacterID != 0 {
+		db = db.Where("postcards.character_id = ?", query.CharacterID)
+	}
+	if err := db.Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to get emotions: %w", err)
+	}
+
+	points := make([]models.MoodTrendPoint, len(periods))
+	index := make(map[string]int, len(periods))
+	for i, t := range periods {
+		key := t.Format(timelineDateLayout)
+		index[key] = i
+		points[i] = models.MoodTrendPoint{
+			Period:   key,
+			Emotions: make(map[string]float64, len(models.Emotions)),
+		}
+		for _, emotion := range models.Emotions {
+			points[i].Emotions[emotion] = 0
+		}
+	}
+
+	valence := make([]float64, len(points))
+	counted := make(map[uint]bool)
+	for _, row := range rows {
+		day := periodStart(row.CreatedAt.In(loc), "day")
+		if query.Period == "week" {
+			day = weekStart(day)
+		}
+		i, ok := index[day.Format(timelineDateLayout)]
+		if !ok {
+			continue
+		}
+		points[i].Emotions[row.Emotion] += row.Score
+		valence[i] += row.Score * emotionValence[row.Emotion]
+		if !counted[row.PostcardID] {
+			counted[row.PostcardID] = true
+			points[i].Postcards++
+		}
+	}
+
+	for i := range points {
+		point := &points[i]
+		if point.Postcards == 0 {
+			continue
+		}
+		best := 0.0
+		for _, emotion := range models.Emotions {
+			score := math.Round(point.Emotions[emotion]*100) / 100
+			point.Emotions[emotion] = score
+			if score > best {
+				best = score
+				point.Dominant = emotion
+			}
+		}
+		point.Valence = math.Round(valence[i]/float64(point.Postcards)*100) / 100
+	}
+
+	return &models.MoodTrendResponse{
+		Period:   query.Period,
+		From:     from.Format(timelineDateLayout),
+		To:       periods[len(periods)-1].Format(timelineDateLayout),
+		TimeZone: loc.String(),
+		Points:   points,
+	}, nil
+}
+
+// weekStart 日期所在周的周一
+func weekStart(day time.Time) time.Time {
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
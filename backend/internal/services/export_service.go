@@ -0,0 +1,453 @@
+package services
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"memory-postcard-backend/internal/models"
+	"net/http"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// exportBatchSize 导出时每次从数据库读取的明信片数量，EPUB 每批写成一个章节
+const exportBatchSize = 100
+
+// epubImageTypes EPUB 核心媒体类型中的图片格式及扩展名
+var epubImageTypes = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+	"image/webp": "webp",
+}
+
+type ExportService struct {
+	db                  *gorm.DB
+	upload              *UploadService
+	conversationService *ConversationService
+}
+
+func NewExportService(db *gorm.DB, uploadService *UploadService, conversationService *ConversationService) *ExportService {
+	return &ExportService{
+		db:                  db,
+		upload:              uploadService,
+		conversationService: conversationService,
+	}
+}
+
+// ConversationExport 一次导出所需的对话信息，明信片在写出时分批读取
+type ConversationExport struct {
+	Conversation *models.Conversation
+	Character    models.Character
+	UserName     string
+	ExportedAt   time.Time
+}
+
+// Title 导出文件的标题
+func (e *ConversationExport) Title() string {
+	if e.Conversation.Title != "" {
+		return e.Conversation.Title
+	}
+	return fmt.Sprintf("我与%s的明信片", e.Character.Name)
+}
+
+// sender 明信片的寄件人名称
+func (e *ConversationExport) sender(postcard *models.Postcard) string {
+	if postcard.Type == "ai" {
+		return e.Character.Name
+	}
+	return e.UserName
+}
+
+// PrepareExport 校验对话归属并加载导出所需的元数据，在开始写出响应前调用
+func (s *ExportService) PrepareExport(conversationID string, userID uint) (*ConversationExport, error) {
+	conversation, err := s.conversationService.getOwnConversation(conversationID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &ConversationExport{
+		Conversation: conversation,
+		ExportedAt:   time.Now(),
+	}
+
+	// 角色被删除后仍导出其资料
+	if err := s.db.Unscoped().First(&export.Character, conversation.CharacterID).Error; err != nil {
+		return nil, fmt.Errorf("failed to get character: %w", err)
+	}
+
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	export.UserName = user.Nickname
+	if export.UserName == "" {
+		export.UserName = user.Username
+	}
+
+	return export, nil
+}
+
+// eachBatch 按 ID 顺序分批读取对话中选定的明信片，内存占用与对话长度无关
+func (s *ExportService) eachBatch(export *ConversationExport, fn func(batch []models.Postcard) error) error {
+	var lastID uint
+	for {
+		var batch []models.Postcard
+		if err := s.db.Where("conversation_id = ? AND user_id = ? AND is_canonical = ? AND id > ?",
+			export.Conversation.ID, export.Conversation.UserID, true, lastID).
+			Order("id ASC").
+			Limit(exportBatchSize).
+			Find(&batch).Error; err != nil {
+			return fmt.Errorf("failed to get postcards: %w", err)
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := fn(batch); err != nil {
+			return err
+		}
+		if len(batch) < exportBatchSize {
+			return nil
+		}
+		lastID = batch[len(batch)-1].ID
+	}
+}
+
+// flush 每批写完后把已生成的内容推送给客户端
+func flush(w io.Writer) {
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// WriteMarkdown 导出为 Markdown，图片和语音以链接形式引用
+func (s *ExportService) WriteMarkdown(w io.Writer, export *ConversationExport) error {
+	character := export.Character
+	var header strings.Builder
+	fmt.Fprintf(&header, "# %s\n\n", export.Title())
+	fmt.Fprintf(&header, "- 角色：%s\n", character.Name)
+	if character.AvatarURL != "" {
+		fmt.Fprintf(&header, "- 头像：![%s](%s)\n", character.Name, character.AvatarURL)
+	}
+	if len(character.Tags) > 0 {
+		fmt.Fprintf(&header, "- 标签：%s\n", strings.Join(character.Tags, "、"))
+	}
+	fmt.Fprintf(&header, "- 对话 ID：%s\n", export.Conversation.ID)
+	fmt.Fprintf(&header, "- 导出时间：%s\n\n", export.ExportedAt.Format("2006-01-02 15:04:05"))
+	if character.Description != "" {
+		fmt.Fprintf(&header, "> %s\n\n", strings.ReplaceAll(strings.TrimSpace(character.Description), "\n", "\n> "))
+	}
+	header.WriteString("---\n\n")
+	if _, err := io.WriteString(w, header.String()); err != nil {
+		return err
+	}
+
+	return s.eachBatch(export, func(batch []models.Postcard) error {
+		var b strings.Builder
+		for i := range batch {
+			postcard := &batch[i]
+			fmt.Fprintf(&b, "### %s · %s\n\n", export.sender(postcard), postcard.CreatedAt.Format("2006-01-02 15:04"))
+			b.WriteString(strings.TrimSpace(postcard.Content))
+			b.WriteString("\n\n")
+			for _, url := range []string{postcard.ImageURL, postcard.AIGeneratedImageURL} {
+				if url != "" {
+					fmt.Fprintf(&b, "![图片](%s)\n\n", url)
+				}
+			}
+			if postcard.VoiceURL != "" {
+				fmt.Fprintf(&b, "[语音](%s)\n\n", postcard.VoiceURL)
+			}
+		}
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return err
+		}
+		flush(w)
+		return nil
+	})
+}
+
+// WriteJSON 导出为 JSON，明信片数组逐条写出
+func (s *ExportService) WriteJSON(w io.Writer, export *ConversationExport) error {
+	conversation, err := json.Marshal(models.ExportedConversation{
+		ID:       export.Conversation.ID,
+		Title:    export.Title(),
+		UserName: export.UserName,
+		Character: models.ExportedCharacter{
+			ID:          export.Character.ID,
+			Name:        export.Character.Name,
+			Description: export.Character.Description,
+			AvatarURL:   export.Character.AvatarURL,
+			Tags:        export.Character.Tags,
+		},
+		CreatedAt:  export.Conversation.CreatedAt,
+		ExportedAt: export.ExportedAt,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, `{"conversation":%s,"postcards":[`, conversation); err != nil {
+		return err
+	}
+
+	first := true
+	err = s.eachBatch(export, func(batch []models.Postcard) error {
+		for i := range batch {
+			postcard := &batch[i]
+			data, err := json.Marshal(models.ExportedPostcard{
+				ID:                  postcard.ID,
+				Type:                postcard.Type,
+				Sender:              export.sender(postcard),
+				Content:             postcard.Content,
+				ImageURL:            postcard.ImageURL,
+				AIGeneratedImageURL: postcard.AIGeneratedImageURL,
+				VoiceURL:            postcard.VoiceURL,
+				PostcardTemplate:    postcard.PostcardTemplate,
+				ReplyToID:           postcard.ReplyToID,
+				CreatedAt:           postcard.CreatedAt,
+				EditedAt:            postcard.EditedAt,
+			})
+			if err != nil {
+				return err
+			}
+			if !first {
+				if _, err := io.WriteString(w, ","); err != nil {
+					return err
+				}
+			}
+			first = false
+			if _, err := w.Write(data); err != nil {
+				return err
+			}
+		}
+		flush(w)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "]}\n")
+	return err
+}
+
+// epubItem content.opf 清单中的一项
+type epubItem struct {
+	ID, Href, MediaType, Properties string
+}
+
+// epubChapter 目录中的一个章节
+type epubChapter struct {
+	Href, Title string
+}
+
+// WriteEPUB 导出为 EPUB 3。每批明信片写成一个章节，本存储桶内的图片直接嵌入，
+// 其他图片和语音以链接形式保留；清单和目录在最后写出
+func (s *ExportService) WriteEPUB(w io.Writer, export *ConversationExport) error {
+	zw := zip.NewWriter(w)
+
+	// mimetype 必须是第一个且不压缩的文件
+	mimetype, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(mimetype, "application/epub+zip"); err != nil {
+		return err
+	}
+	if err := writeZipFile(zw, "META-INF/container.xml", epubContainerXML); err != nil {
+		return err
+	}
+	if err := writeZipFile(zw, "OEBPS/style.css", epubStyleCSS); err != nil {
+		return err
+	}
+
+	items := []epubItem{
+		{ID: "nav", Href: "nav.xhtml", MediaType: "application/xhtml+xml", Properties: "nav"},
+		{ID: "style", Href: "style.css", MediaType: "text/css"},
+		{ID: "intro", Href: "intro.xhtml", MediaType: "application/xhtml+xml"},
+	}
+	chapters := []epubChapter{{Href: "intro.xhtml", Title: "角色介绍"}}
+
+	if err := writeZipFile(zw, "OEBPS/intro.xhtml", s.epubIntro(export)); err != nil {
+		return err
+	}
+
+	err = s.eachBatch(export, func(batch []models.Postcard) error {
+		n := len(chapters)
+		href := fmt.Sprintf("chapter-%04d.xhtml", n)
+		title := fmt.Sprintf("%s — %s", batch[0].CreatedAt.Format("2006.01.02"), batch[len(batch)-1].CreatedAt.Format("2006.01.02"))
+
+		var body strings.Builder
+		for i := range batch {
+			postcard := &batch[i]
+			fmt.Fprintf(&body, "<section class=\"postcard %s\">\n<h3>%s <small>%s</small></h3>\n",
+				html.EscapeString(postcard.Type), html.EscapeString(export.sender(postcard)), postcard.CreatedAt.Format("2006-01-02 15:04"))
+			for _, line := range strings.Split(strings.TrimSpace(postcard.Content), "\n") {
+				fmt.Fprintf(&body, "<p>%s</p>\n", html.EscapeString(line))
+			}
+			for j, url := range []string{postcard.ImageURL, postcard.AIGeneratedImageURL} {
+				if url == "" {
+					continue
+				}
+				if item, ok := s.embedEPUBImage(zw, url, fmt.Sprintf("img-%d-%d", postcard.ID, j)); ok {
+					items = append(items, item)
+					fmt.Fprintf(&body, "<p><img src=\"%s\" alt=\"图片\"/></p>\n", item.Href)
+				} else {
+					fmt.Fprintf(&body, "<p><a href=\"%s\">查看图片</a></p>\n", html.EscapeString(url))
+				}
+			}
+			if postcard.VoiceURL != "" {
+				fmt.Fprintf(&body, "<p><a href=\"%s\">收听语音</a></p>\n", html.EscapeString(postcard.VoiceURL))
+			}
+			body.WriteString("</section>\n")
+		}
+
+		if err := writeZipFile(zw, "OEBPS/"+href, epubXHTML(title, body.String())); err != nil {
+			return err
+		}
+		items = append(items, epubItem{ID: fmt.Sprintf("chapter-%04d", n), Href: href, MediaType: "application/xhtml+xml"})
+		chapters = append(chapters, epubChapter{Href: href, Title: title})
+		if err := zw.Flush(); err != nil {
+			return err
+		}
+		flush(w)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := writeZipFile(zw, "OEBPS/nav.xhtml", epubNav(export.Title(), chapters)); err != nil {
+		return err
+	}
+	if err := writeZipFile(zw, "OEBPS/content.opf", epubPackage(export, items)); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// embedEPUBImage 将本存储桶中的图片写入 EPUB，外部图片或读取失败时返回 false
+func (s *ExportService) embedEPUBImage(zw *zip.Writer, url, id string) (epubItem, bool) {
+	objectName, ok := s.upload.ObjectNameFromURL(url)
+	if !ok {
+		return epubItem{}, false
+	}
+	data, err := s.upload.ReadObject(objectName, renderImageMaxSize)
+	if err != nil {
+		log.Printf("Failed to embed image %s in epub: %v", url, err)
+		return epubItem{}, false
+	}
+	mediaType := http.DetectContentType(data)
+	ext, ok := epubImageTypes[mediaType]
+	if !ok {
+		return epubItem{}, false
+	}
+
+	item := epubItem{ID: id, Href: fmt.Sprintf("images/%s.%s", id, ext), MediaType: mediaType}
+	f, err := zw.Create("OEBPS/" + item.Href)
+	if err != nil {
+		return epubItem{}, false
+	}
+	if _, err := f.Write(data); err != nil {
+		return epubItem{}, false
+	}
+	return item, true
+}
+
+func (s *ExportService) epubIntro(export *ConversationExport) string {
+	character := export.Character
+	var body strings.Builder
+	fmt.Fprintf(&body, "<h1>%s</h1>\n<h2>%s</h2>\n", html.EscapeString(export.Title()), html.EscapeString(character.Name))
+	if character.AvatarURL != "" {
+		fmt.Fprintf(&body, "<p><a href=\"%s\">角色头像</a></p>\n", html.EscapeString(character.AvatarURL))
+	}
+	for _, line := range strings.Split(strings.TrimSpace(character.Description), "\n") {
+		fmt.Fprintf(&body, "<p>%s</p>\n", html.EscapeString(line))
+	}
+	if len(character.Tags) > 0 {
+		fmt.Fprintf(&body, "<p>标签：%s</p>\n", html.EscapeString(strings.Join(character.Tags, "、")))
+	}
+	fmt.Fprintf(&body, "<p class=\"meta\">导出时间：%s</p>\n", export.ExportedAt.Format("2006-01-02 15:04:05"))
+	return epubXHTML("角色介绍", body.String())
+}
+
+func writeZipFile(zw *zip.Writer, name, content string) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(f, content)
+	return err
+}
+
+const epubContainerXML = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+const epubStyleCSS = `body { font-family: serif; line-height: 1.6; }
+section.postcard { margin: 1.5em 0; padding: 0.8em 1em; border-left: 3px solid #b03a2e; }
+section.postcard.ai { border-left-color: #785e3c; }
+h3 small { color: #888; font-weight: normal; }
+img { max-width: 100%; }
+.meta { color: #888; }
+`
+
+func epubXHTML(title, body string) string {
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" xml:lang="zh-CN" lang="zh-CN">
+<head><meta charset="UTF-8"/><title>%s</title><link rel="stylesheet" type="text/css" href="style.css"/></head>
+<body>
+%s</body>
+</html>
+`, html.EscapeString(title), body)
+}
+
+func epubNav(title string, chapters []epubChapter) string {
+	var body strings.Builder
+	fmt.Fprintf(&body, "<nav epub:type=\"toc\" id=\"toc\">\n<h1>%s</h1>\n<ol>\n", html.EscapeString(title))
+	for _, chapter := range chapters {
+		fmt.Fprintf(&body, "<li><a href=\"%s\">%s</a></li>\n", chapter.Href, html.EscapeString(chapter.Title))
+	}
+	body.WriteString("</ol>\n</nav>\n")
+	return epubXHTML(title, body.String())
+}
+
+func epubPackage(export *ConversationExport, items []epubItem) string {
+	var manifest, spine strings.Builder
+	for _, item := range items {
+		properties := ""
+		if item.Properties != "" {
+			properties = fmt.Sprintf(` properties="%s"`, item.Properties)
+		}
+		fmt.Fprintf(&manifest, "    <item id=\"%s\" href=\"%s\" media-type=\"%s\"%s/>\n", item.ID, item.Href, item.MediaType, properties)
+		if item.MediaType == "application/xhtml+xml" && item.ID != "nav" {
+			fmt.Fprintf(&spine, "    <itemref idref=\"%s\"/>\n", item.ID)
+		}
+	}
+
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id" xml:lang="zh-CN">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="book-id">urn:uuid:%s</dc:identifier>
+    <dc:title>%s</dc:title>
+    <dc:creator>%s</dc:creator>
+    <dc:language>zh-CN</dc:language>
+    <meta property="dcterms:modified">%s</meta>
+  </metadata>
+  <manifest>
+%s  </manifest>
+  <spine>
+%s  </spine>
+</package>
+`, html.EscapeString(export.Conversation.ID), html.EscapeString(export.Title()), html.EscapeString(export.UserName),
+		export.ExportedAt.UTC().Format("2006-01-02T15:04:05Z"), manifest.String(), spine.String())
+}
@@ -0,0 +1,277 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"memory-postcard-backend/internal/models"
+	"memory-postcard-backend/internal/utils"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// lorebookScanDepth 匹配关键词时扫描的最近明信片数量（不含当前消息）
+const lorebookScanDepth = 4
+
+// defaultLorebookTokenBudget 创建角色时未指定的世界设定 token 上限
+const defaultLorebookTokenBudget = 500
+
+type LorebookService struct {
+	db *gorm.DB
+}
+
+func NewLorebookService(db *gorm.DB) *LorebookService {
+	return &LorebookService{
+		db: db,
+	}
+}
+
+// ListEntries 获取角色的世界设定条目（仅角色创建者）
+func (s *LorebookService) ListEntries(characterID, userID uint) ([]models.LorebookEntry, error) {
+	if _, err := s.getOwnCharacter(characterID, userID); err != nil {
+		return nil, err
+	}
+
+	var entries []models.LorebookEntry
+	if err := s.db.Where("character_id = ?", characterID).
+		Order("priority DESC, id ASC").
+		Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to get lorebook entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// CreateEntry 创建世界设定条目
+func (s *LorebookService) CreateEntry(characterID, userID uint, req *models.LorebookEntryCreateRequest) (*models.LorebookEntry, error) {
+	if _, err := s.getOwnCharacter(characterID, userID); err != nil {
+		return nil, err
+	}
+
+	entry := models.LorebookEntry{
+		CharacterID: characterID,
+		Title:       req.Title,
+		Keywords:    normalizeKeywords(req.Keywords),
+		Content:     req.Content,
+		Priority:    req.Priority,
+		Enabled:     true,
+	}
+	if req.Enabled != nil {
+		entry.Enabled = *req.Enabled
+	}
+
+	// Enabled 为 false 时 GORM 会使用数据库默认值，需要显式写入
+	if err := s.db.Select("*").Omit("id").Create(&entry).Error; err != nil {
+		return nil, fmt.Errorf("failed to create lorebook entry: %w", err)
+	}
+
+	return &entry, nil
+}
+
+// UpdateEntry 更新世界设定条目
+func (s *LorebookService) UpdateEntry(characterID, entryID, userID uint, req *models.LorebookEntryUpdateRequest) (*models.LorebookEntry, error) {
+	entry, err := s.getOwnEntry(characterID, entryID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 更新字段
+	if req.Title != "" {
+		entry.Title = req.Title
+	}
+	if req.Keywords != nil {
+		entry.Keywords = normalizeKeywords(req.Keywords)
+	}
+	if req.Content != "" {
+		entry.Content = req.Content
+	}
+	if req.Priority != nil {
+		entry.Priority = *req.Priority
+	}
+	if req.Enabled != nil {
+		entry.Enabled = *req.Enabled
+	}
+
+	if err := s.db.Save(entry).Error; err != nil {
+		return nil, fmt.Errorf("failed to update lorebook entry: %w", err)
+	}
+
+	return entry, nil
+}
+
+// DeleteEntry 删除世界设定条目
+func (s *LorebookService) DeleteEntry(characterID, entryID, userID uint) error {
+	entry, err := s.getOwnEntry(characterID, entryID, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.Delete(entry).Error; err != nil {
+		return fmt.Errorf("failed to delete lorebook entry: %w", err)
+	}
+
+	return nil
+}
+
+// DryRun 试运行：返回给定消息会触发的条目，以及在 token 预算内实际会注入的条目
+func (s *LorebookService) DryRun(characterID, userID uint, req *models.LorebookDryRunRequest) (*models.LorebookDryRunResponse, error) {
+	character, err := s.getOwnCharacter(characterID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var history []models.Postcard
+	if req.ConversationID != "" {
+		// 只能使用自己与该角色的对话
+		var count int64
+		if err := s.db.Model(&models.Postcard{}).
+			Where("conversation_id = ? AND character_id = ? AND user_id = ?", req.ConversationID, characterID, userID).
+			Count(&count).Error; err != nil {
+			return nil, fmt.Errorf("failed to get conversation: %w", err)
+		}
+		if count == 0 {
+			return nil, errors.New("conversation not found")
+		}
+
+		// 与生成回复时相同：使用选定的明信片，match 只扫描其中最近的几张
+		history, err = canonicalHistory(s.db, req.ConversationID, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	matches, used, err := s.match(character, history, req.Message)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.LorebookDryRunResponse{
+		Matches:     matches,
+		TokenBudget: character.LorebookTokenBudget,
+		UsedTokens:  used,
+	}, nil
+}
+
+// SelectEntries 根据最近的对话内容选出需要注入 AI 上下文的条目
+func (s *LorebookService) SelectEntries(character *models.Character, history []models.Postcard, userMessage string) ([]models.LorebookEntry, error) {
+	matches, _, err := s.match(character, history, userMessage)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []models.LorebookEntry
+	for _, m := range matches {
+		if m.Injected {
+			entries = append(entries, m.Entry)
+		}
+	}
+
+	return entries, nil
+}
+
+// match 在最近的明信片和当前消息中匹配关键词
+// 命中的条目按优先级从高到低依次放入 token 预算，放不下的条目跳过，继续尝试后面的条目
+func (s *LorebookService) match(character *models.Character, history []models.Postcard, userMessage string) ([]models.LorebookMatch, int, error) {
+	var entries []models.LorebookEntry
+	if err := s.db.Where("character_id = ? AND enabled = ?", character.ID, true).
+		Order("priority DESC, id ASC").
+		Find(&entries).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get lorebook entries: %w", err)
+	}
+	if len(entries) == 0 {
+		return []models.LorebookMatch{}, 0, nil
+	}
+
+	start := 0
+	if len(history) > lorebookScanDepth {
+		start = len(history) - lorebookScanDepth
+	}
+	texts := make([]string, 0, len(history)-start+1)
+	for _, postcard := range history[start:] {
+		texts = append(texts, postcard.Content)
+	}
+	texts = append(texts, userMessage)
+	scanText := strings.ToLower(strings.Join(texts, "\n"))
+
+	matches := []models.LorebookMatch{}
+	for _, entry := range entries {
+		var matched []string
+		for _, keyword := range entry.Keywords {
+			if keyword != "" && strings.Contains(scanText, strings.ToLower(keyword)) {
+				matched = append(matched, keyword)
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+		matches = append(matches, models.LorebookMatch{
+			Entry:           entry,
+			MatchedKeywords: matched,
+			Tokens:          utils.EstimateTokens(entry.Content),
+		})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Entry.Priority > matches[j].Entry.Priority
+	})
+
+	used := 0
+	for i := range matches {
+		if used+matches[i].Tokens <= character.LorebookTokenBudget {
+			matches[i].Injected = true
+			used += matches[i].Tokens
+		}
+	}
+
+	return matches, used, nil
+}
+
+func (s *LorebookService) getOwnCharacter(characterID, userID uint) (*models.Character, error) {
+	var character models.Character
+	if err := s.db.First(&character, characterID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("character not found")
+		}
+		return nil, fmt.Errorf("failed to get character: %w", err)
+	}
+
+	// 世界设定属于角色的隐藏设定，仅创建者可见
+	if character.CreatorID != userID {
+		return nil, errors.New("permission denied")
+	}
+
+	return &character, nil
+}
+
+func (s *LorebookService) getOwnEntry(characterID, entryID, userID uint) (*models.LorebookEntry, error) {
+	if _, err := s.getOwnCharacter(characterID, userID); err != nil {
+		return nil, err
+	}
+
+	var entry models.LorebookEntry
+	if err := s.db.Where("id = ? AND character_id = ?", entryID, characterID).First(&entry).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("lorebook entry not found")
+		}
+		return nil, fmt.Errorf("failed to get lorebook entry: %w", err)
+	}
+
+	return &entry, nil
+}
+
+// normalizeKeywords 去除关键词首尾空白并去重
+func normalizeKeywords(keywords []string) []string {
+	seen := make(map[string]bool, len(keywords))
+	result := make([]string, 0, len(keywords))
+	for _, keyword := range keywords {
+		keyword = strings.TrimSpace(keyword)
+		key := strings.ToLower(keyword)
+		if keyword == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, keyword)
+	}
+	return result
+}
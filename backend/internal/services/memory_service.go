@@ -0,0 +1,292 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"memory-postcard-backend/config"
+	"memory-postcard-backend/internal/models"
+	"memory-postcard-backend/internal/utils"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	memoryMinScore         = 0.2  // 相似度低于该值的明信片不会被回忆
+	memoryEmbedBatchSize   = 64   // 补建向量时每批处理的明信片数量
+	maxLoadedMemoryIndexes = 1000 // 内存中最多保留的索引数量
+)
+
+type memoryIndexKey struct {
+	userID      uint
+	characterID uint
+}
+
+// MemoryService 基于向量检索的记忆服务：为用户写的明信片建立向量，生成回复时回忆相关的过往明信片
+// 向量持久化在 MySQL 中，每个用户与角色的组合在内存中维护一个独立的索引，首次使用时加载
+type MemoryService struct {
+	db       *gorm.DB
+	provider EmbeddingProvider
+	topK     int
+
+	mu      sync.Mutex
+	indexes map[memoryIndexKey]utils.VectorIndex
+}
+
+func NewMemoryService(db *gorm.DB, provider EmbeddingProvider, cfg *config.Config) *MemoryService {
+	return &MemoryService{
+		db:       db,
+		provider: provider,
+		topK:     cfg.MemoryRecallTopK,
+		indexes:  make(map[memoryIndexKey]utils.VectorIndex),
+	}
+}
+
+// IndexPostcard 为用户写的明信片生成向量，并加入已加载的索引
+func (s *MemoryService) IndexPostcard(postcard *models.Postcard) error {
+	// 分支对话复制的明信片与原明信片内容相同，只索引原明信片
+	if postcard.Type != "user" || postcard.CopiedFromID != nil || strings.TrimSpace(postcard.Content) == "" {
+		return nil
+	}
+
+	vectors, err := s.provider.Embed([]string{postcard.Content})
+	if err != nil {
+		return fmt.Errorf("failed to embed postcard: %w", err)
+	}
+	if err := s.saveEmbedding(postcard, vectors[0]); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	index, ok := s.indexes[memoryIndexKey{postcard.UserID, postcard.CharacterID}]
+	s.mu.Unlock()
+	if ok {
+		index.Add(postcard.ID, vectors[0])
+	}
+
+	return nil
+}
+
+// RemovePostcard 删除明信片的向量
+func (s *MemoryService) RemovePostcard(postcardID uint) error {
+	var embedding models.PostcardEmbedding
+	if err := s.db.Where("postcard_id = ?", postcardID).First(&embedding).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to get embedding: %w", err)
+	}
+
+	if err := s.db.Delete(&embedding).Error; err != nil {
+		return fmt.Errorf("failed to delete embedding: %w", err)
+	}
+
+	s.mu.Lock()
+	index, ok := s.indexes[memoryIndexKey{embedding.UserID, embedding.CharacterID}]
+	s.mu.Unlock()
+	if ok {
+		index.Remove(postcardID)
+	}
+
+	return nil
+}
+
+// Recall 回忆与查询内容最相关的过往明信片，excludeIDs 中的明信片（如已在上下文中的近期对话）不会返回
+func (s *MemoryService) Recall(userID, characterID uint, query string, excludeIDs []uint) ([]models.RecalledMemory, error) {
+	if s.topK <= 0 || strings.TrimSpace(query) == "" {
+		return nil, nil
+	}
+
+	index, err := s.getIndex(userID, characterID)
+	if err != nil {
+		return nil, err
+	}
+	if index.Len() == 0 {
+		return nil, nil
+	}
+
+	vectors, err := s.provider.Embed([]string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+
+	excluded := make(map[uint]bool, len(excludeIDs))
+	for _, id := range excludeIDs {
+		excluded[id] = true
+	}
+
+	var recalls []models.MemoryRecall
+	for _, hit := range index.Search(vectors[0], s.topK+len(excludeIDs)) {
+		if excluded[hit.ID] || hit.Score < memoryMinScore {
+			continue
+		}
+		recalls = append(recalls, models.MemoryRecall{PostcardID: hit.ID, Score: hit.Score})
+		if len(recalls) == s.topK {
+			break
+		}
+	}
+
+	return s.loadRecalledMemories(userID, recalls)
+}
+
+// GetRecalledMemories 获取 AI 明信片生成时回忆起的明信片
+func (s *MemoryService) GetRecalledMemories(postcardID, userID uint) ([]models.RecalledMemory, error) {
+	var postcard models.Postcard
+	if err := s.db.Where("id = ? AND user_id = ?", postcardID, userID).First(&postcard).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("postcard not found")
+		}
+		return nil, fmt.Errorf("failed to get postcard: %w", err)
+	}
+
+	memories, err := s.loadRecalledMemories(userID, postcard.RecalledMemories)
+	if err != nil {
+		return nil, err
+	}
+	if memories == nil {
+		memories = []models.RecalledMemory{}
+	}
+
+	return memories, nil
+}
+
+// loadRecalledMemories 按回忆顺序加载明信片，已删除的明信片会被跳过
+func (s *MemoryService) loadRecalledMemories(userID uint, recalls []models.MemoryRecall) ([]models.RecalledMemory, error) {
+	if len(recalls) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uint, len(recalls))
+	for i, recall := range recalls {
+		ids[i] = recall.PostcardID
+	}
+
+	var postcards []models.Postcard
+	if err := s.db.Where("id IN ? AND user_id = ?", ids, userID).Find(&postcards).Error; err != nil {
+		return nil, fmt.Errorf("failed to get recalled postcards: %w", err)
+	}
+
+	postcardMap := make(map[uint]models.Postcard, len(postcards))
+	for _, postcard := range postcards {
+		postcardMap[postcard.ID] = postcard
+	}
+
+	var memories []models.RecalledMemory
+	for _, recall := range recalls {
+		if postcard, ok := postcardMap[recall.PostcardID]; ok {
+			memories = append(memories, models.RecalledMemory{Postcard: postcard, Score: recall.Score})
+		}
+	}
+
+	return memories, nil
+}
+
+// getIndex 获取用户与角色的索引，未加载时从数据库加载
+func (s *MemoryService) getIndex(userID, characterID uint) (utils.VectorIndex, error) {
+	key := memoryIndexKey{userID, characterID}
+
+	s.mu.Lock()
+	index, ok := s.indexes[key]
+	s.mu.Unlock()
+	if ok {
+		return index, nil
+	}
+
+	// 加载过程可能需要补建向量，不持有锁
+	loaded, err := s.loadIndex(userID, characterID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if index, ok := s.indexes[key]; ok {
+		return index, nil
+	}
+	if len(s.indexes) >= maxLoadedMemoryIndexes {
+		// 随机淘汰一个索引，下次使用时重新加载
+		for k := range s.indexes {
+			delete(s.indexes, k)
+			break
+		}
+	}
+	s.indexes[key] = loaded
+
+	return loaded, nil
+}
+
+// loadIndex 从数据库加载向量，并为尚未建立向量的明信片补建向量
+func (s *MemoryService) loadIndex(userID, characterID uint) (utils.VectorIndex, error) {
+	index := utils.NewBruteForceIndex()
+	model := s.provider.Name()
+
+	var embeddings []models.PostcardEmbedding
+	if err := s.db.Where("user_id = ? AND character_id = ? AND model = ?", userID, characterID, model).
+		Find(&embeddings).Error; err != nil {
+		return nil, fmt.Errorf("failed to get embeddings: %w", err)
+	}
+	for _, embedding := range embeddings {
+		vector, err := utils.DecodeVector(embedding.Vector)
+		if err != nil {
+			log.Printf("Skipping invalid embedding for postcard %d: %v", embedding.PostcardID, err)
+			continue
+		}
+		index.Add(embedding.PostcardID, vector)
+	}
+
+	// 补建向量：历史明信片，或更换向量模型后需要重新生成的明信片
+	var missing []models.Postcard
+	if err := s.db.Where("user_id = ? AND character_id = ? AND type = ? AND copied_from_id IS NULL AND TRIM(content) <> ?", userID, characterID, "user", "").
+		Where("id NOT IN (?)", s.db.Model(&models.PostcardEmbedding{}).Select("postcard_id").Where("model = ?", model)).
+		Find(&missing).Error; err != nil {
+		return nil, fmt.Errorf("failed to get postcards without embeddings: %w", err)
+	}
+
+	for start := 0; start < len(missing); start += memoryEmbedBatchSize {
+		end := start + memoryEmbedBatchSize
+		if end > len(missing) {
+			end = len(missing)
+		}
+		batch := missing[start:end]
+
+		texts := make([]string, len(batch))
+		for i, postcard := range batch {
+			texts[i] = postcard.Content
+		}
+		vectors, err := s.provider.Embed(texts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed postcards: %w", err)
+		}
+		for i := range batch {
+			if err := s.saveEmbedding(&batch[i], vectors[i]); err != nil {
+				return nil, err
+			}
+			index.Add(batch[i].ID, vectors[i])
+		}
+	}
+
+	return index, nil
+}
+
+func (s *MemoryService) saveEmbedding(postcard *models.Postcard, vector []float32) error {
+	embedding := models.PostcardEmbedding{
+		PostcardID:  postcard.ID,
+		UserID:      postcard.UserID,
+		CharacterID: postcard.CharacterID,
+		Model:       s.provider.Name(),
+		Dimensions:  len(vector),
+		Vector:      utils.EncodeVector(vector),
+	}
+
+	if err := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "postcard_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"model", "dimensions", "vector", "updated_at"}),
+	}).Create(&embedding).Error; err != nil {
+		return fmt.Errorf("failed to save embedding: %w", err)
+	}
+
+	return nil
+}
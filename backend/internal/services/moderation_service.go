@@ -0,0 +1,320 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"memory-postcard-backend/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ModerationService struct {
+	db               *gorm.DB
+	characterService *CharacterService
+	uploadService    *UploadService
+	notifier         Notifier
+}
+
+func NewModerationService(db *gorm.DB, characterService *CharacterService, uploadService *UploadService, notifier Notifier) *ModerationService {
+	return &ModerationService{
+		db:               db,
+		characterService: characterService,
+		uploadService:    uploadService,
+		notifier:         notifier,
+	}
+}
+
+// CreateReport 举报角色、明信片、用户或媒体文件
+func (s *ModerationService) CreateReport(reporterID uint, req *models.ReportCreateRequest) (*models.Report, error) {
+	if err := s.checkTargetExists(req); err != nil {
+		return nil, err
+	}
+
+	// 同一用户对同一内容只能有一条未处理的举报
+	var count int64
+	db := s.db.Model(&models.Report{}).
+		Where("reporter_id = ? AND target_type = ? AND status IN ?", reporterID, req.TargetType, []string{"pending", "reviewing"})
+	if req.TargetType == "media" {
+		db = db.Where("target_url = ?", req.TargetURL)
+	} else {
+		db = db.Where("target_id = ?", req.TargetID)
+	}
+	if err := db.Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to check existing reports: %w", err)
+	}
+	if count > 0 {
+		return nil, errors.New("you have already reported this content")
+	}
+
+	report := models.Report{
+		ReporterID: reporterID,
+		TargetType: req.TargetType,
+		TargetID:   req.TargetID,
+		TargetURL:  req.TargetURL,
+		Reason:     req.Reason,
+		Detail:     req.Detail,
+		Status:     "pending",
+		Action:     "none",
+	}
+	if req.TargetType == "media" {
+		report.TargetID = 0
+	}
+
+	if err := s.db.Create(&report).Error; err != nil {
+		return nil, fmt.Errorf("failed to create report: %w", err)
+	}
+
+	return &report, nil
+}
+
+// ListMyReports 获取当前用户提交的举报及处理结果
+func (s *ModerationService) ListMyReports(reporterID uint, query *models.ReportListQuery) (*models.PaginatedResponse, error) {
+	db := s.db.Model(&models.Report{}).Where("reporter_id = ?", reporterID)
+	return s.listReports(db, query, "created_at DESC", false)
+}
+
+// ListReports 获取审核队列
+func (s *ModerationService) ListReports(query *models.ReportListQuery) (*models.PaginatedResponse, error) {
+	// 审核队列按先进先出处理
+	return s.listReports(s.db.Model(&models.Report{}), query, "created_at ASC", true)
+}
+
+// GetReport 获取举报详情
+func (s *ModerationService) GetReport(id uint) (*models.Report, error) {
+	var report models.Report
+	if err := s.db.Preload("Reporter").First(&report, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("report not found")
+		}
+		return nil, fmt.Errorf("failed to get report: %w", err)
+	}
+
+	return &report, nil
+}
+
+// ReviewReport 审核举报：更新状态，必要时隐藏被举报内容，并通知举报人处理结果
+// 处理结论会同步到针对同一内容的其他未处理举报
+func (s *ModerationService) ReviewReport(id uint, moderatorID uint, req *models.ReportReviewRequest) (*models.Report, error) {
+	report, err := s.GetReport(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if report.Status == "resolved" || report.Status == "rejected" {
+		return nil, errors.New("report already closed")
+	}
+
+	action := req.Action
+	if action == "" || req.Status != "resolved" {
+		action = "none"
+	}
+
+	if action == "hide" {
+		if err := s.hideTarget(report); err != nil {
+			return nil, err
+		}
+	}
+
+	// 需要更新的举报：当前举报，以及结案时针对同一内容的其他未处理举报
+	reports := []models.Report{*report}
+	if req.Status != "reviewing" {
+		var related []models.Report
+		db := s.db.Where("id <> ? AND target_type = ? AND status IN ?", report.ID, report.TargetType, []string{"pending", "reviewing"})
+		if report.TargetType == "media" {
+			db = db.Where("target_url = ?", report.TargetURL)
+		} else {
+			db = db.Where("target_id = ?", report.TargetID)
+		}
+		if err := db.Find(&related).Error; err != nil {
+			return nil, fmt.Errorf("failed to get related reports: %w", err)
+		}
+		reports = append(reports, related...)
+	}
+
+	now := time.Now()
+	for i := range reports {
+		reports[i].Status = req.Status
+		reports[i].Action = action
+		reports[i].ModeratorID = &moderatorID
+		reports[i].ModeratorNote = req.Note
+		if req.Status != "reviewing" {
+			reports[i].ResolvedAt = &now
+		}
+		if err := s.db.Model(&models.Report{}).Where("id = ?", reports[i].ID).Updates(map[string]interface{}{
+			"status":         reports[i].Status,
+			"action":         reports[i].Action,
+			"moderator_id":   reports[i].ModeratorID,
+			"moderator_note": reports[i].ModeratorNote,
+			"resolved_at":    reports[i].ResolvedAt,
+		}).Error; err != nil {
+			return nil, fmt.Errorf("failed to update report: %w", err)
+		}
+
+		if req.Status != "reviewing" {
+			s.notifyReporter(&reports[i])
+		}
+	}
+
+	return s.GetReport(id)
+}
+
+// checkTargetExists 检查被举报的内容是否存在
+func (s *ModerationService) checkTargetExists(req *models.ReportCreateRequest) error {
+	var model interface{}
+	switch req.TargetType {
+	case "character":
+		model = &models.Character{}
+	case "postcard":
+		model = &models.Postcard{}
+	case "user":
+		model = &models.User{}
+	case "media":
+		if _, ok := s.uploadService.ObjectNameFromURL(req.TargetURL); !ok {
+			return errors.New("media not found")
+		}
+		return nil
+	}
+
+	if err := s.db.Select("id").First(model, req.TargetID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%s not found", req.TargetType)
+		}
+		return fmt.Errorf("failed to get %s: %w", req.TargetType, err)
+	}
+
+	return nil
+}
+
+// hideTarget 隐藏被举报的内容
+// 角色：隐藏；用户：隐藏其创建的全部角色；明信片：软删除；媒体：解除引用并删除文件
+func (s *ModerationService) hideTarget(report *models.Report) error {
+	switch report.TargetType {
+	case "character":
+		if err := s.db.Model(&models.Character{}).Where("id = ? AND hidden_at IS NULL", report.TargetID).
+			UpdateColumn("hidden_at", time.Now()).Error; err != nil {
+			return fmt.Errorf("failed to hide character: %w", err)
+		}
+		s.characterService.clearCharacterCache(report.TargetID)
+
+	case "user":
+		var characterIDs []uint
+		if err := s.db.Model(&models.Character{}).Where("creator_id = ? AND hidden_at IS NULL", report.TargetID).
+			Pluck("id", &characterIDs).Error; err != nil {
+			return fmt.Errorf("failed to get user characters: %w", err)
+		}
+		if len(characterIDs) > 0 {
+			if err := s.db.Model(&models.Character{}).Where("id IN ?", characterIDs).
+				UpdateColumn("hidden_at", time.Now()).Error; err != nil {
+				return fmt.Errorf("failed to hide user characters: %w", err)
+			}
+			for _, id := range characterIDs {
+				s.characterService.clearCharacterCache(id)
+			}
+		}
+
+	case "postcard":
+		if err := s.db.Delete(&models.Postcard{}, report.TargetID).Error; err != nil {
+			return fmt.Errorf("failed to hide postcard: %w", err)
+		}
+
+	case "media":
+		if err := s.hideMedia(report.TargetURL); err != nil {
+			return err
+		}
+	}
+
+	s.characterService.clearCharacterListCache()
+	return nil
+}
+
+// hideMedia 解除所有对该媒体文件的引用，并从存储中删除文件
+func (s *ModerationService) hideMedia(url string) error {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("avatar_url = ?", url).UpdateColumn("avatar_url", "").Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Character{}).Where("avatar_url = ?", url).UpdateColumn("avatar_url", "").Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Character{}).Where("voice_url = ?", url).UpdateColumn("voice_url", "").Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Postcard{}).Where("image_url = ?", url).UpdateColumn("image_url", "").Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Postcard{}).Where("ai_generated_image_url = ?", url).UpdateColumn("ai_generated_image_url", "").Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Postcard{}).Where("voice_url = ?", url).UpdateColumn("voice_url", "").Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove media references: %w", err)
+	}
+
+	if objectName, ok := s.uploadService.ObjectNameFromURL(url); ok {
+		if err := s.uploadService.DeleteFile(objectName); err != nil {
+			log.Printf("Failed to delete reported media %s: %v", objectName, err)
+		}
+	}
+
+	return nil
+}
+
+// notifyReporter 通知举报人处理结果
+func (s *ModerationService) notifyReporter(report *models.Report) {
+	body := "感谢你的举报，经审核该内容未违反社区规范。"
+	if report.Status == "resolved" {
+		body = "感谢你的举报，我们已处理被举报的内容。"
+	}
+
+	s.notifier.Notify(NotificationEvent{
+		UserID: report.ReporterID,
+		Type:   NotificationReportResolved,
+		Title:  "举报处理结果",
+		Body:   body,
+		Payload: map[string]interface{}{
+			"report_id":   report.ID,
+			"target_type": report.TargetType,
+			"target_id":   report.TargetID,
+			"status":      report.Status,
+			"action":      report.Action,
+		},
+	})
+}
+
+func (s *ModerationService) listReports(db *gorm.DB, query *models.ReportListQuery, orderBy string, withReporter bool) (*models.PaginatedResponse, error) {
+	var reports []models.Report
+	var total int64
+
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+	if query.TargetType != "" {
+		db = db.Where("target_type = ?", query.TargetType)
+	}
+
+	// 计算总数
+	if err := db.Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count reports: %w", err)
+	}
+
+	if withReporter {
+		db = db.Preload("Reporter")
+	}
+
+	// 分页
+	offset := (query.Page - 1) * query.PageSize
+	if err := db.Order(orderBy).Offset(offset).Limit(query.PageSize).Find(&reports).Error; err != nil {
+		return nil, fmt.Errorf("failed to get reports: %w", err)
+	}
+
+	return &models.PaginatedResponse{
+		Items:      reports,
+		Total:      total,
+		Page:       query.Page,
+		PageSize:   query.PageSize,
+		TotalPages: int((total + int64(query.PageSize) - 1) / int64(query.PageSize)),
+	}, nil
+}
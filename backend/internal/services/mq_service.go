@@ -1,16 +1,18 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"memory-postcard-backend/config"
+	"memory-postcard-backend/internal/models"
 	"time"
 
 	"github.com/streadway/amqp"
 )
 
-// MQService 消息队列服务（只包含生产者）
+// MQService 消息队列服务：发布 AI 回复请求，并消费 AI 助手保存回复后发出的事件
 type MQService struct {
 	config *config.Config
 	conn   *amqp.Connection
@@ -19,10 +21,25 @@ type MQService struct {
 
 // AIReplyMessage AI 回复消息结构
 type AIReplyMessage struct {
+	ConversationID string                   `json:"conversation_id"`
+	UserID         uint                     `json:"user_id"`
+	CharacterID    uint                     `json:"character_id"`
+	UserPostcardID uint                     `json:"user_postcard_id"` // 被回复的用户明信片，保存 AI 回复时写入 reply_to_id 字段
+	UserMessage    string                   `json:"user_message"`
+	UserRoleName   string                   `json:"user_role_name,omitempty"` // 用户在对话中使用的人设
+	UserRoleDesc   string                   `json:"user_role_desc,omitempty"`
+	Examples       []models.ExampleDialogue `json:"examples,omitempty"` // 角色的示例对话，占位符已替换
+	Lore           []string                 `json:"lore,omitempty"`     // 由关键词触发的世界设定
+	Memories       []string                 `json:"memories,omitempty"` // 回忆起的过往明信片，格式为「（日期）内容」
+	// 回忆起的明信片 ID 及相似度，保存 AI 回复时写入 recalled_memories 字段
+	RecalledMemories []models.MemoryRecall `json:"recalled_memories,omitempty"`
+}
+
+// AIReplySavedMessage AI 助手保存回复后发布的事件
+type AIReplySavedMessage struct {
+	PostcardID     uint   `json:"postcard_id"` // 保存的 AI 回复
+	ReplyToID      uint   `json:"reply_to_id"` // 被回复的用户明信片
 	ConversationID string `json:"conversation_id"`
-	UserID         uint   `json:"user_id"`
-	CharacterID    uint   `json:"character_id"`
-	UserMessage    string `json:"user_message"`
 }
 
 // NewMQService 创建 MQ 服务
@@ -69,19 +86,21 @@ func (s *MQService) connect() error {
 
 // declareQueue 声明队列
 func (s *MQService) declareQueue() error {
-	_, err := s.ch.QueueDeclare(
-		"ai_reply_queue", // 队列名称
-		true,             // 持久化
-		false,            // 自动删除
-		false,            // 排他性
-		false,            // 不等待
-		nil,              // 参数
-	)
-	if err != nil {
-		return fmt.Errorf("failed to declare queue: %w", err)
+	for _, queue := range []string{"ai_reply_queue", "ai_reply_saved_queue"} {
+		_, err := s.ch.QueueDeclare(
+			queue, // 队列名称
+			true,  // 持久化
+			false, // 自动删除
+			false, // 排他性
+			false, // 不等待
+			nil,   // 参数
+		)
+		if err != nil {
+			return fmt.Errorf("failed to declare queue: %w", err)
+		}
+
+		log.Printf("Successfully declared queue: %s", queue)
 	}
-
-	log.Println("Successfully declared queue: ai_reply_queue")
 	return nil
 }
 
@@ -115,6 +134,60 @@ func (s *MQService) PublishAIReplyMessage(message *AIReplyMessage) error {
 	return nil
 }
 
+// ConsumeAIReplySaved 在独立的通道上消费 AI 回复保存事件，ctx 结束或连接断开时停止
+// handle 返回错误时消息不再重新投递，避免同一事件反复触发
+func (s *MQService) ConsumeAIReplySaved(ctx context.Context, handle func(*AIReplySavedMessage) error) error {
+	ch, err := s.conn.Channel()
+	if err != nil {
+		return fmt.Errorf("failed to open channel: %w", err)
+	}
+
+	deliveries, err := ch.Consume(
+		"ai_reply_saved_queue", // 队列名称
+		"",                     // 消费者标签
+		false,                  // 自动确认
+		false,                  // 排他性
+		false,                  // 不接收本连接发布的消息
+		false,                  // 不等待
+		nil,                    // 参数
+	)
+	if err != nil {
+		ch.Close()
+		return fmt.Errorf("failed to consume queue: %w", err)
+	}
+
+	go func() {
+		defer ch.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case delivery, ok := <-deliveries:
+				if !ok {
+					log.Println("AI reply saved consumer stopped: channel closed")
+					return
+				}
+
+				var message AIReplySavedMessage
+				if err := json.Unmarshal(delivery.Body, &message); err != nil {
+					log.Printf("Failed to unmarshal AI reply saved message: %v", err)
+					delivery.Nack(false, false)
+					continue
+				}
+				if err := handle(&message); err != nil {
+					log.Printf("Failed to handle AI reply saved message for postcard %d: %v", message.PostcardID, err)
+					delivery.Nack(false, false)
+					continue
+				}
+				delivery.Ack(false)
+			}
+		}
+	}()
+
+	log.Println("Started consuming queue: ai_reply_saved_queue")
+	return nil
+}
+
 // Close 关闭连接
 func (s *MQService) Close() {
 	if s.ch != nil {
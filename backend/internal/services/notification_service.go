@@ -0,0 +1,180 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"memory-postcard-backend/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NotificationService 站内通知收件箱，实现 Notifier 接口
+type NotificationService struct {
+	db *gorm.DB
+}
+
+func NewNotificationService(db *gorm.DB) *NotificationService {
+	return &NotificationService{
+		db: db,
+	}
+}
+
+// Notify 保存一条站内通知，用户关闭了该类通知时忽略
+func (s *NotificationService) Notify(event NotificationEvent) {
+	var preference models.NotificationPreference
+	err := s.db.Where("user_id = ? AND type = ?", event.UserID, event.Type).First(&preference).Error
+	if err == nil && !preference.Enabled {
+		return
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Printf("Failed to get notification preference for user %d: %v", event.UserID, err)
+	}
+
+	notification := models.Notification{
+		UserID:  event.UserID,
+		Type:    event.Type,
+		Title:   event.Title,
+		Body:    event.Body,
+		Payload: event.Payload,
+	}
+	if err := s.db.Create(&notification).Error; err != nil {
+		log.Printf("Failed to create notification: user_id=%d, type=%s: %v", event.UserID, event.Type, err)
+	}
+}
+
+// ListNotifications 获取用户的通知，从新到旧排列
+func (s *NotificationService) ListNotifications(userID uint, query *models.NotificationListQuery) (*models.NotificationListResponse, error) {
+	db := s.db.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if query.Type != "" {
+		db = db.Where("type = ?", query.Type)
+	}
+	if query.UnreadOnly {
+		db = db.Where("read_at IS NULL")
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count notifications: %w", err)
+	}
+
+	var notifications []models.Notification
+	offset := (query.Page - 1) * query.PageSize
+	if err := db.Order("id DESC").Offset(offset).Limit(query.PageSize).Find(&notifications).Error; err != nil {
+		return nil, fmt.Errorf("failed to get notifications: %w", err)
+	}
+
+	unread, err := s.GetUnreadCount(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.NotificationListResponse{
+		PaginatedResponse: models.PaginatedResponse{
+			Items:      notifications,
+			Total:      total,
+			Page:       query.Page,
+			PageSize:   query.PageSize,
+			TotalPages: int((total + int64(query.PageSize) - 1) / int64(query.PageSize)),
+		},
+		UnreadCount: unread,
+	}, nil
+}
+
+// GetUnreadCount 获取用户的未读通知数
+func (s *NotificationService) GetUnreadCount(userID uint) (int64, error) {
+	var count int64
+	if err := s.db.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count unread notifications: %w", err)
+	}
+	return count, nil
+}
+
+// MarkRead 将通知标记为已读
+func (s *NotificationService) MarkRead(id, userID uint) (*models.Notification, error) {
+	var notification models.Notification
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&notification).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("notification not found")
+		}
+		return nil, fmt.Errorf("failed to get notification: %w", err)
+	}
+
+	if notification.ReadAt == nil {
+		now := time.Now()
+		if err := s.db.Model(&notification).Update("read_at", now).Error; err != nil {
+			return nil, fmt.Errorf("failed to mark notification as read: %w", err)
+		}
+		notification.ReadAt = &now
+	}
+	return &notification, nil
+}
+
+// MarkAllRead 将用户的未读通知全部标记为已读，notificationType 不为空时只标记该类通知，返回标记的数量
+func (s *NotificationService) MarkAllRead(userID uint, notificationType string) (int64, error) {
+	db := s.db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID)
+	if notificationType != "" {
+		db = db.Where("type = ?", notificationType)
+	}
+	result := db.Update("read_at", time.Now())
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to mark notifications as read: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// GetPreferences 获取用户对各类通知的设置
+func (s *NotificationService) GetPreferences(userID uint) ([]models.NotificationPreferenceItem, error) {
+	var preferences []models.NotificationPreference
+	if err := s.db.Where("user_id = ?", userID).Find(&preferences).Error; err != nil {
+		return nil, fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+
+	enabled := make(map[string]bool, len(preferences))
+	for _, p := range preferences {
+		enabled[p.Type] = p.Enabled
+	}
+
+	items := make([]models.NotificationPreferenceItem, 0, len(notificationTypes))
+	for _, t := range notificationTypes {
+		item := models.NotificationPreferenceItem{Type: t.Type, Description: t.Description, Enabled: true}
+		if e, ok := enabled[t.Type]; ok {
+			item.Enabled = e
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// UpdatePreferences 更新用户对各类通知的设置，未提供的类型保持不变
+func (s *NotificationService) UpdatePreferences(userID uint, req *models.NotificationPreferencesRequest) ([]models.NotificationPreferenceItem, error) {
+	known := make(map[string]bool, len(notificationTypes))
+	for _, t := range notificationTypes {
+		known[t.Type] = true
+	}
+
+	preferences := make([]models.NotificationPreference, 0, len(req.Preferences))
+	for _, p := range req.Preferences {
+		if !known[p.Type] {
+			return nil, errors.New("invalid notification type")
+		}
+		preferences = append(preferences, models.NotificationPreference{
+			UserID:  userID,
+			Type:    p.Type,
+			Enabled: p.Enabled,
+		})
+	}
+
+	if err := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled", "updated_at"}),
+	}).Create(&preferences).Error; err != nil {
+		return nil, fmt.Errorf("failed to update notification preferences: %w", err)
+	}
+
+	return s.GetPreferences(userID)
+}
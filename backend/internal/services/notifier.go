@@ -0,0 +1,40 @@
+package services
+
+// 通知类型
+const (
+	NotificationReportResolved     = "report_resolved"
+	NotificationReminder           = "reminder"            // 写明信片提醒
+	NotificationCharacterCheckIn   = "character_check_in"  // 角色主动寄来明信片
+	NotificationAIReply            = "ai_reply"            // 角色回信
+	NotificationCharacterFavorited = "character_favorited" // 创建的角色被收藏
+	NotificationExportReady        = "export_ready"        // 印刷导出完成
+	NotificationExportFailed       = "export_failed"       // 印刷导出失败
+)
+
+// notificationTypes 用户可以在通知设置中开关的通知类型及说明
+var notificationTypes = []struct {
+	Type        string
+	Description string
+}{
+	{NotificationAIReply, "角色回信"},
+	{NotificationCharacterCheckIn, "角色主动寄来明信片"},
+	{NotificationReminder, "写明信片提醒"},
+	{NotificationCharacterFavorited, "我创建的角色被收藏"},
+	{NotificationExportReady, "印刷导出完成"},
+	{NotificationExportFailed, "印刷导出失败"},
+	{NotificationReportResolved, "举报处理结果"},
+}
+
+// NotificationEvent 服务内部产生的通知事件
+type NotificationEvent struct {
+	UserID  uint
+	Type    string
+	Title   string
+	Body    string
+	Payload map[string]interface{}
+}
+
+// Notifier 通知发送接口，各服务通过它向用户发送通知
+type Notifier interface {
+	Notify(event NotificationEvent)
+}
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"memory-postcard-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type PersonaService struct {
+	db               *gorm.DB
+	characterService *CharacterService
+}
+
+func NewPersonaService(db *gorm.DB, characterService *CharacterService) *PersonaService {
+	return &PersonaService{
+		db:               db,
+		characterService: characterService,
+	}
+}
+
+// ListPersonas 获取用户保存的人设列表
+func (s *PersonaService) ListPersonas(userID uint) ([]models.UserPersona, error) {
+	var personas []models.UserPersona
+	if err := s.db.Where("user_id = ?", userID).Order("is_default DESC, created_at ASC").Find(&personas).Error; err != nil {
+		return nil, fmt.Errorf("failed to get personas: %w", err)
+	}
+
+	return personas, nil
+}
+
+// CreatePersona 创建人设
+func (s *PersonaService) CreatePersona(userID uint, req *models.PersonaCreateRequest) (*models.UserPersona, error) {
+	persona := models.UserPersona{
+		UserID:      userID,
+		Name:        req.Name,
+		Description: req.Description,
+		IsDefault:   req.IsDefault,
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if persona.IsDefault {
+			if err := s.clearDefault(tx, userID); err != nil {
+				return err
+			}
+		}
+		return tx.Create(&persona).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create persona: %w", err)
+	}
+
+	return &persona, nil
+}
+
+// UpdatePersona 更新人设
+func (s *PersonaService) UpdatePersona(id uint, userID uint, req *models.PersonaUpdateRequest) (*models.UserPersona, error) {
+	persona, err := s.getOwnPersona(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 更新字段
+	if req.Name != "" {
+		persona.Name = req.Name
+	}
+	if req.Description != "" {
+		persona.Description = req.Description
+	}
+	if req.IsDefault != nil {
+		persona.IsDefault = *req.IsDefault
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if persona.IsDefault {
+			if err := s.clearDefault(tx, userID); err != nil {
+				return err
+			}
+		}
+		return tx.Save(persona).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update persona: %w", err)
+	}
+
+	return persona, nil
+}
+
+// DeletePersona 删除人设，并解除各角色对它的引用
+func (s *PersonaService) DeletePersona(id uint, userID uint) error {
+	persona, err := s.getOwnPersona(id, userID)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.UserCharacterRelation{}).
+			Where("user_id = ? AND persona_id = ?", userID, persona.ID).
+			UpdateColumn("persona_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(persona).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete persona: %w", err)
+	}
+
+	return nil
+}
+
+// SetCharacterPersona 为某个角色设置用户人设
+func (s *PersonaService) SetCharacterPersona(userID, characterID uint, req *models.CharacterPersonaRequest) (*models.ResolvedPersona, error) {
+	character, err := s.characterService.GetCharacter(characterID, &userID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{
+		"persona_id":   nil,
+		"persona_name": req.Name,
+		"persona_desc": req.Description,
+	}
+	if req.PersonaID != nil {
+		if _, err := s.getOwnPersona(*req.PersonaID, userID); err != nil {
+			return nil, err
+		}
+		updates = map[string]interface{}{
+			"persona_id":   *req.PersonaID,
+			"persona_name": "",
+			"persona_desc": "",
+		}
+	}
+
+	relation, err := s.findOrCreateRelation(userID, characterID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Model(relation).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to set character persona: %w", err)
+	}
+
+	return s.ResolvePersona(userID, character)
+}
+
+// ClearCharacterPersona 清除为某个角色设置的人设，恢复使用默认人设
+func (s *PersonaService) ClearCharacterPersona(userID, characterID uint) error {
+	if err := s.db.Model(&models.UserCharacterRelation{}).
+		Where("user_id = ? AND character_id = ?", userID, characterID).
+		Updates(map[string]interface{}{
+			"persona_id":   nil,
+			"persona_name": "",
+			"persona_desc": "",
+		}).Error; err != nil {
+		return fmt.Errorf("failed to clear character persona: %w", err)
+	}
+
+	return nil
+}
+
+// GetCharacterPersona 获取与角色交流时实际生效的人设
+func (s *PersonaService) GetCharacterPersona(userID, characterID uint) (*models.ResolvedPersona, error) {
+	character, err := s.characterService.GetCharacter(characterID, &userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.ResolvePersona(userID, character)
+}
+
+// ResolvePersona 解析用户与角色交流时使用的人设
+// 优先级：为该角色单独填写的人设 > 为该角色选用的已保存人设 > 用户的默认人设 > 角色设定的用户角色
+func (s *PersonaService) ResolvePersona(userID uint, character *models.Character) (*models.ResolvedPersona, error) {
+	var relation models.UserCharacterRelation
+	err := s.db.Where("user_id = ? AND character_id = ?", userID, character.ID).First(&relation).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get relation: %w", err)
+	}
+
+	if relation.PersonaName != "" {
+		return &models.ResolvedPersona{
+			Name:        relation.PersonaName,
+			Description: relation.PersonaDesc,
+			Source:      "custom",
+		}, nil
+	}
+
+	var persona models.UserPersona
+	if relation.PersonaID != nil {
+		if err := s.db.Where("id = ? AND user_id = ?", *relation.PersonaID, userID).First(&persona).Error; err == nil {
+			return &models.ResolvedPersona{
+				Name:        persona.Name,
+				Description: persona.Description,
+				Source:      "persona",
+				PersonaID:   &persona.ID,
+			}, nil
+		}
+	}
+
+	if err := s.db.Where("user_id = ? AND is_default = ?", userID, true).First(&persona).Error; err == nil {
+		return &models.ResolvedPersona{
+			Name:        persona.Name,
+			Description: persona.Description,
+			Source:      "default",
+			PersonaID:   &persona.ID,
+		}, nil
+	}
+
+	return &models.ResolvedPersona{
+		Name:        character.UserRoleName,
+		Description: character.UserRoleDesc,
+		Source:      "character",
+	}, nil
+}
+
+func (s *PersonaService) getOwnPersona(id uint, userID uint) (*models.UserPersona, error) {
+	var persona models.UserPersona
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&persona).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("persona not found")
+		}
+		return nil, fmt.Errorf("failed to get persona: %w", err)
+	}
+
+	return &persona, nil
+}
+
+func (s *PersonaService) clearDefault(tx *gorm.DB, userID uint) error {
+	return tx.Model(&models.UserPersona{}).
+		Where("user_id = ? AND is_default = ?", userID, true).
+		UpdateColumn("is_default", false).Error
+}
+
+func (s *PersonaService) findOrCreateRelation(userID, characterID uint) (*models.UserCharacterRelation, error) {
+	var relation models.UserCharacterRelation
+	err := s.db.Where("user_id = ? AND character_id = ?", userID, characterID).First(&relation).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		relation = models.UserCharacterRelation{
+			UserID:      userID,
+			CharacterID: characterID,
+		}
+		if err := s.db.Create(&relation).Error; err != nil {
+			return nil, fmt.Errorf("failed to create relation: %w", err)
+		}
+		return &relation, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get relation: %w", err)
+	}
+
+	return &relation, nil
+}
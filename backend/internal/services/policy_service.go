@@ -0,0 +1,143 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"memory-postcard-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// PolicyService 集中的授权策略：对话归属、私有角色可见性，以及谁可以写 AI 明信片
+// Can*/Check* 方法只根据传入的数据做判断，不访问数据库；Authorize* 方法加载数据后调用它们
+type PolicyService struct {
+	db *gorm.DB
+}
+
+func NewPolicyService(db *gorm.DB) *PolicyService {
+	return &PolicyService{
+		db: db,
+	}
+}
+
+// CanViewCharacter 公开角色所有人可见，私有角色和被管理员隐藏的角色仅创建者可见
+func (p *PolicyService) CanViewCharacter(userID *uint, character *models.Character) bool {
+	if userID != nil && character.CreatorID == *userID {
+		return true
+	}
+	return character.Visibility != "private" && character.HiddenAt == nil
+}
+
+// CanChatWithCharacter 可以给可见且处于启用状态的角色写明信片，创建者也可以给自己停用的角色写
+// 被管理员隐藏的角色任何人都不能再写
+func (p *PolicyService) CanChatWithCharacter(userID uint, character *models.Character) bool {
+	if character.HiddenAt != nil {
+		return false
+	}
+	if character.CreatorID == userID {
+		return true
+	}
+	return character.Visibility != "private" && character.IsActive
+}
+
+// CanAccessConversation 对话只属于创建它的用户
+func (p *PolicyService) CanAccessConversation(userID uint, conversation *models.Conversation) bool {
+	return conversation.UserID == userID
+}
+
+// CanWritePostcard 用户只能以自己的身份写明信片，AI 明信片只能由系统生成
+func (p *PolicyService) CanWritePostcard(postcardType string) bool {
+	return postcardType == "user"
+}
+
+// CheckPostcardCreate 检查用户能否在对话中给角色写明信片
+// conversation 为 nil 表示开始新对话
+func (p *PolicyService) CheckPostcardCreate(userID uint, postcardType string, character *models.Character, conversation *models.Conversation) error {
+	if !p.CanWritePostcard(postcardType) {
+		return errors.New("permission denied")
+	}
+	if !p.CanChatWithCharacter(userID, character) {
+		return errors.New("character not found")
+	}
+	if conversation != nil {
+		if !p.CanAccessConversation(userID, conversation) {
+			return errors.New("conversation not found")
+		}
+		if conversation.CharacterID != character.ID {
+			return errors.New("conversation belongs to another character")
+		}
+	}
+	return nil
+}
+
+// AuthorizeCharacterView 加载用户可见的角色，不可见的私有角色视为不存在
+func (p *PolicyService) AuthorizeCharacterView(userID *uint, characterID uint) (*models.Character, error) {
+	character, err := p.loadCharacter(characterID)
+	if err != nil {
+		return nil, err
+	}
+	if !p.CanViewCharacter(userID, character) {
+		return nil, errors.New("character not found")
+	}
+	return character, nil
+}
+
+// AuthorizeCharacterChat 加载用户可以写明信片的角色
+func (p *PolicyService) AuthorizeCharacterChat(userID uint, characterID uint) (*models.Character, error) {
+	character, err := p.loadCharacter(characterID)
+	if err != nil {
+		return nil, err
+	}
+	if !p.CanChatWithCharacter(userID, character) {
+		return nil, errors.New("character not found")
+	}
+	return character, nil
+}
+
+// AuthorizeConversation 加载用户自己的对话，其他用户的对话视为不存在
+func (p *PolicyService) AuthorizeConversation(userID uint, conversationID string) (*models.Conversation, error) {
+	var conversation models.Conversation
+	if err := p.db.Where("id = ?", conversationID).First(&conversation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("conversation not found")
+		}
+		return nil, fmt.Errorf("failed to get conversation: %w", err)
+	}
+	if !p.CanAccessConversation(userID, &conversation) {
+		return nil, errors.New("conversation not found")
+	}
+	return &conversation, nil
+}
+
+// AuthorizePostcardCreate 检查创建明信片的请求
+// 提供的 conversation_id 尚不存在时视为开始新对话
+func (p *PolicyService) AuthorizePostcardCreate(userID uint, req *models.PostcardCreateRequest) error {
+	character, err := p.loadCharacter(req.CharacterID)
+	if err != nil {
+		return err
+	}
+
+	var conversation *models.Conversation
+	if req.ConversationID != "" {
+		var existing models.Conversation
+		err := p.db.Where("id = ?", req.ConversationID).First(&existing).Error
+		if err == nil {
+			conversation = &existing
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to get conversation: %w", err)
+		}
+	}
+
+	return p.CheckPostcardCreate(userID, req.Type, character, conversation)
+}
+
+func (p *PolicyService) loadCharacter(characterID uint) (*models.Character, error) {
+	var character models.Character
+	if err := p.db.First(&character, characterID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("character not found")
+		}
+		return nil, fmt.Errorf("failed to get character: %w", err)
+	}
+	return &character, nil
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"memory-postcard-backend/internal/models"
+)
+
+func uintPtr(v uint) *uint {
+	return &v
+}
+
+func timePtr(v time.Time) *time.Time {
+	return &v
+}
+
+func TestPolicyCanViewCharacter(t *testing.T) {
+	p := &PolicyService{}
+
+	tests := []struct {
+		name      string
+		userID    *uint
+		character models.Character
+		want      bool
+	}{
+		{"public character, anonymous", nil, models.Character{CreatorID: 1, Visibility: "public"}, true},
+		{"public character, other user", uintPtr(2), models.Character{CreatorID: 1, Visibility: "public"}, true},
+		{"private character, anonymous", nil, models.Character{CreatorID: 1, Visibility: "private"}, false},
+		{"private character, other user", uintPtr(2), models.Character{CreatorID: 1, Visibility: "private"}, false},
+		{"private character, creator", uintPtr(1), models.Character{CreatorID: 1, Visibility: "private"}, true},
+		{"hidden character, other user", uintPtr(2), models.Character{CreatorID: 1, Visibility: "public", HiddenAt: timePtr(time.Now())}, false},
+		{"hidden character, creator", uintPtr(1), models.Character{CreatorID: 1, Visibility: "public", HiddenAt: timePtr(time.Now())}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.CanViewCharacter(tt.userID, &tt.character); got != tt.want {
+				t.Errorf("CanViewCharacter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyCanChatWithCharacter(t *testing.T) {
+	p := &PolicyService{}
+
+	tests := []struct {
+		name      string
+		userID    uint
+		character models.Character
+		want      bool
+	}{
+		{"public active character", 2, models.Character{CreatorID: 1, Visibility: "public", IsActive: true}, true},
+		{"public inactive character", 2, models.Character{CreatorID: 1, Visibility: "public", IsActive: false}, false},
+		{"private character, other user", 2, models.Character{CreatorID: 1, Visibility: "private", IsActive: true}, false},
+		{"private character, creator", 1, models.Character{CreatorID: 1, Visibility: "private", IsActive: true}, true},
+		{"inactive character, creator", 1, models.Character{CreatorID: 1, Visibility: "public", IsActive: false}, true},
+		{"hidden character, other user", 2, models.Character{CreatorID: 1, Visibility: "public", IsActive: true, HiddenAt: timePtr(time.Now())}, false},
+		{"hidden character, creator", 1, models.Character{CreatorID: 1, Visibility: "public", IsActive: true, HiddenAt: timePtr(time.Now())}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.CanChatWithCharacter(tt.userID, &tt.character); got != tt.want {
+				t.Errorf("CanChatWithCharacter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyCanAccessConversation(t *testing.T) {
+	p := &PolicyService{}
+
+	tests := []struct {
+		name         string
+		userID       uint
+		conversation models.Conversation
+		want         bool
+	}{
+		{"own conversation", 1, models.Conversation{ID: "c1", UserID: 1}, true},
+		{"other user's conversation", 2, models.Conversation{ID: "c1", UserID: 1}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.CanAccessConversation(tt.userID, &tt.conversation); got != tt.want {
+				t.Errorf("CanAccessConversation() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyCheckPostcardCreate(t *testing.T) {
+	p := &PolicyService{}
+
+	public := &models.Character{ID: 10, CreatorID: 1, Visibility: "public", IsActive: true}
+	private := &models.Character{ID: 11, CreatorID: 1, Visibility: "private", IsActive: true}
+
+	tests := []struct {
+		name         string
+		userID       uint
+		postcardType string
+		character    *models.Character
+		conversation *models.Conversation
+		wantErr      string
+	}{
+		{"new conversation with public character", 2, "user", public, nil, ""},
+		{"own conversation", 2, "user", public, &models.Conversation{UserID: 2, CharacterID: 10}, ""},
+		{"ai postcard is rejected", 2, "ai", public, nil, "permission denied"},
+		{"ai postcard is rejected for creator", 1, "ai", public, nil, "permission denied"},
+		{"unknown type is rejected", 2, "system", public, nil, "permission denied"},
+		{"other user's private character", 2, "user", private, nil, "character not found"},
+		{"creator's private character", 1, "user", private, nil, ""},
+		{"other user's conversation", 2, "user", public, &models.Conversation{UserID: 3, CharacterID: 10}, "conversation not found"},
+		{"conversation with another character", 2, "user", public, &models.Conversation{UserID: 2, CharacterID: 99}, "conversation belongs to another character"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := p.CheckPostcardCreate(tt.userID, tt.postcardType, tt.character, tt.conversation)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("CheckPostcardCreate() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("CheckPostcardCreate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
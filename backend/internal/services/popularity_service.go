@@ -0,0 +1,287 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"memory-postcard-backend/internal/models"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+const (
+	// 热度计算只统计最近 30 天的明信片
+	popularityLookback = 30 * 24 * time.Hour
+	// 明信片热度的半衰期
+	popularityHalfLifeHours = 72.0
+
+	popularityPostcardWeight = 1.0
+	popularityUserWeight     = 2.0
+	popularityFavoriteWeight = 3.0
+
+	trendingCacheTTL = 10 * time.Minute
+)
+
+type PopularityService struct {
+	db    *gorm.DB
+	redis *redis.Client
+}
+
+func NewPopularityService(db *gorm.DB, redis *redis.Client) *PopularityService {
+	return &PopularityService{
+		db:    db,
+		redis: redis,
+	}
+}
+
+type characterActivity struct {
+	CharacterID   uint
+	DecayedVolume float64
+	UserCount     int64
+}
+
+type characterFavorites struct {
+	CharacterID   uint
+	FavoriteCount int64
+}
+
+// StartPopularityJob 启动定期计算角色热度的后台任务
+func (s *PopularityService) StartPopularityJob(ctx context.Context, interval time.Duration) {
+	go func() {
+		if err := s.RecomputePopularity(); err != nil {
+			log.Printf("Failed to recompute popularity: %v", err)
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.RecomputePopularity(); err != nil {
+					log.Printf("Failed to recompute popularity: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// RecomputePopularity 根据近期明信片量、独立用户数、收藏数和时间衰减重新计算角色热度
+func (s *PopularityService) RecomputePopularity() error {
+	since := time.Now().Add(-popularityLookback)
+
+	// 近期明信片量（按时间指数衰减）与独立用户数
+	var activities []characterActivity
+	if err := s.db.Model(&models.Postcard{}).
+		Select("character_id, SUM(EXP(-TIMESTAMPDIFF(HOUR, created_at, NOW()) * LN(2) / ?)) AS decayed_volume, COUNT(DISTINCT user_id) AS user_count", popularityHalfLifeHours).
+		Where("type = ? AND created_at >= ?", "user", since).
+		Group("character_id").
+		Scan(&activities).Error; err != nil {
+		return fmt.Errorf("failed to aggregate postcard activity: %w", err)
+	}
+
+	// 收藏数
+	var favorites []characterFavorites
+	if err := s.db.Model(&models.UserCharacterRelation{}).
+		Select("character_id, COUNT(*) AS favorite_count").
+		Where("is_favorite = ?", true).
+		Group("character_id").
+		Scan(&favorites).Error; err != nil {
+		return fmt.Errorf("failed to aggregate favorites: %w", err)
+	}
+
+	scores := make(map[uint]float64)
+	for _, a := range activities {
+		scores[a.CharacterID] += popularityPostcardWeight*a.DecayedVolume + popularityUserWeight*float64(a.UserCount)
+	}
+	for _, f := range favorites {
+		scores[f.CharacterID] += popularityFavoriteWeight * float64(f.FavoriteCount)
+	}
+
+	var characters []models.Character
+	if err := s.db.Select("id", "popularity_score").Find(&characters).Error; err != nil {
+		return fmt.Errorf("failed to get characters: %w", err)
+	}
+
+	updated := 0
+	for _, character := range characters {
+		score := math.Round(scores[character.ID]*10000) / 10000
+		if score == character.PopularityScore {
+			continue
+		}
+		if err := s.db.Model(&models.Character{}).Where("id = ?", character.ID).
+			UpdateColumn("popularity_score", score).Error; err != nil {
+			return fmt.Errorf("failed to update popularity score: %w", err)
+		}
+		s.redis.Del(context.Background(), fmt.Sprintf("character:%d", character.ID))
+		updated++
+	}
+
+	if updated > 0 {
+		s.clearCharacterListCache()
+	}
+	s.clearTrendingCache()
+
+	log.Printf("Popularity recomputed: %d characters updated", updated)
+	return nil
+}
+
+// GetTrendingCharacters 获取热门角色（day/week 按窗口内的明信片量排序，all 按热度分排序）
+func (s *PopularityService) GetTrendingCharacters(query *models.TrendingQuery) ([]models.TrendingCharacter, error) {
+	cacheKey := fmt.Sprintf("character_trending:%s:%d", query.Window, query.Limit)
+	if cached := s.getTrendingFromCache(cacheKey); cached != nil {
+		return cached, nil
+	}
+
+	var trending []models.TrendingCharacter
+	var err error
+	if query.Window == "all" {
+		trending, err = s.getAllTimeTrending(query.Limit)
+	} else {
+		trending, err = s.getWindowTrending(trendingWindowStart(query.Window), query.Limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.cacheTrending(cacheKey, trending)
+
+	return trending, nil
+}
+
+func (s *PopularityService) getAllTimeTrending(limit int) ([]models.TrendingCharacter, error) {
+	var characters []models.Character
+	if err := s.db.Preload("Creator").
+		Where("visibility = ? AND is_active = ? AND hidden_at IS NULL", "public", true).
+		Order("popularity_score DESC, usage_count DESC").
+		Limit(limit).
+		Find(&characters).Error; err != nil {
+		return nil, fmt.Errorf("failed to get trending characters: %w", err)
+	}
+
+	trending := make([]models.TrendingCharacter, len(characters))
+	for i, character := range characters {
+		trending[i] = models.TrendingCharacter{
+			Character:     character,
+			TrendingScore: character.PopularityScore,
+		}
+	}
+
+	return trending, nil
+}
+
+func (s *PopularityService) getWindowTrending(since time.Time, limit int) ([]models.TrendingCharacter, error) {
+	var rows []struct {
+		CharacterID   uint
+		PostcardCount int64
+		UserCount     int64
+	}
+	if err := s.db.Table("postcards AS p").
+		Select("p.character_id, COUNT(*) AS postcard_count, COUNT(DISTINCT p.user_id) AS user_count").
+		Joins("JOIN characters AS c ON c.id = p.character_id").
+		Where("p.type = ? AND p.created_at >= ? AND p.deleted_at IS NULL", "user", since).
+		Where("c.visibility = ? AND c.is_active = ? AND c.hidden_at IS NULL AND c.deleted_at IS NULL", "public", true).
+		Group("p.character_id").
+		Order(fmt.Sprintf("COUNT(*) * %v + COUNT(DISTINCT p.user_id) * %v DESC", popularityPostcardWeight, popularityUserWeight)).
+		Limit(limit).
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to aggregate trending characters: %w", err)
+	}
+
+	if len(rows) == 0 {
+		return []models.TrendingCharacter{}, nil
+	}
+
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = row.CharacterID
+	}
+
+	var characters []models.Character
+	if err := s.db.Preload("Creator").Where("id IN ?", ids).Find(&characters).Error; err != nil {
+		return nil, fmt.Errorf("failed to get trending characters: %w", err)
+	}
+
+	byID := make(map[uint]models.Character, len(characters))
+	for _, character := range characters {
+		byID[character.ID] = character
+	}
+
+	trending := make([]models.TrendingCharacter, 0, len(rows))
+	for _, row := range rows {
+		character, ok := byID[row.CharacterID]
+		if !ok {
+			continue
+		}
+		trending = append(trending, models.TrendingCharacter{
+			Character:       character,
+			WindowPostcards: row.PostcardCount,
+			WindowUsers:     row.UserCount,
+			TrendingScore:   float64(row.PostcardCount)*popularityPostcardWeight + float64(row.UserCount)*popularityUserWeight,
+		})
+	}
+
+	return trending, nil
+}
+
+// trendingWindowStart 返回统计窗口的起始时间
+func trendingWindowStart(window string) time.Time {
+	switch window {
+	case "day":
+		return time.Now().Add(-24 * time.Hour)
+	default:
+		return time.Now().Add(-7 * 24 * time.Hour)
+	}
+}
+
+// 缓存相关方法
+func (s *PopularityService) cacheTrending(key string, trending []models.TrendingCharacter) {
+	ctx := context.Background()
+	data, _ := json.Marshal(trending)
+	s.redis.Set(ctx, key, data, trendingCacheTTL)
+}
+
+func (s *PopularityService) getTrendingFromCache(key string) []models.TrendingCharacter {
+	ctx := context.Background()
+	data, err := s.redis.Get(ctx, key).Result()
+	if err != nil {
+		return nil
+	}
+
+	var trending []models.TrendingCharacter
+	if err := json.Unmarshal([]byte(data), &trending); err != nil {
+		return nil
+	}
+
+	return trending
+}
+
+func (s *PopularityService) clearTrendingCache() {
+	ctx := context.Background()
+	keys, err := s.redis.Keys(ctx, "character_trending:*").Result()
+	if err != nil {
+		return
+	}
+
+	if len(keys) > 0 {
+		s.redis.Del(ctx, keys...)
+	}
+}
+
+func (s *PopularityService) clearCharacterListCache() {
+	ctx := context.Background()
+	keys, err := s.redis.Keys(ctx, "character_list:*").Result()
+	if err != nil {
+		return
+	}
+
+	if len(keys) > 0 {
+		s.redis.Del(ctx, keys...)
+	}
+}
@@ -1,10 +1,12 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
 	"memory-postcard-backend/internal/models"
+	"strings"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -12,19 +14,42 @@ import (
 	"gorm.io/gorm"
 )
 
+// maxReplyAlternatives 每张用户明信片最多生成的回复数量
+const maxReplyAlternatives = 10
+
 type PostcardService struct {
-	db        *gorm.DB
-	redis     *redis.Client
-	aiService *AIService
-	mqService *MQService
+	db              *gorm.DB
+	redis           *redis.Client
+	aiService       *AIService
+	mqService       *MQService
+	personaService  *PersonaService
+	lorebookService *LorebookService
+	memoryService   *MemoryService
+
+	conversationService *ConversationService
+	templateService     *TemplateService
+	emotionService      *EmotionService
+	webhookService      *WebhookService
+	uploadService       *UploadService
+	notifier            Notifier
 }
 
-func NewPostcardService(db *gorm.DB, redis *redis.Client, aiService *AIService, mqService *MQService) *PostcardService {
+func NewPostcardService(db *gorm.DB, redis *redis.Client, aiService *AIService, mqService *MQService, personaService *PersonaService, lorebookService *LorebookService, memoryService *MemoryService, conversationService *ConversationService, templateService *TemplateService, emotionService *EmotionService, webhookService *WebhookService, uploadService *UploadService, notifier Notifier) *PostcardService {
 	return &PostcardService{
-		db:        db,
-		redis:     redis,
-		aiService: aiService,
-		mqService: mqService,
+		db:              db,
+		redis:           redis,
+		aiService:       aiService,
+		mqService:       mqService,
+		personaService:  personaService,
+		lorebookService: lorebookService,
+		memoryService:   memoryService,
+
+		conversationService: conversationService,
+		templateService:     templateService,
+		emotionService:      emotionService,
+		webhookService:      webhookService,
+		uploadService:       uploadService,
+		notifier:            notifier,
 	}
 }
 
@@ -39,11 +64,27 @@ func (s *PostcardService) CreatePostcard(userID uint, req *models.PostcardCreate
 		return nil, fmt.Errorf("failed to get character: %w", err)
 	}
 
-	// 生成对话 ID（如果没有提供）
+	if err := s.templateService.ValidateTemplateID(req.PostcardTemplate); err != nil {
+		return nil, err
+	}
+	if err := s.uploadService.VerifyOwnership(userID, req.ImageURL, req.VoiceURL); err != nil {
+		return nil, err
+	}
+
+	// 生成对话 ID（如果没有提供），新对话先发送角色的问候明信片
 	conversationID := req.ConversationID
-	if conversationID == "" {
+	newConversation := conversationID == ""
+	if newConversation {
 		conversationID = uuid.New().String()
 	}
+	if _, err := s.conversationService.EnsureConversation(conversationID, userID, req.CharacterID); err != nil {
+		return nil, err
+	}
+	if newConversation {
+		if _, err := s.sendGreeting(conversationID, userID, &character); err != nil {
+			return nil, err
+		}
+	}
 
 	// 创建明信片
 	postcard := models.Postcard{
@@ -68,16 +109,60 @@ func (s *PostcardService) CreatePostcard(userID uint, req *models.PostcardCreate
 	// 更新角色使用次数和用户关系
 	go s.updateCharacterStats(userID, req.CharacterID)
 
-	// 异步生成 AI 回复
-	go s.generateAIReply(conversationID, userID, req.CharacterID, req.Content)
+	// 为明信片建立向量，供之后回忆
+	go func() {
+		if err := s.memoryService.IndexPostcard(&postcard); err != nil {
+			log.Printf("Failed to index postcard %d: %v", postcard.ID, err)
+		}
+	}()
+
+	// 分析明信片情绪
+	go func() {
+		if err := s.emotionService.ClassifyPostcard(&postcard); err != nil {
+			log.Printf("Failed to classify postcard %d: %v", postcard.ID, err)
+		}
+	}()
+
+	go s.webhookService.Publish(userID, WebhookPostcardCreated, postcard)
+
+	// 异步生成 AI 回复（客户端选择使用流式回复接口时跳过）
+	if !req.SkipAIReply {
+		go s.generateAIReply(&postcard)
+	}
 
 	return &postcard, nil
 }
 
+// StartConversation 开始与角色的新对话，角色设置了开场白时自动发送问候明信片
+func (s *PostcardService) StartConversation(userID uint, req *models.ConversationStartRequest) (*models.ConversationStartResponse, error) {
+	var character models.Character
+	if err := s.db.First(&character, req.CharacterID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("character not found")
+		}
+		return nil, fmt.Errorf("failed to get character: %w", err)
+	}
+
+	conversationID := uuid.New().String()
+	if _, err := s.conversationService.EnsureConversation(conversationID, userID, character.ID); err != nil {
+		return nil, err
+	}
+
+	greeting, err := s.sendGreeting(conversationID, userID, &character)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ConversationStartResponse{
+		ConversationID: conversationID,
+		Greeting:       greeting,
+	}, nil
+}
+
 // GetPostcard 获取明信片详情
 func (s *PostcardService) GetPostcard(id uint, userID uint) (*models.Postcard, error) {
 	var postcard models.Postcard
-	if err := s.db.Preload("User").Preload("Character").Where("id = ? AND user_id = ?", id, userID).First(&postcard).Error; err != nil {
+	if err := s.db.Preload("User").Preload("Character").Preload("Emotions").Where("id = ? AND user_id = ?", id, userID).First(&postcard).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("postcard not found")
 		}
@@ -87,11 +172,16 @@ func (s *PostcardService) GetPostcard(id uint, userID uint) (*models.Postcard, e
 	return &postcard, nil
 }
 
+// GetRecalledMemories 获取 AI 明信片生成时回忆起的明信片
+func (s *PostcardService) GetRecalledMemories(id uint, userID uint) ([]models.RecalledMemory, error) {
+	return s.memoryService.GetRecalledMemories(id, userID)
+}
+
 // GetPostcardsByConversationID 通过 conversation_id 获取明信片列表
 func (s *PostcardService) GetPostcardsByConversationID(conversationID string) ([]models.Postcard, error) {
 	var postcards []models.Postcard
 	if err := s.db.Preload("User").Preload("Character").
-		Where("conversation_id = ?", conversationID).
+		Where("conversation_id = ? AND is_canonical = ?", conversationID, true).
 		Order("created_at ASC").
 		Find(&postcards).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -108,7 +198,7 @@ func (s *PostcardService) ListPostcards(userID uint, query *models.PostcardListQ
 	var postcards []models.Postcard
 	var total int64
 
-	db := s.db.Model(&models.Postcard{}).Where("user_id = ?", userID).Preload("User").Preload("Character")
+	db := s.db.Model(&models.Postcard{}).Where("user_id = ?", userID).Preload("User").Preload("Character").Preload("Emotions")
 
 	// 构建查询条件
 	if query.ConversationID != "" {
@@ -131,6 +221,14 @@ func (s *PostcardService) ListPostcards(userID uint, query *models.PostcardListQ
 		db = db.Where("is_favorite = ?", *query.IsFavorite)
 	}
 
+	if query.Emotion != "" {
+		db = db.Where("id IN (?)", s.db.Model(&models.PostcardEmotion{}).Select("postcard_id").Where("user_id = ? AND emotion = ?", userID, query.Emotion))
+	}
+
+	if !query.IncludeAlternatives {
+		db = db.Where("is_canonical = ?", true)
+	}
+
 	// 计算总数
 	if err := db.Count(&total).Error; err != nil {
 		return nil, fmt.Errorf("failed to count postcards: %w", err)
@@ -166,16 +264,34 @@ func (s *PostcardService) UpdatePostcard(id uint, userID uint, req *models.Postc
 	}
 
 	// 更新字段
-	if req.Content != "" {
+	contentChanged := req.Content != "" && req.Content != postcard.Content
+	if contentChanged {
+		// 编辑 AI 回复时保留原文
+		if postcard.Type == "ai" {
+			if postcard.OriginalContent == "" {
+				postcard.OriginalContent = postcard.Content
+			}
+			now := time.Now()
+			postcard.EditedAt = &now
+		}
 		postcard.Content = req.Content
 	}
-	if req.ImageURL != "" {
+	if req.ImageURL != "" && req.ImageURL != postcard.ImageURL {
+		if err := s.uploadService.VerifyOwnership(userID, req.ImageURL); err != nil {
+			return nil, err
+		}
 		postcard.ImageURL = req.ImageURL
 	}
-	if req.VoiceURL != "" {
+	if req.VoiceURL != "" && req.VoiceURL != postcard.VoiceURL {
+		if err := s.uploadService.VerifyOwnership(userID, req.VoiceURL); err != nil {
+			return nil, err
+		}
 		postcard.VoiceURL = req.VoiceURL
 	}
-	if req.PostcardTemplate != "" {
+	if req.PostcardTemplate != "" && req.PostcardTemplate != postcard.PostcardTemplate {
+		if err := s.templateService.ValidateTemplateID(req.PostcardTemplate); err != nil {
+			return nil, err
+		}
 		postcard.PostcardTemplate = req.PostcardTemplate
 	}
 	if req.Status != "" {
@@ -189,6 +305,21 @@ func (s *PostcardService) UpdatePostcard(id uint, userID uint, req *models.Postc
 		return nil, fmt.Errorf("failed to update postcard: %w", err)
 	}
 
+	// 内容修改后重新分析情绪，并重建向量以免回忆起修改前的内容
+	if contentChanged {
+		updated := postcard
+		go func() {
+			if err := s.emotionService.ClassifyPostcard(&updated); err != nil {
+				log.Printf("Failed to classify postcard %d: %v", updated.ID, err)
+			}
+		}()
+		go func() {
+			if err := s.memoryService.IndexPostcard(&updated); err != nil {
+				log.Printf("Failed to index postcard %d: %v", updated.ID, err)
+			}
+		}()
+	}
+
 	// 重新加载数据
 	s.db.Preload("User").Preload("Character").First(&postcard, postcard.ID)
 
@@ -210,13 +341,25 @@ func (s *PostcardService) DeletePostcard(id uint, userID uint) error {
 		return fmt.Errorf("failed to delete postcard: %w", err)
 	}
 
+	// 删除选定的回复后，改为选定最新的备选回复
+	if postcard.Type == "ai" && postcard.IsCanonical && postcard.ReplyToID != nil {
+		var sibling models.Postcard
+		if err := s.db.Where("reply_to_id = ?", *postcard.ReplyToID).Order("created_at DESC").First(&sibling).Error; err == nil {
+			s.db.Model(&sibling).UpdateColumn("is_canonical", true)
+		}
+	}
+
+	if err := s.memoryService.RemovePostcard(postcard.ID); err != nil {
+		log.Printf("Failed to remove postcard %d from memory: %v", postcard.ID, err)
+	}
+
 	return nil
 }
 
 // GetConversation 获取对话记录
 func (s *PostcardService) GetConversation(conversationID string, userID uint) ([]models.Postcard, error) {
 	var postcards []models.Postcard
-	if err := s.db.Where("conversation_id = ? AND user_id = ?", conversationID, userID).
+	if err := s.db.Where("conversation_id = ? AND user_id = ? AND is_canonical = ?", conversationID, userID, true).
 		Preload("User").Preload("Character").
 		Order("created_at ASC").
 		Find(&postcards).Error; err != nil {
@@ -226,6 +369,78 @@ func (s *PostcardService) GetConversation(conversationID string, userID uint) ([
 	return postcards, nil
 }
 
+// sendGreeting 以角色身份发送问候明信片，角色未设置开场白时返回 nil
+func (s *PostcardService) sendGreeting(conversationID string, userID uint, character *models.Character) (*models.Postcard, error) {
+	if strings.TrimSpace(character.Greeting) == "" {
+		return nil, nil
+	}
+
+	userName := ""
+	if persona, err := s.personaService.ResolvePersona(userID, character); err == nil {
+		userName = persona.Name
+	}
+
+	greeting := models.Postcard{
+		ConversationID: conversationID,
+		UserID:         userID,
+		CharacterID:    character.ID,
+		Type:           "ai",
+		Content:        ApplyPlaceholders(character.Greeting, character.Name, userName),
+		Status:         "sent",
+	}
+
+	if err := s.db.Create(&greeting).Error; err != nil {
+		return nil, fmt.Errorf("failed to create greeting postcard: %w", err)
+	}
+
+	return &greeting, nil
+}
+
+// SendCheckIn 用户在对话中长时间没有来信时，由角色主动寄出一张明信片
+func (s *PostcardService) SendCheckIn(conversation *models.Conversation, silentDays int) (*models.Postcard, error) {
+	var character models.Character
+	if err := s.db.First(&character, conversation.CharacterID).Error; err != nil {
+		return nil, fmt.Errorf("failed to get character: %w", err)
+	}
+	if character.HiddenAt != nil {
+		return nil, errors.New("character not found")
+	}
+
+	history, err := canonicalHistory(s.db, conversation.ID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	persona, err := s.personaService.ResolvePersona(conversation.UserID, &character)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := s.aiService.GenerateCheckIn(&ReplyContext{
+		Character: &character,
+		Persona:   persona,
+		History:   history,
+	}, silentDays)
+	if err != nil {
+		return nil, err
+	}
+
+	postcard := models.Postcard{
+		ConversationID: conversation.ID,
+		UserID:         conversation.UserID,
+		CharacterID:    character.ID,
+		Type:           "ai",
+		Content:        content,
+		Status:         "sent",
+	}
+	if err := s.db.Create(&postcard).Error; err != nil {
+		return nil, fmt.Errorf("failed to create check-in postcard: %w", err)
+	}
+	postcard.Character = character
+
+	return &postcard, nil
+}
+
 // updateCharacterStats 更新角色统计信息
 func (s *PostcardService) updateCharacterStats(userID, characterID uint) {
 	// 更新角色使用次数
@@ -255,58 +470,437 @@ func (s *PostcardService) updateCharacterStats(userID, characterID uint) {
 }
 
 // generateAIReply 生成 AI 回复（通过 MQ 异步处理）
-func (s *PostcardService) generateAIReply(conversationID string, userID, characterID uint, userMessage string) {
+func (s *PostcardService) generateAIReply(postcard *models.Postcard) {
 	// 如果 MQ 服务不可用，使用同步方式处理
 	if s.mqService == nil {
-		s.generateAIReplySync(conversationID, userID, characterID, userMessage)
+		s.generateAIReplySync(postcard)
 		return
 	}
 
 	// 创建 AI 回复消息
 	message := &AIReplyMessage{
-		ConversationID: conversationID,
-		UserID:         userID,
-		CharacterID:    characterID,
-		UserMessage:    userMessage,
+		ConversationID: postcard.ConversationID,
+		UserID:         postcard.UserID,
+		CharacterID:    postcard.CharacterID,
+		UserPostcardID: postcard.ID,
+		UserMessage:    postcard.Content,
+	}
+
+	// 附带用户在该对话中使用的人设、角色的示例对话、触发的世界设定和回忆起的明信片
+	if rc, err := s.buildReplyContext(postcard); err == nil {
+		message.UserRoleName = rc.Persona.Name
+		message.UserRoleDesc = rc.Persona.Description
+		for _, example := range rc.Character.ExampleDialogues {
+			message.Examples = append(message.Examples, models.ExampleDialogue{
+				User:      ApplyPlaceholders(example.User, rc.Character.Name, rc.Persona.Name),
+				Character: ApplyPlaceholders(example.Character, rc.Character.Name, rc.Persona.Name),
+			})
+		}
+		for _, entry := range rc.Lore {
+			message.Lore = append(message.Lore, fmt.Sprintf("【%s】%s", entry.Title, entry.Content))
+		}
+		for _, memory := range rc.Memories {
+			message.Memories = append(message.Memories, fmt.Sprintf("（%s）%s", memory.Postcard.CreatedAt.Format("2006-01-02"), memory.Postcard.Content))
+		}
+		message.RecalledMemories = rc.RecalledMemories()
 	}
 
 	// 发布到消息队列
 	if err := s.mqService.PublishAIReplyMessage(message); err != nil {
 		// 如果发布失败，回退到同步处理
 		log.Printf("Failed to publish AI reply message to MQ: %v, falling back to sync processing", err)
-		s.generateAIReplySync(conversationID, userID, characterID, userMessage)
+		s.generateAIReplySync(postcard)
 	}
 }
 
 // generateAIReplySync 同步生成 AI 回复（MQ 不可用时的回退方案）
-func (s *PostcardService) generateAIReplySync(conversationID string, userID, characterID uint, userMessage string) {
-	// 获取角色信息
-	var character models.Character
-	if err := s.db.First(&character, characterID).Error; err != nil {
+func (s *PostcardService) generateAIReplySync(postcard *models.Postcard) {
+	rc, err := s.buildReplyContext(postcard)
+	if err != nil {
 		return
 	}
 
-	// 获取对话历史
-	var history []models.Postcard
-	s.db.Where("conversation_id = ?", conversationID).
-		Order("created_at ASC").
-		Find(&history)
-
 	// 生成 AI 回复
-	reply, err := s.aiService.GenerateReply(&character, history, userMessage)
+	reply, err := s.aiService.GenerateReply(rc)
 	if err != nil {
 		return
 	}
 
-	// 创建 AI 回复明信片
+	if _, err := s.saveAIReply(postcard, rc, reply); err != nil {
+		log.Printf("Failed to save AI reply for postcard %d: %v", postcard.ID, err)
+	}
+}
+
+// StartAIReplySavedConsumer 消费 AI 助手通过 MQ 保存回复后发出的事件，MQ 不可用时不做任何事
+func (s *PostcardService) StartAIReplySavedConsumer(ctx context.Context) {
+	if s.mqService == nil {
+		return
+	}
+	if err := s.mqService.ConsumeAIReplySaved(ctx, s.HandleAIReplySaved); err != nil {
+		log.Printf("Failed to start AI reply saved consumer: %v", err)
+	}
+}
+
+// HandleAIReplySaved 处理 AI 助手保存的回复：加载回复及被回复的明信片后执行与同步路径相同的后续处理
+func (s *PostcardService) HandleAIReplySaved(message *AIReplySavedMessage) error {
+	var aiPostcard models.Postcard
+	if err := s.db.Where("id = ? AND type = ?", message.PostcardID, "ai").First(&aiPostcard).Error; err != nil {
+		return fmt.Errorf("failed to get AI reply: %w", err)
+	}
+	if aiPostcard.ReplyToID == nil {
+		return errors.New("AI reply has no reply_to_id")
+	}
+
+	var postcard models.Postcard
+	if err := s.db.First(&postcard, *aiPostcard.ReplyToID).Error; err != nil {
+		return fmt.Errorf("failed to get replied postcard: %w", err)
+	}
+
+	var character models.Character
+	if err := s.db.Select("id", "name").First(&character, aiPostcard.CharacterID).Error; err != nil {
+		return fmt.Errorf("failed to get character: %w", err)
+	}
+
+	s.onAIReplySaved(&postcard, &aiPostcard, character.Name)
+	return nil
+}
+
+// StreamReply 流式生成对用户明信片的回复
+// 返回的通道依次发送 delta 事件，最后发送 done 或 error 事件后关闭
+// 生成过程与客户端连接无关：ctx 结束（客户端断开）后停止发送事件，但回复仍会生成完毕并保存
+func (s *PostcardService) StreamReply(ctx context.Context, id uint, userID uint) (<-chan models.ReplyStreamEvent, error) {
+	var postcard models.Postcard
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&postcard).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("postcard not found")
+		}
+		return nil, fmt.Errorf("failed to get postcard: %w", err)
+	}
+	if postcard.Type != "user" {
+		return nil, errors.New("can only reply to user postcards")
+	}
+
+	var count int64
+	if err := s.db.Model(&models.Postcard{}).Where("reply_to_id = ?", postcard.ID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to check existing reply: %w", err)
+	}
+	if count > 0 {
+		return nil, errors.New("reply already exists")
+	}
+
+	// 同一张明信片同时只允许一个生成任务
+	if err := s.acquireReplyLock(postcard.ID); err != nil {
+		return nil, err
+	}
+
+	rc, err := s.buildReplyContext(&postcard)
+	if err != nil {
+		s.releaseReplyLock(postcard.ID)
+		return nil, err
+	}
+
+	events := make(chan models.ReplyStreamEvent)
+	send := func(event models.ReplyStreamEvent) {
+		select {
+		case events <- event:
+		case <-ctx.Done():
+		}
+	}
+
+	go func() {
+		defer close(events)
+		defer s.releaseReplyLock(postcard.ID)
+
+		genCtx, cancel := context.WithTimeout(context.Background(), streamReplyTimeout)
+		defer cancel()
+
+		reply, err := s.aiService.StreamReply(genCtx, rc, func(delta string) {
+			send(models.ReplyStreamEvent{Type: "delta", Delta: delta})
+		})
+		if err != nil {
+			log.Printf("AI reply stream for postcard %d interrupted: %v", postcard.ID, err)
+		}
+		if reply == "" {
+			send(models.ReplyStreamEvent{Type: "error", Error: "failed to generate reply"})
+			return
+		}
+
+		aiPostcard, err := s.saveAIReply(&postcard, rc, reply)
+		if err != nil {
+			log.Printf("Failed to save AI reply for postcard %d: %v", postcard.ID, err)
+			send(models.ReplyStreamEvent{Type: "error", Error: err.Error()})
+			return
+		}
+		send(models.ReplyStreamEvent{Type: "done", Postcard: aiPostcard})
+	}()
+
+	return events, nil
+}
+
+// RegenerateReply 为用户明信片重新生成一个回复
+// id 可以是用户明信片，也可以是它的任一 AI 回复；新回复成为选定的回复，原有回复保留为备选
+func (s *PostcardService) RegenerateReply(id uint, userID uint) (*models.Postcard, error) {
+	postcard, err := s.getRepliedPostcard(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var count int64
+	if err := s.db.Model(&models.Postcard{}).Where("reply_to_id = ?", postcard.ID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to count replies: %w", err)
+	}
+	if count >= maxReplyAlternatives {
+		return nil, fmt.Errorf("at most %d replies can be generated for a postcard", maxReplyAlternatives)
+	}
+
+	if err := s.acquireReplyLock(postcard.ID); err != nil {
+		return nil, err
+	}
+	defer s.releaseReplyLock(postcard.ID)
+
+	rc, err := s.buildReplyContext(postcard)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := s.aiService.GenerateReply(rc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate reply: %w", err)
+	}
+
+	aiPostcard, err := s.saveAIReply(postcard, rc, reply)
+	if err != nil {
+		return nil, err
+	}
+
+	s.db.Preload("User").Preload("Character").First(aiPostcard, aiPostcard.ID)
+	return aiPostcard, nil
+}
+
+// ListAlternatives 获取用户明信片的全部回复（含备选），id 可以是用户明信片或它的任一 AI 回复
+func (s *PostcardService) ListAlternatives(id uint, userID uint) ([]models.Postcard, error) {
+	postcard, err := s.getRepliedPostcard(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var replies []models.Postcard
+	if err := s.db.Where("reply_to_id = ?", postcard.ID).
+		Order("created_at ASC").
+		Find(&replies).Error; err != nil {
+		return nil, fmt.Errorf("failed to get replies: %w", err)
+	}
+
+	return replies, nil
+}
+
+// SelectReply 将 AI 回复设为选定的回复，只有选定的回复会进入后续对话历史
+func (s *PostcardService) SelectReply(id uint, userID uint) (*models.Postcard, error) {
+	var reply models.Postcard
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&reply).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("postcard not found")
+		}
+		return nil, fmt.Errorf("failed to get postcard: %w", err)
+	}
+	if reply.Type != "ai" || reply.ReplyToID == nil {
+		return nil, errors.New("postcard is not a reply")
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Postcard{}).
+			Where("reply_to_id = ? AND id <> ?", *reply.ReplyToID, reply.ID).
+			UpdateColumn("is_canonical", false).Error; err != nil {
+			return err
+		}
+		return tx.Model(&reply).UpdateColumn("is_canonical", true).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to select reply: %w", err)
+	}
+
+	s.db.Preload("User").Preload("Character").First(&reply, reply.ID)
+	return &reply, nil
+}
+
+// getRepliedPostcard 获取被回复的用户明信片，id 为 AI 回复时返回它所回复的明信片
+func (s *PostcardService) getRepliedPostcard(id uint, userID uint) (*models.Postcard, error) {
+	var postcard models.Postcard
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&postcard).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("postcard not found")
+		}
+		return nil, fmt.Errorf("failed to get postcard: %w", err)
+	}
+
+	if postcard.Type == "ai" {
+		if postcard.ReplyToID == nil {
+			return nil, errors.New("postcard is not a reply")
+		}
+		var replied models.Postcard
+		if err := s.db.Where("id = ? AND user_id = ?", *postcard.ReplyToID, userID).First(&replied).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errors.New("replied postcard not found")
+			}
+			return nil, fmt.Errorf("failed to get replied postcard: %w", err)
+		}
+		return &replied, nil
+	}
+
+	return &postcard, nil
+}
+
+// acquireReplyLock 获取明信片的回复生成锁，同一张明信片同时只允许一个生成任务
+func (s *PostcardService) acquireReplyLock(postcardID uint) error {
+	key := fmt.Sprintf("postcard_reply_lock:%d", postcardID)
+	locked, err := s.redis.SetNX(context.Background(), key, 1, streamReplyTimeout).Result()
+	if err != nil {
+		return fmt.Errorf("failed to acquire reply lock: %w", err)
+	}
+	if !locked {
+		return errors.New("reply is already being generated")
+	}
+	return nil
+}
+
+func (s *PostcardService) releaseReplyLock(postcardID uint) {
+	s.redis.Del(context.Background(), fmt.Sprintf("postcard_reply_lock:%d", postcardID))
+}
+
+// saveAIReply 保存对用户明信片的 AI 回复，并将其设为选定的回复
+func (s *PostcardService) saveAIReply(postcard *models.Postcard, rc *ReplyContext, reply string) (*models.Postcard, error) {
 	aiPostcard := models.Postcard{
-		ConversationID: conversationID,
-		UserID:         userID, // 这里可能需要调整，或者创建一个系统用户
-		CharacterID:    characterID,
-		Type:           "ai",
-		Content:        reply,
-		Status:         "sent",
+		ConversationID:   postcard.ConversationID,
+		UserID:           postcard.UserID, // 这里可能需要调整，或者创建一个系统用户
+		CharacterID:      postcard.CharacterID,
+		Type:             "ai",
+		ReplyToID:        &postcard.ID,
+		Content:          reply,
+		Status:           "sent",
+		RecalledMemories: rc.RecalledMemories(),
+	}
+
+	// 新回复成为选定的回复，同一明信片的其他回复变为备选
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&aiPostcard).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Postcard{}).
+			Where("reply_to_id = ? AND id <> ?", postcard.ID, aiPostcard.ID).
+			UpdateColumn("is_canonical", false).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AI reply: %w", err)
+	}
+
+	s.onAIReplySaved(postcard, &aiPostcard, rc.Character.Name)
+
+	return &aiPostcard, nil
+}
+
+// onAIReplySaved AI 回复保存后的处理：通知用户并推送 Webhook
+// 本服务保存的回复和 AI 助手通过 MQ 保存的回复都经过这里
+func (s *PostcardService) onAIReplySaved(postcard, aiPostcard *models.Postcard, characterName string) {
+	s.notifier.Notify(NotificationEvent{
+		UserID: postcard.UserID,
+		Type:   NotificationAIReply,
+		Title:  fmt.Sprintf("%s 给你回信了", characterName),
+		Body:   aiPostcard.Content,
+		Payload: map[string]interface{}{
+			"conversation_id": postcard.ConversationID,
+			"postcard_id":     aiPostcard.ID,
+			"reply_to_id":     postcard.ID,
+			"character_id":    postcard.CharacterID,
+		},
+	})
+
+	go s.webhookService.Publish(postcard.UserID, WebhookPostcardAIReplied, *aiPostcard)
+}
+
+// canonicalHistory 按对话顺序获取对话中选定的明信片
+// AI 回复排在所回复的明信片之后，后来重新生成并选定的回复也是如此；before 不为空时只返回排在它之前的明信片
+func canonicalHistory(db *gorm.DB, conversationID string, before *models.Postcard) ([]models.Postcard, error) {
+	query := db.Model(&models.Postcard{}).
+		Select("postcards.*").
+		Joins("LEFT JOIN postcards AS parent ON parent.id = postcards.reply_to_id").
+		Where("postcards.conversation_id = ? AND postcards.is_canonical = ?", conversationID, true)
+
+	if before != nil {
+		// 位置从数据库读取，与库中保存的时间精度一致
+		anchorID := before.ID
+		if before.ReplyToID != nil {
+			anchorID = *before.ReplyToID
+		}
+		var anchor models.Postcard
+		if err := db.Unscoped().Select("id", "created_at").First(&anchor, anchorID).Error; err != nil {
+			return nil, fmt.Errorf("failed to get postcard position: %w", err)
+		}
+		query = query.Where("(COALESCE(parent.created_at, postcards.created_at), COALESCE(parent.id, postcards.id), postcards.id) < (?, ?, ?)",
+			anchor.CreatedAt, anchor.ID, before.ID)
+	}
+
+	var history []models.Postcard
+	if err := query.Order("COALESCE(parent.created_at, postcards.created_at) ASC, COALESCE(parent.id, postcards.id) ASC, postcards.id ASC").
+		Find(&history).Error; err != nil {
+		return nil, fmt.Errorf("failed to get conversation history: %w", err)
+	}
+	return history, nil
+}
+
+// buildReplyContext 收集回复用户明信片所需的上下文：角色、对话历史、用户人设、世界设定和回忆
+func (s *PostcardService) buildReplyContext(postcard *models.Postcard) (*ReplyContext, error) {
+	// 获取角色信息
+	var character models.Character
+	if err := s.db.First(&character, postcard.CharacterID).Error; err != nil {
+		return nil, fmt.Errorf("failed to get character: %w", err)
+	}
+	// 被管理员隐藏的角色不再回信
+	if character.HiddenAt != nil {
+		return nil, errors.New("character not found")
 	}
 
-	s.db.Create(&aiPostcard)
+	// 获取对话历史（该明信片之前选定的明信片，不包括被替换的备选回复）
+	history, err := canonicalHistory(s.db, postcard.ConversationID, postcard)
+	if err != nil {
+		return nil, err
+	}
+
+	// 获取用户人设
+	persona, err := s.personaService.ResolvePersona(postcard.UserID, &character)
+	if err != nil {
+		return nil, err
+	}
+
+	// 获取触发的世界设定
+	lore, err := s.lorebookService.SelectEntries(&character, history, postcard.Content)
+	if err != nil {
+		log.Printf("Failed to select lorebook entries: %v", err)
+	}
+
+	// 回忆相关的过往明信片，当前明信片和已在上下文中的近期对话不重复回忆
+	// 分支对话中的明信片是副本，同时排除对应的原明信片
+	var recentIDs []uint
+	excludePostcard := func(p *models.Postcard) {
+		recentIDs = append(recentIDs, p.ID)
+		if p.CopiedFromID != nil {
+			recentIDs = append(recentIDs, *p.CopiedFromID)
+		}
+	}
+	excludePostcard(postcard)
+	for _, p := range recentHistory(history) {
+		excludePostcard(&p)
+	}
+	memories, err := s.memoryService.Recall(postcard.UserID, postcard.CharacterID, postcard.Content, recentIDs)
+	if err != nil {
+		log.Printf("Failed to recall memories: %v", err)
+	}
+
+	return &ReplyContext{
+		Character:   &character,
+		Persona:     persona,
+		Lore:        lore,
+		Memories:    memories,
+		History:     history,
+		UserMessage: postcard.Content,
+	}, nil
 }
@@ -0,0 +1,528 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"log"
+	"memory-postcard-backend/internal/models"
+	"memory-postcard-backend/internal/utils"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"golang.org/x/image/font"
+	"gorm.io/gorm"
+)
+
+const (
+	// 明信片成品尺寸（A6 横版）与印刷出血、裁切线留白，单位毫米
+	printTrimWidthMM  = 148.0
+	printTrimHeightMM = 105.0
+	printBleedMM      = 3.0
+	printSlugMM       = 12.0 // 出血外放置裁切线的留白
+	printCropMarkMM   = 5.0
+
+	// 纪念册页面为 A5 竖版，正好上下放下一张明信片的正反面
+	bookletWidth  = renderWidth
+	bookletHeight = renderHeight * 2
+
+	maxBookletPostcards = 300
+	printWorkers        = 2
+)
+
+type PrintService struct {
+	db                  *gorm.DB
+	render              *RenderService
+	upload              *UploadService
+	conversationService *ConversationService
+	notifier            Notifier
+	workers             chan struct{}
+}
+
+func NewPrintService(db *gorm.DB, renderService *RenderService, uploadService *UploadService, conversationService *ConversationService, notifier Notifier) *PrintService {
+	return &PrintService{
+		db:                  db,
+		render:              renderService,
+		upload:              uploadService,
+		conversationService: conversationService,
+		notifier:            notifier,
+		workers:             make(chan struct{}, printWorkers),
+	}
+}
+
+// CreatePostcardPrint 创建单张明信片的印刷导出任务
+func (s *PrintService) CreatePostcardPrint(postcardID, userID uint) (*models.PrintJob, error) {
+	var count int64
+	if err := s.db.Model(&models.Postcard{}).Where("id = ? AND user_id = ?", postcardID, userID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to get postcard: %w", err)
+	}
+	if count == 0 {
+		return nil, errors.New("postcard not found")
+	}
+
+	return s.createJob(&models.PrintJob{
+		UserID:     userID,
+		Kind:       "postcard",
+		PostcardID: &postcardID,
+	})
+}
+
+// CreateBookletPrint 创建整段对话纪念册的印刷导出任务
+func (s *PrintService) CreateBookletPrint(conversationID string, userID uint) (*models.PrintJob, error) {
+	conversation, err := s.conversationService.getOwnConversation(conversationID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var count int64
+	if err := s.db.Model(&models.Postcard{}).
+		Where("conversation_id = ? AND user_id = ? AND is_canonical = ?", conversation.ID, userID, true).
+		Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to count postcards: %w", err)
+	}
+	if count == 0 {
+		return nil, errors.New("conversation has no postcards")
+	}
+	if count > maxBookletPostcards {
+		return nil, fmt.Errorf("conversation too long to print, maximum %d postcards", maxBookletPostcards)
+	}
+
+	return s.createJob(&models.PrintJob{
+		UserID:         userID,
+		Kind:           "booklet",
+		ConversationID: conversation.ID,
+	})
+}
+
+// GetPrintJob 获取导出任务状态
+func (s *PrintService) GetPrintJob(id, userID uint) (*models.PrintJob, error) {
+	var job models.PrintJob
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&job).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("print job not found")
+		}
+		return nil, fmt.Errorf("failed to get print job: %w", err)
+	}
+	return &job, nil
+}
+
+// ResumePendingJobs 服务重启后继续处理未完成的任务
+func (s *PrintService) ResumePendingJobs() {
+	var ids []uint
+	if err := s.db.Model(&models.PrintJob{}).Where("status IN ?", []string{"pending", "processing"}).Pluck("id", &ids).Error; err != nil {
+		log.Printf("Failed to load pending print jobs: %v", err)
+		return
+	}
+	for _, id := range ids {
+		go s.process(id)
+	}
+}
+
+func (s *PrintService) createJob(job *models.PrintJob) (*models.PrintJob, error) {
+	job.Status = "pending"
+	if err := s.db.Create(job).Error; err != nil {
+		return nil, fmt.Errorf("failed to create print job: %w", err)
+	}
+	go s.process(job.ID)
+	return job, nil
+}
+
+// process 生成 PDF 并上传，同时运行的任务数受 printWorkers 限制
+func (s *PrintService) process(jobID uint) {
+	s.workers <- struct{}{}
+	defer func() { <-s.workers }()
+
+	var job models.PrintJob
+	if err := s.db.First(&job, jobID).Error; err != nil {
+		log.Printf("Failed to load print job %d: %v", jobID, err)
+		return
+	}
+	if err := s.db.Model(&job).Update("status", "processing").Error; err != nil {
+		log.Printf("Failed to update print job %d: %v", jobID, err)
+		return
+	}
+
+	url, pages, err := s.generate(&job)
+	now := time.Now()
+	updates := map[string]interface{}{"completed_at": &now}
+	if err != nil {
+		log.Printf("Print job %d failed: %v", jobID, err)
+		message := err.Error()
+		if utf8.RuneCountInString(message) > 500 {
+			message = string([]rune(message)[:500])
+		}
+		updates["status"] = "failed"
+		updates["error"] = message
+	} else {
+		updates["status"] = "completed"
+		updates["file_url"] = url
+		updates["page_count"] = pages
+		updates["error"] = ""
+	}
+	if err := s.db.Model(&job).Updates(updates).Error; err != nil {
+		log.Printf("Failed to update print job %d: %v", jobID, err)
+		return
+	}
+
+	event := NotificationEvent{
+		UserID: job.UserID,
+		Type:   NotificationExportReady,
+		Title:  "印刷文件已生成",
+		Payload: map[string]interface{}{
+			"print_job_id": job.ID,
+			"kind":         job.Kind,
+		},
+	}
+	if err != nil {
+		event.Type = NotificationExportFailed
+		event.Title = "印刷文件生成失败"
+		event.Body = updates["error"].(string)
+	} else {
+		event.Payload["file_url"] = url
+	}
+	s.notifier.Notify(event)
+}
+
+// generate 将 PDF 写入临时文件后上传，返回访问 URL 和页数
+func (s *PrintService) generate(job *models.PrintJob) (string, int, error) {
+	file, err := os.CreateTemp("", "print-*.pdf")
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	pdf, err := utils.NewPDFWriter(file)
+	if err != nil {
+		return "", 0, err
+	}
+
+	var title string
+	switch job.Kind {
+	case "postcard":
+		title, err = s.writePostcard(pdf, job)
+	case "booklet":
+		title, err = s.writeBooklet(pdf, job)
+	default:
+		err = fmt.Errorf("unknown print job kind: %s", job.Kind)
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	if err := pdf.Close(title); err != nil {
+		return "", 0, fmt.Errorf("failed to write pdf: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return "", 0, fmt.Errorf("failed to write pdf: %w", err)
+	}
+
+	objectName := fmt.Sprintf("exports/print/%d/%s.pdf", job.UserID, uuid.New().String())
+	url, err := s.upload.PutFile(objectName, file.Name(), "application/pdf")
+	if err != nil {
+		return "", 0, err
+	}
+	return url, pdf.PageCount(), nil
+}
+
+// writePostcard 单张明信片：正反两页，带 3mm 出血和裁切线
+func (s *PrintService) writePostcard(pdf *utils.PDFWriter, job *models.PrintJob) (string, error) {
+	var postcard models.Postcard
+	if err := s.db.Preload("User").Preload("Character").
+		Where("id = ? AND user_id = ?", job.PostcardID, job.UserID).
+		First(&postcard).Error; err != nil {
+		return "", fmt.Errorf("failed to get postcard: %w", err)
+	}
+	template := s.render.templates.GetTemplateForRender(postcard.PostcardTemplate)
+
+	mmPerPx := printTrimWidthMM / renderWidth
+	bleedPx := int(printBleedMM/mmPerPx + 0.5)
+
+	front, err := s.render.drawFront(&postcard, template, bleedPx)
+	if err != nil {
+		return "", err
+	}
+	back, err := s.render.drawBack(&postcard, template, bleedPx)
+	if err != nil {
+		return "", err
+	}
+
+	for _, img := range []*image.RGBA{front, back} {
+		page, err := printPostcardPage(img, bleedPx, mmPerPx)
+		if err != nil {
+			return "", err
+		}
+		if err := pdf.AddPage(page); err != nil {
+			return "", fmt.Errorf("failed to write pdf: %w", err)
+		}
+	}
+
+	return fmt.Sprintf("明信片 No.%06d", postcard.ID), nil
+}
+
+// printPostcardPage 生成带出血的明信片页面：页面四周留白处画出裁切线
+func printPostcardPage(img *image.RGBA, bleedPx int, mmPerPx float64) (utils.PDFPage, error) {
+	data, _, err := utils.EncodeImage(img, "jpeg", renderJPEGQuality)
+	if err != nil {
+		return utils.PDFPage{}, err
+	}
+
+	trim := utils.PDFBox{
+		X: utils.MMToPt(printSlugMM),
+		Y: utils.MMToPt(printSlugMM),
+		W: utils.MMToPt(printTrimWidthMM),
+		H: utils.MMToPt(printTrimHeightMM),
+	}
+	bleed := utils.MMToPt(float64(bleedPx) * mmPerPx)
+	bleedBox := utils.PDFBox{X: trim.X - bleed, Y: trim.Y - bleed, W: trim.W + 2*bleed, H: trim.H + 2*bleed}
+
+	page := utils.PDFPage{
+		Width:    trim.W + 2*utils.MMToPt(printSlugMM),
+		Height:   trim.H + 2*utils.MMToPt(printSlugMM),
+		TrimBox:  &trim,
+		BleedBox: &bleedBox,
+		Images: []utils.PDFImage{{
+			JPEG:   data,
+			Width:  img.Bounds().Dx(),
+			Height: img.Bounds().Dy(),
+			Box:    bleedBox,
+		}},
+	}
+
+	// 裁切线：在成品四角外侧、出血范围以外各画一横一竖
+	gap := bleed + utils.MMToPt(1)
+	length := utils.MMToPt(printCropMarkMM)
+	for _, x := range []float64{trim.X, trim.X + trim.W} {
+		for _, y := range []float64{trim.Y, trim.Y + trim.H} {
+			dx, dy := -1.0, -1.0
+			if x > trim.X {
+				dx = 1
+			}
+			if y > trim.Y {
+				dy = 1
+			}
+			page.Lines = append(page.Lines,
+				utils.PDFLine{X1: x + dx*gap, Y1: y, X2: x + dx*(gap+length), Y2: y, Width: 0.25},
+				utils.PDFLine{X1: x, Y1: y + dy*gap, X2: x, Y2: y + dy*(gap+length), Width: 0.25},
+			)
+		}
+	}
+	return page, nil
+}
+
+// bookletEntry 纪念册目录中的一项
+type bookletEntry struct {
+	postcard *models.Postcard
+	page     int // 从 0 开始的页码
+}
+
+// writeBooklet 对话纪念册：封面、目录，之后每页上下放一张明信片的正反面
+func (s *PrintService) writeBooklet(pdf *utils.PDFWriter, job *models.PrintJob) (string, error) {
+	var conversation models.Conversation
+	if err := s.db.Preload("Character").Where("id = ? AND user_id = ?", job.ConversationID, job.UserID).First(&conversation).Error; err != nil {
+		return "", fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	var postcards []models.Postcard
+	if err := s.db.Preload("User").Preload("Character").
+		Where("conversation_id = ? AND user_id = ? AND is_canonical = ?", conversation.ID, job.UserID, true).
+		Order("created_at ASC, id ASC").
+		Limit(maxBookletPostcards).
+		Find(&postcards).Error; err != nil {
+		return "", fmt.Errorf("failed to get postcards: %w", err)
+	}
+	if len(postcards) == 0 {
+		return "", errors.New("conversation has no postcards")
+	}
+
+	title := conversation.Title
+	if title == "" && conversation.Character != nil {
+		title = fmt.Sprintf("我与%s的明信片", conversation.Character.Name)
+	}
+
+	pageSize := utils.PDFBox{W: utils.MMToPt(printTrimWidthMM), H: utils.MMToPt(printTrimHeightMM * 2)}
+	addImagePage := func(img *image.RGBA, links []utils.PDFLink) error {
+		data, _, err := utils.EncodeImage(img, "jpeg", renderJPEGQuality)
+		if err != nil {
+			return err
+		}
+		return pdf.AddPage(utils.PDFPage{
+			Width:  pageSize.W,
+			Height: pageSize.H,
+			Images: []utils.PDFImage{{JPEG: data, Width: img.Bounds().Dx(), Height: img.Bounds().Dy(), Box: pageSize}},
+			Links:  links,
+		})
+	}
+
+	// 封面
+	cover, err := s.drawBookletCover(title, &conversation, postcards)
+	if err != nil {
+		return "", err
+	}
+	if err := addImagePage(cover, nil); err != nil {
+		return "", err
+	}
+
+	// 目录：先确定页数，再计算每张明信片所在页
+	tocPages := (len(postcards) + bookletTOCEntriesPerPage - 1) / bookletTOCEntriesPerPage
+	entries := make([]bookletEntry, len(postcards))
+	for i := range postcards {
+		entries[i] = bookletEntry{postcard: &postcards[i], page: 1 + tocPages + i}
+	}
+	for i := 0; i < tocPages; i++ {
+		end := min((i+1)*bookletTOCEntriesPerPage, len(entries))
+		img, links, err := s.drawBookletTOC(entries[i*bookletTOCEntriesPerPage:end], i == 0, pageSize)
+		if err != nil {
+			return "", err
+		}
+		if err := addImagePage(img, links); err != nil {
+			return "", err
+		}
+	}
+
+	// 正文：上半页正面，下半页背面
+	templates := make(map[string]*models.PostcardTemplate)
+	for i := range postcards {
+		postcard := &postcards[i]
+		template, ok := templates[postcard.PostcardTemplate]
+		if !ok {
+			template = s.render.templates.GetTemplateForRender(postcard.PostcardTemplate)
+			templates[postcard.PostcardTemplate] = template
+		}
+
+		front, err := s.render.drawFront(postcard, template, 0)
+		if err != nil {
+			return "", err
+		}
+		back, err := s.render.drawBack(postcard, template, 0)
+		if err != nil {
+			return "", err
+		}
+
+		page := image.NewRGBA(image.Rect(0, 0, bookletWidth, bookletHeight))
+		draw.Draw(page, image.Rect(0, 0, renderWidth, renderHeight), front, front.Bounds().Min, draw.Src)
+		draw.Draw(page, image.Rect(0, renderHeight, renderWidth, bookletHeight), back, back.Bounds().Min, draw.Src)
+		if err := addImagePage(page, nil); err != nil {
+			return "", err
+		}
+	}
+
+	return title, nil
+}
+
+const (
+	bookletTOCTop            = 360
+	bookletTOCLineHeight     = 84
+	bookletTOCEntriesPerPage = 22
+	bookletMargin            = 140
+)
+
+// drawBookletCover 封面：角色头像、标题、日期范围和明信片数量
+func (s *PrintService) drawBookletCover(title string, conversation *models.Conversation, postcards []models.Postcard) (*image.RGBA, error) {
+	theme := defaultRenderTheme
+	canvas := image.NewRGBA(image.Rect(0, 0, bookletWidth, bookletHeight))
+	utils.FillRect(canvas, canvas.Bounds(), theme.Paper)
+	utils.StrokeRect(canvas, canvas.Bounds().Inset(60), 6, theme.Accent)
+
+	avatar := image.Rect(bookletWidth/2-260, 520, bookletWidth/2+260, 1040)
+	if conversation.Character != nil {
+		if img := s.render.loadImage(conversation.Character.AvatarURL); img != nil {
+			utils.DrawImageCover(canvas, avatar, img)
+		} else {
+			utils.FillRect(canvas, avatar, theme.Accent)
+		}
+	}
+	utils.StrokeRect(canvas, avatar.Inset(-16), 4, theme.Accent)
+
+	titleFace, err := utils.NewFontFace(s.render.font, 96)
+	if err != nil {
+		return nil, err
+	}
+	defer titleFace.Close()
+	subFace, err := utils.NewFontFace(s.render.font, 44)
+	if err != nil {
+		return nil, err
+	}
+	defer subFace.Close()
+
+	lines := utils.TruncateLines(titleFace, utils.WrapText(titleFace, title, bookletWidth-2*bookletMargin), 2, bookletWidth-2*bookletMargin)
+	y := 1300
+	for _, line := range lines {
+		utils.DrawText(canvas, titleFace, theme.Ink, (bookletWidth-utils.MeasureText(titleFace, line))/2, y, line)
+		y += 130
+	}
+
+	first := postcards[0].CreatedAt.Format("2006.01.02")
+	last := postcards[len(postcards)-1].CreatedAt.Format("2006.01.02")
+	for _, text := range []string{first + " — " + last, fmt.Sprintf("共 %d 张明信片", len(postcards))} {
+		y += 40
+		utils.DrawText(canvas, subFace, theme.Ink, (bookletWidth-utils.MeasureText(subFace, text))/2, y, text)
+		y += 40
+	}
+	return canvas, nil
+}
+
+// drawBookletTOC 目录页，每一项链接到对应明信片所在页
+func (s *PrintService) drawBookletTOC(entries []bookletEntry, first bool, pageSize utils.PDFBox) (*image.RGBA, []utils.PDFLink, error) {
+	theme := defaultRenderTheme
+	canvas := image.NewRGBA(image.Rect(0, 0, bookletWidth, bookletHeight))
+	utils.FillRect(canvas, canvas.Bounds(), theme.Paper)
+
+	faces := make([]font.Face, 0, 2)
+	defer func() {
+		for _, face := range faces {
+			face.Close()
+		}
+	}()
+	titleFace, err := utils.NewFontFace(s.render.font, 72)
+	if err != nil {
+		return nil, nil, err
+	}
+	faces = append(faces, titleFace)
+	entryFace, err := utils.NewFontFace(s.render.font, 38)
+	if err != nil {
+		return nil, nil, err
+	}
+	faces = append(faces, entryFace)
+
+	if first {
+		utils.DrawText(canvas, titleFace, theme.Ink, bookletMargin, 240, "目录")
+	}
+
+	// 像素坐标（原点左上）换算为 PDF 坐标（原点左下）
+	scale := pageSize.W / bookletWidth
+	links := make([]utils.PDFLink, 0, len(entries))
+	for i, entry := range entries {
+		y := bookletTOCTop + i*bookletTOCLineHeight
+		from, _ := postcardParties(entry.postcard)
+		pageNumber := fmt.Sprintf("%d", entry.page+1)
+		numberWidth := utils.MeasureText(entryFace, pageNumber)
+		textWidth := bookletWidth - 2*bookletMargin - numberWidth - 40
+
+		snippet := []rune(strings.Join(strings.Fields(entry.postcard.Content), " "))
+		if len(snippet) > 80 {
+			snippet = snippet[:80]
+		}
+		text := fmt.Sprintf("%s  %s：%s", entry.postcard.CreatedAt.Format("01.02"), from, string(snippet))
+		text = utils.TruncateText(entryFace, text, textWidth)
+
+		utils.DrawText(canvas, entryFace, theme.Ink, bookletMargin, y, text)
+		utils.DrawText(canvas, entryFace, theme.Accent, bookletWidth-bookletMargin-numberWidth, y, pageNumber)
+		utils.FillRect(canvas, image.Rect(bookletMargin, y+22, bookletWidth-bookletMargin, y+24), withAlpha(theme.Ink, 40))
+
+		top := y - bookletTOCLineHeight + 24
+		links = append(links, utils.PDFLink{
+			Box: utils.PDFBox{
+				X: float64(bookletMargin) * scale,
+				Y: pageSize.H - float64(y+24)*scale,
+				W: float64(bookletWidth-2*bookletMargin) * scale,
+				H: float64(y+24-top) * scale,
+			},
+			TargetPage: entry.page,
+		})
+	}
+	return canvas, links, nil
+}
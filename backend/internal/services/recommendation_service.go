@@ -0,0 +1,358 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"memory-postcard-backend/internal/models"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+const (
+	// 交互次数达到该值视为"经常聊天"，不再推荐
+	frequentInteractionThreshold = 5
+	// 协同过滤时最多参考的相似用户数
+	maxNeighborUsers = 500
+	// 标签相似度计算时参与比较的候选角色数
+	tagCandidatePoolSize = 200
+
+	recommendationCacheTTL = 10 * time.Minute
+)
+
+type RecommendationService struct {
+	db         *gorm.DB
+	redis      *redis.Client
+	popularity *PopularityService
+}
+
+func NewRecommendationService(db *gorm.DB, redis *redis.Client, popularity *PopularityService) *RecommendationService {
+	return &RecommendationService{
+		db:         db,
+		redis:      redis,
+		popularity: popularity,
+	}
+}
+
+// GetRecommendedCharacters 获取个性化推荐角色
+// 优先使用基于用户-角色关系的物品协同过滤，结果不足时依次使用标签相似度和热门角色补足
+func (s *RecommendationService) GetRecommendedCharacters(userID uint, limit int) ([]models.RecommendedCharacter, error) {
+	cacheKey := fmt.Sprintf("character_recommended:%d:%d", userID, limit)
+	if cached := s.getRecommendationsFromCache(cacheKey); cached != nil {
+		return cached, nil
+	}
+
+	var userRelations []models.UserCharacterRelation
+	if err := s.db.Where("user_id = ?", userID).Find(&userRelations).Error; err != nil {
+		return nil, fmt.Errorf("failed to get user relations: %w", err)
+	}
+
+	// 排除用户经常聊天、已收藏或自己创建的角色
+	excluded := make(map[uint]bool)
+	for _, relation := range userRelations {
+		if relation.InteractionCount >= frequentInteractionThreshold || relation.IsFavorite {
+			excluded[relation.CharacterID] = true
+		}
+	}
+	var ownIDs []uint
+	if err := s.db.Model(&models.Character{}).Where("creator_id = ?", userID).Pluck("id", &ownIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to get own characters: %w", err)
+	}
+	for _, id := range ownIDs {
+		excluded[id] = true
+	}
+
+	results := make([]models.RecommendedCharacter, 0, limit)
+	picked := make(map[uint]bool)
+	appendCharacters := func(characters []models.Character, scores map[uint]float64, reason string) {
+		for _, character := range characters {
+			if len(results) >= limit {
+				return
+			}
+			if picked[character.ID] || excluded[character.ID] {
+				continue
+			}
+			picked[character.ID] = true
+			results = append(results, models.RecommendedCharacter{
+				Character: character,
+				Score:     math.Round(scores[character.ID]*10000) / 10000,
+				Reason:    reason,
+			})
+		}
+	}
+
+	// 1. 物品协同过滤
+	if len(userRelations) > 0 {
+		scores, err := s.collaborativeScores(userID, userRelations)
+		if err != nil {
+			return nil, err
+		}
+		characters, err := s.loadRankedCharacters(scores, excluded)
+		if err != nil {
+			return nil, err
+		}
+		appendCharacters(characters, scores, "collaborative")
+	}
+
+	// 2. 冷启动：标签相似度
+	if len(results) < limit && len(userRelations) > 0 {
+		scores, err := s.tagScores(userRelations)
+		if err != nil {
+			return nil, err
+		}
+		characters, err := s.loadRankedCharacters(scores, excluded)
+		if err != nil {
+			return nil, err
+		}
+		appendCharacters(characters, scores, "tags")
+	}
+
+	// 3. 冷启动：热门角色
+	if len(results) < limit {
+		trending, err := s.popularity.GetTrendingCharacters(&models.TrendingQuery{Window: "week", Limit: 50})
+		if err != nil {
+			return nil, err
+		}
+		if len(trending) < limit {
+			allTime, err := s.popularity.GetTrendingCharacters(&models.TrendingQuery{Window: "all", Limit: 50})
+			if err != nil {
+				return nil, err
+			}
+			trending = append(trending, allTime...)
+		}
+		characters := make([]models.Character, len(trending))
+		scores := make(map[uint]float64, len(trending))
+		for i, t := range trending {
+			characters[i] = t.Character
+			if _, ok := scores[t.ID]; !ok {
+				scores[t.ID] = t.TrendingScore
+			}
+		}
+		appendCharacters(characters, scores, "trending")
+	}
+
+	s.cacheRecommendations(cacheKey, results)
+
+	return results, nil
+}
+
+// collaborativeScores 计算候选角色的协同过滤分数
+// 角色向量由与其有关系的用户权重构成，只在目标用户的近邻范围内计算余弦相似度
+func (s *RecommendationService) collaborativeScores(userID uint, userRelations []models.UserCharacterRelation) (map[uint]float64, error) {
+	seedIDs := make([]uint, len(userRelations))
+	for i, relation := range userRelations {
+		seedIDs[i] = relation.CharacterID
+	}
+
+	// 与目标用户有共同角色的近邻用户，优先取共同角色最多的用户
+	var neighborIDs []uint
+	if err := s.db.Model(&models.UserCharacterRelation{}).
+		Select("user_id").
+		Where("character_id IN ? AND user_id <> ?", seedIDs, userID).
+		Group("user_id").
+		Order("COUNT(*) DESC, user_id DESC").
+		Limit(maxNeighborUsers).
+		Pluck("user_id", &neighborIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to get neighbor users: %w", err)
+	}
+	if len(neighborIDs) == 0 {
+		return map[uint]float64{}, nil
+	}
+
+	var neighborRelations []models.UserCharacterRelation
+	if err := s.db.Where("user_id IN ?", neighborIDs).Find(&neighborRelations).Error; err != nil {
+		return nil, fmt.Errorf("failed to get neighbor relations: %w", err)
+	}
+
+	return itemBasedScores(userRelations, neighborRelations), nil
+}
+
+// tagScores 根据用户交互过的角色的标签画像，计算候选角色的标签相似度（Jaccard）
+func (s *RecommendationService) tagScores(userRelations []models.UserCharacterRelation) (map[uint]float64, error) {
+	seedIDs := make([]uint, len(userRelations))
+	for i, relation := range userRelations {
+		seedIDs[i] = relation.CharacterID
+	}
+
+	var seeds []models.Character
+	if err := s.db.Select("id", "tags").Where("id IN ?", seedIDs).Find(&seeds).Error; err != nil {
+		return nil, fmt.Errorf("failed to get seed characters: %w", err)
+	}
+
+	profile := make(map[string]bool)
+	for _, seed := range seeds {
+		for _, tag := range seed.Tags {
+			profile[normalizeTag(tag)] = true
+		}
+	}
+	if len(profile) == 0 {
+		return map[uint]float64{}, nil
+	}
+
+	var candidates []models.Character
+	if err := s.db.Select("id", "tags").
+		Where("visibility = ? AND is_active = ? AND hidden_at IS NULL", "public", true).
+		Order("popularity_score DESC").
+		Limit(tagCandidatePoolSize).
+		Find(&candidates).Error; err != nil {
+		return nil, fmt.Errorf("failed to get tag candidates: %w", err)
+	}
+
+	scores := make(map[uint]float64)
+	for _, candidate := range candidates {
+		if score := tagJaccard(profile, candidate.Tags); score > 0 {
+			scores[candidate.ID] = score
+		}
+	}
+
+	return scores, nil
+}
+
+// loadRankedCharacters 按分数从高到低加载可推荐的公开角色
+func (s *RecommendationService) loadRankedCharacters(scores map[uint]float64, excluded map[uint]bool) ([]models.Character, error) {
+	ids := make([]uint, 0, len(scores))
+	for id := range scores {
+		if !excluded[id] {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var characters []models.Character
+	if err := s.db.Preload("Creator").
+		Where("id IN ? AND visibility = ? AND is_active = ? AND hidden_at IS NULL", ids, "public", true).
+		Find(&characters).Error; err != nil {
+		return nil, fmt.Errorf("failed to get recommended characters: %w", err)
+	}
+
+	sort.SliceStable(characters, func(i, j int) bool {
+		if scores[characters[i].ID] != scores[characters[j].ID] {
+			return scores[characters[i].ID] > scores[characters[j].ID]
+		}
+		return characters[i].PopularityScore > characters[j].PopularityScore
+	})
+
+	return characters, nil
+}
+
+// relationWeight 用户对角色的隐式评分：交互次数取对数，收藏额外加分
+func relationWeight(relation models.UserCharacterRelation) float64 {
+	weight := math.Log1p(float64(relation.InteractionCount))
+	if relation.IsFavorite {
+		weight += 1
+	}
+	return weight
+}
+
+// itemBasedScores 物品协同过滤打分
+// score(c) = Σ_seed w(user, seed) * cos(seed, c)，其中角色向量为各用户对该角色的权重
+func itemBasedScores(userRelations, neighborRelations []models.UserCharacterRelation) map[uint]float64 {
+	vectors := make(map[uint]map[uint]float64)
+	addRelation := func(relation models.UserCharacterRelation) {
+		weight := relationWeight(relation)
+		if weight == 0 {
+			return
+		}
+		if vectors[relation.CharacterID] == nil {
+			vectors[relation.CharacterID] = make(map[uint]float64)
+		}
+		vectors[relation.CharacterID][relation.UserID] = weight
+	}
+	for _, relation := range userRelations {
+		addRelation(relation)
+	}
+	for _, relation := range neighborRelations {
+		addRelation(relation)
+	}
+
+	norms := make(map[uint]float64, len(vectors))
+	for characterID, vector := range vectors {
+		var sum float64
+		for _, weight := range vector {
+			sum += weight * weight
+		}
+		norms[characterID] = math.Sqrt(sum)
+	}
+
+	seeds := make(map[uint]float64, len(userRelations))
+	for _, relation := range userRelations {
+		if weight := relationWeight(relation); weight > 0 {
+			seeds[relation.CharacterID] = weight
+		}
+	}
+
+	scores := make(map[uint]float64)
+	for candidateID, candidate := range vectors {
+		if _, isSeed := seeds[candidateID]; isSeed {
+			continue
+		}
+		for seedID, seedWeight := range seeds {
+			seed := vectors[seedID]
+			var dot float64
+			for userID, weight := range candidate {
+				dot += weight * seed[userID]
+			}
+			if dot == 0 || norms[candidateID] == 0 || norms[seedID] == 0 {
+				continue
+			}
+			scores[candidateID] += seedWeight * dot / (norms[candidateID] * norms[seedID])
+		}
+	}
+
+	return scores
+}
+
+// tagJaccard 计算标签集合的 Jaccard 相似度
+func tagJaccard(profile map[string]bool, tags []string) float64 {
+	if len(profile) == 0 || len(tags) == 0 {
+		return 0
+	}
+
+	set := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		set[normalizeTag(tag)] = true
+	}
+
+	intersection := 0
+	for tag := range set {
+		if profile[tag] {
+			intersection++
+		}
+	}
+	union := len(profile) + len(set) - intersection
+
+	return float64(intersection) / float64(union)
+}
+
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// 缓存相关方法
+func (s *RecommendationService) cacheRecommendations(key string, recommendations []models.RecommendedCharacter) {
+	ctx := context.Background()
+	data, _ := json.Marshal(recommendations)
+	s.redis.Set(ctx, key, data, recommendationCacheTTL)
+}
+
+func (s *RecommendationService) getRecommendationsFromCache(key string) []models.RecommendedCharacter {
+	ctx := context.Background()
+	data, err := s.redis.Get(ctx, key).Result()
+	if err != nil {
+		return nil
+	}
+
+	var recommendations []models.RecommendedCharacter
+	if err := json.Unmarshal([]byte(data), &recommendations); err != nil {
+		return nil
+	}
+
+	return recommendations
+}
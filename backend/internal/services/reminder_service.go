@@ -0,0 +1,394 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"memory-postcard-backend/internal/models"
+	"slices"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	maxRemindersPerUser = 10
+
+	// reminderStaleAfter 到期超过该时长的提醒（如服务停机期间错过的）不再发送，只安排下一次
+	reminderStaleAfter = time.Hour
+	// reminderBatchSize 每轮最多处理的到期提醒数
+	reminderBatchSize = 500
+
+	// 角色主动问候只在用户当地时间的白天寄出
+	checkInStartHour = 9
+	checkInEndHour   = 21
+)
+
+type ReminderService struct {
+	db              *gorm.DB
+	userService     *UserService
+	postcardService *PostcardService
+	notifier        Notifier
+	mailer          Mailer
+}
+
+func NewReminderService(db *gorm.DB, userService *UserService, postcardService *PostcardService, notifier Notifier, mailer Mailer) *ReminderService {
+	return &ReminderService{
+		db:              db,
+		userService:     userService,
+		postcardService: postcardService,
+		notifier:        notifier,
+		mailer:          mailer,
+	}
+}
+
+// GetStreak 计算用户连续写明信片的天数
+func (s *ReminderService) GetStreak(userID uint) (*models.StreakResponse, error) {
+	loc := s.userService.GetLocation(userID)
+
+	var times []time.Time
+	if err := s.db.Model(&models.Postcard{}).
+		Where("user_id = ? AND type = ?", userID, "user").
+		Pluck("created_at", &times).Error; err != nil {
+		return nil, fmt.Errorf("failed to get postcards: %w", err)
+	}
+
+	written := make(map[string]bool)
+	for _, t := range times {
+		written[t.In(loc).Format(timelineDateLayout)] = true
+	}
+	days := make([]string, 0, len(written))
+	for day := range written {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+
+	resp := &models.StreakResponse{
+		TotalDays: len(days),
+		TimeZone:  loc.String(),
+	}
+	if len(days) == 0 {
+		return resp, nil
+	}
+	resp.LastWrittenDate = days[len(days)-1]
+
+	run := 0
+	var prev time.Time
+	for _, day := range days {
+		date, _ := time.ParseInLocation(timelineDateLayout, day, loc)
+		if run > 0 && prev.AddDate(0, 0, 1).Equal(date) {
+			run++
+		} else {
+			run = 1
+		}
+		resp.LongestStreak = max(resp.LongestStreak, run)
+		prev = date
+	}
+
+	// 今天还没写时，连续记录截至昨天仍然有效
+	now := time.Now().In(loc)
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	resp.WrittenToday = written[day.Format(timelineDateLayout)]
+	if !resp.WrittenToday {
+		day = day.AddDate(0, 0, -1)
+	}
+	for written[day.Format(timelineDateLayout)] {
+		resp.CurrentStreak++
+		day = day.AddDate(0, 0, -1)
+	}
+
+	return resp, nil
+}
+
+// ListReminders 获取用户的提醒
+func (s *ReminderService) ListReminders(userID uint) ([]models.ReminderSchedule, error) {
+	var reminders []models.ReminderSchedule
+	if err := s.db.Where("user_id = ?", userID).Order("time_of_day ASC, id ASC").Find(&reminders).Error; err != nil {
+		return nil, fmt.Errorf("failed to get reminders: %w", err)
+	}
+	return reminders, nil
+}
+
+// CreateReminder 创建提醒
+func (s *ReminderService) CreateReminder(userID uint, req *models.ReminderCreateRequest) (*models.ReminderSchedule, error) {
+	var count int64
+	if err := s.db.Model(&models.ReminderSchedule{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to count reminders: %w", err)
+	}
+	if count >= maxRemindersPerUser {
+		return nil, errors.New("too many reminders")
+	}
+
+	reminder := &models.ReminderSchedule{
+		UserID:        userID,
+		TimeOfDay:     req.TimeOfDay,
+		DaysOfWeek:    normalizeDaysOfWeek(req.DaysOfWeek),
+		Message:       req.Message,
+		Email:         req.Email,
+		SkipIfWritten: req.SkipIfWritten == nil || *req.SkipIfWritten,
+		Enabled:       req.Enabled == nil || *req.Enabled,
+	}
+	reminder.NextRunAt = nextReminderRun(reminder, s.userService.GetLocation(userID), time.Now())
+
+	if err := s.db.Create(reminder).Error; err != nil {
+		return nil, fmt.Errorf("failed to create reminder: %w", err)
+	}
+	return reminder, nil
+}
+
+// UpdateReminder 更新提醒，并按新的设置重新计算下次提醒时间
+func (s *ReminderService) UpdateReminder(id, userID uint, req *models.ReminderUpdateRequest) (*models.ReminderSchedule, error) {
+	reminder, err := s.getOwnReminder(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.TimeOfDay != nil {
+		reminder.TimeOfDay = *req.TimeOfDay
+	}
+	if req.DaysOfWeek != nil {
+		reminder.DaysOfWeek = normalizeDaysOfWeek(req.DaysOfWeek)
+	}
+	if req.Message != nil {
+		reminder.Message = *req.Message
+	}
+	if req.Email != nil {
+		reminder.Email = *req.Email
+	}
+	if req.SkipIfWritten != nil {
+		reminder.SkipIfWritten = *req.SkipIfWritten
+	}
+	if req.Enabled != nil {
+		reminder.Enabled = *req.Enabled
+	}
+	reminder.NextRunAt = nextReminderRun(reminder, s.userService.GetLocation(userID), time.Now())
+
+	if err := s.db.Save(reminder).Error; err != nil {
+		return nil, fmt.Errorf("failed to update reminder: %w", err)
+	}
+	return reminder, nil
+}
+
+// DeleteReminder 删除提醒
+func (s *ReminderService) DeleteReminder(id, userID uint) error {
+	reminder, err := s.getOwnReminder(id, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Delete(reminder).Error; err != nil {
+		return fmt.Errorf("failed to delete reminder: %w", err)
+	}
+	return nil
+}
+
+func (s *ReminderService) getOwnReminder(id, userID uint) (*models.ReminderSchedule, error) {
+	var reminder models.ReminderSchedule
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&reminder).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("reminder not found")
+		}
+		return nil, fmt.Errorf("failed to get reminder: %w", err)
+	}
+	return &reminder, nil
+}
+
+// normalizeDaysOfWeek 去重并排序
+func normalizeDaysOfWeek(days []int) []int {
+	result := slices.Clone(days)
+	slices.Sort(result)
+	return slices.Compact(result)
+}
+
+// nextReminderRun 计算 after 之后的下一次提醒时间，提醒关闭时返回 nil
+func nextReminderRun(reminder *models.ReminderSchedule, loc *time.Location, after time.Time) *time.Time {
+	if !reminder.Enabled || len(reminder.DaysOfWeek) == 0 {
+		return nil
+	}
+	clock, err := time.Parse("15:04", reminder.TimeOfDay)
+	if err != nil {
+		return nil
+	}
+
+	local := after.In(loc)
+	for i := 0; i <= 7; i++ {
+		run := time.Date(local.Year(), local.Month(), local.Day()+i, clock.Hour(), clock.Minute(), 0, 0, loc)
+		if run.After(after) && slices.Contains(reminder.DaysOfWeek, int(run.Weekday())) {
+			return &run
+		}
+	}
+	return nil
+}
+
+// StartReminderJobs 启动提醒和角色主动问候的定时任务
+func (s *ReminderService) StartReminderJobs(ctx context.Context, reminderInterval, checkInInterval time.Duration) {
+	runEvery := func(interval time.Duration, job func()) {
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					job()
+				}
+			}
+		}()
+	}
+
+	runEvery(reminderInterval, s.RunDueReminders)
+	runEvery(checkInInterval, s.RunCheckIns)
+}
+
+// RunDueReminders 发送所有到期的提醒
+func (s *ReminderService) RunDueReminders() {
+	now := time.Now()
+
+	var due []models.ReminderSchedule
+	if err := s.db.Where("enabled = ? AND next_run_at <= ?", true, now).
+		Order("next_run_at ASC").
+		Limit(reminderBatchSize).
+		Find(&due).Error; err != nil {
+		log.Printf("Failed to get due reminders: %v", err)
+		return
+	}
+
+	for i := range due {
+		s.deliverReminder(&due[i], now)
+	}
+}
+
+// deliverReminder 安排下一次提醒并发送本次提醒
+func (s *ReminderService) deliverReminder(reminder *models.ReminderSchedule, now time.Time) {
+	scheduled := *reminder.NextRunAt
+	next := nextReminderRun(reminder, s.userService.GetLocation(reminder.UserID), now)
+
+	// 多个实例同时运行时，只有成功更新下次提醒时间的实例发送本次提醒
+	result := s.db.Model(&models.ReminderSchedule{}).
+		Where("id = ? AND next_run_at = ?", reminder.ID, scheduled).
+		UpdateColumn("next_run_at", next)
+	if result.Error != nil {
+		log.Printf("Failed to schedule reminder %d: %v", reminder.ID, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 || now.Sub(scheduled) > reminderStaleAfter {
+		return
+	}
+
+	streak, err := s.GetStreak(reminder.UserID)
+	if err != nil {
+		log.Printf("Failed to get streak for reminder %d: %v", reminder.ID, err)
+		return
+	}
+	if reminder.SkipIfWritten && streak.WrittenToday {
+		return
+	}
+
+	title := "今天也写一张明信片吧"
+	body := reminder.Message
+	if body == "" {
+		if streak.CurrentStreak > 0 {
+			body = fmt.Sprintf("你已经连续 %d 天写明信片了，今天也来记下此刻的心情吧。", streak.CurrentStreak)
+		} else {
+			body = "花几分钟，给在意的角色写一张明信片，记下今天的心情吧。"
+		}
+	}
+
+	s.notifier.Notify(NotificationEvent{
+		UserID: reminder.UserID,
+		Type:   NotificationReminder,
+		Title:  title,
+		Body:   body,
+		Payload: map[string]interface{}{
+			"reminder_id":    reminder.ID,
+			"current_streak": streak.CurrentStreak,
+		},
+	})
+
+	if reminder.Email {
+		if user, err := s.userService.GetProfile(reminder.UserID); err != nil {
+			log.Printf("Failed to get user for reminder %d: %v", reminder.ID, err)
+		} else if err := s.mailer.Send(user.Email, title, body); err != nil {
+			log.Printf("Failed to email reminder %d: %v", reminder.ID, err)
+		}
+	}
+
+	s.db.Model(reminder).UpdateColumn("last_sent_at", now)
+}
+
+// checkInCandidate 开启了角色主动问候的对话及用户最后一次来信的时间
+type checkInCandidate struct {
+	ID                 string
+	UserID             uint
+	CharacterID        uint
+	CheckInDays        int
+	LastCheckInAt      *time.Time
+	LastUserPostcardAt time.Time
+}
+
+// RunCheckIns 为用户长时间没有来信的对话寄出角色的问候明信片，每段沉默只问候一次
+func (s *ReminderService) RunCheckIns() {
+	var candidates []checkInCandidate
+	if err := s.db.Table("conversations").
+		Select("conversations.id, conversations.user_id, conversations.character_id, conversations.check_in_days, conversations.last_check_in_at, MAX(postcards.created_at) AS last_user_postcard_at").
+		Joins("JOIN postcards ON postcards.conversation_id = conversations.id AND postcards.type = ? AND postcards.deleted_at IS NULL", "user").
+		Joins("JOIN characters ON characters.id = conversations.character_id AND characters.hidden_at IS NULL AND characters.deleted_at IS NULL").
+		Where("conversations.check_in_days > 0 AND conversations.is_archived = ?", false).
+		Group("conversations.id").
+		Scan(&candidates).Error; err != nil {
+		log.Printf("Failed to get check-in candidates: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for _, c := range candidates {
+		silence := now.Sub(c.LastUserPostcardAt)
+		if silence < time.Duration(c.CheckInDays)*24*time.Hour {
+			continue
+		}
+		if c.LastCheckInAt != nil && c.LastCheckInAt.After(c.LastUserPostcardAt) {
+			continue
+		}
+		if hour := now.In(s.userService.GetLocation(c.UserID)).Hour(); hour < checkInStartHour || hour >= checkInEndHour {
+			continue
+		}
+
+		// 多个实例同时运行时，只有成功记录问候时间的实例寄出明信片
+		claim := s.db.Model(&models.Conversation{}).Where("id = ?", c.ID)
+		if c.LastCheckInAt == nil {
+			claim = claim.Where("last_check_in_at IS NULL")
+		} else {
+			claim = claim.Where("last_check_in_at = ?", *c.LastCheckInAt)
+		}
+		result := claim.UpdateColumn("last_check_in_at", now)
+		if result.Error != nil {
+			log.Printf("Failed to claim check-in for conversation %s: %v", c.ID, result.Error)
+			continue
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		conversation := &models.Conversation{ID: c.ID, UserID: c.UserID, CharacterID: c.CharacterID}
+		postcard, err := s.postcardService.SendCheckIn(conversation, int(silence.Hours()/24))
+		if err != nil {
+			log.Printf("Failed to send check-in for conversation %s: %v", c.ID, err)
+			continue
+		}
+
+		s.notifier.Notify(NotificationEvent{
+			UserID: c.UserID,
+			Type:   NotificationCharacterCheckIn,
+			Title:  fmt.Sprintf("%s 给你寄来了一张明信片", postcard.Character.Name),
+			Body:   postcard.Content,
+			Payload: map[string]interface{}{
+				"conversation_id": c.ID,
+				"postcard_id":     postcard.ID,
+				"character_id":    c.CharacterID,
+			},
+		})
+	}
+}
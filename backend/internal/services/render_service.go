@@ -0,0 +1,511 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"memory-postcard-backend/config"
+	"memory-postcard-backend/internal/models"
+	"memory-postcard-backend/internal/utils"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	_ "golang.org/x/image/webp"
+	"gorm.io/gorm"
+)
+
+const (
+	// 明信片按 A6 横版、300 DPI 输出
+	renderWidth  = 1748
+	renderHeight = 1240
+
+	// 渲染版式变化时递增，使已缓存的图片失效
+	renderVersion = 2
+
+	renderImageMaxSize = 10 * 1024 * 1024
+	renderJPEGQuality  = 90
+)
+
+// renderTheme 明信片配色
+type renderTheme struct {
+	Paper  color.RGBA // 纸张底色
+	Ink    color.RGBA // 文字颜色
+	Accent color.RGBA // 分隔线、邮票边框等点缀色
+}
+
+// defaultRenderTheme 未指定模板或模板未设置颜色时使用的配色
+var defaultRenderTheme = renderTheme{
+	Paper:  color.RGBA{250, 246, 238, 255},
+	Ink:    color.RGBA{51, 45, 40, 255},
+	Accent: color.RGBA{176, 58, 46, 255},
+}
+
+// defaultTextRegions 模板未定义文字区域时背面的默认排版
+var defaultTextRegions = map[string]models.TemplateTextRegion{
+	"body":      {Name: "body", X: 100, Y: 140, Width: 853, Height: 910, FontSize: 44, Align: "left"},
+	"signature": {Name: "signature", X: 100, Y: 1060, Width: 853, Height: 60, FontSize: 34, Align: "right"},
+	"address":   {Name: "address", X: 1073, Y: 740, Width: 575, Height: 300, FontSize: 34, Align: "left"},
+}
+
+type RenderService struct {
+	db         *gorm.DB
+	upload     *UploadService
+	templates  *TemplateService
+	font       *opentype.Font
+	fontDir    string
+	httpClient *http.Client
+
+	fontsMu sync.Mutex
+	fonts   map[string]*opentype.Font // 已加载的模板字体，加载失败的记为 nil
+}
+
+func NewRenderService(db *gorm.DB, uploadService *UploadService, templateService *TemplateService, cfg *config.Config) *RenderService {
+	f, err := utils.LoadFont(cfg.RenderFontPath)
+	if err != nil {
+		log.Printf("Warning: Failed to load render font %q, falling back to built-in font: %v", cfg.RenderFontPath, err)
+		f, _ = utils.LoadFont("")
+	} else if cfg.RenderFontPath == "" {
+		log.Println("Warning: RENDER_FONT_PATH not set, CJK text in rendered postcards will not display correctly")
+	}
+
+	return &RenderService{
+		db:         db,
+		upload:     uploadService,
+		templates:  templateService,
+		font:       f,
+		fontDir:    cfg.RenderFontDir,
+		fonts:      make(map[string]*opentype.Font),
+		httpClient: newOutboundClient(10*time.Second, false), // 外部图片地址由用户提供，拒绝内网地址且不跟随重定向
+	}
+}
+
+// RenderPostcard 渲染明信片正反面图片，结果按内容哈希缓存在 MinIO 中
+func (s *RenderService) RenderPostcard(postcardID, userID uint, query *models.PostcardRenderQuery) (*models.PostcardRenderResponse, error) {
+	var postcard models.Postcard
+	if err := s.db.Preload("User").Preload("Character").
+		Where("id = ? AND user_id = ?", postcardID, userID).
+		First(&postcard).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("postcard not found")
+		}
+		return nil, fmt.Errorf("failed to get postcard: %w", err)
+	}
+
+	side := query.Side
+	if side == "" {
+		side = "both"
+	}
+	format := query.Format
+	if format == "" {
+		format = "png"
+	}
+
+	template := s.templates.GetTemplateForRender(postcard.PostcardTemplate)
+
+	resp := &models.PostcardRenderResponse{
+		PostcardID: postcard.ID,
+		Format:     format,
+	}
+
+	var err error
+	if side == "front" || side == "both" {
+		if resp.FrontURL, err = s.renderSide(&postcard, template, "front", format); err != nil {
+			return nil, err
+		}
+	}
+	if side == "back" || side == "both" {
+		if resp.BackURL, err = s.renderSide(&postcard, template, "back", format); err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
+// renderSide 渲染单面，命中缓存时直接返回已有图片
+func (s *RenderService) renderSide(postcard *models.Postcard, template *models.PostcardTemplate, side, format string) (string, error) {
+	ext := format
+	if format == "jpeg" {
+		ext = "jpg"
+	}
+	objectName := fmt.Sprintf("renders/postcards/%d/%s-%s.%s", postcard.ID, side, renderCacheKey(postcard, template), ext)
+
+	if _, err := s.upload.GetFileInfo(objectName); err == nil {
+		return s.upload.generateURL(objectName), nil
+	}
+
+	var img *image.RGBA
+	var err error
+	if side == "front" {
+		img, err = s.drawFront(postcard, template, 0)
+	} else {
+		img, err = s.drawBack(postcard, template, 0)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to render postcard: %w", err)
+	}
+
+	data, contentType, err := utils.EncodeImage(img, format, renderJPEGQuality)
+	if err != nil {
+		return "", err
+	}
+	return s.upload.PutBytes(objectName, data, contentType)
+}
+
+// templateFont 返回模板字体中第一个可以加载的字体，没有时使用默认字体
+func (s *RenderService) templateFont(template *models.PostcardTemplate) *opentype.Font {
+	if template == nil {
+		return s.font
+	}
+
+	s.fontsMu.Lock()
+	defer s.fontsMu.Unlock()
+	for _, name := range template.Fonts {
+		f, loaded := s.fonts[name]
+		if !loaded {
+			path, err := templateFontPath(s.fontDir, name)
+			if err == nil {
+				f, err = utils.LoadFont(path)
+			}
+			if err != nil {
+				log.Printf("Warning: Failed to load template font %q: %v", name, err)
+			}
+			s.fonts[name] = f
+		}
+		if f != nil {
+			return f
+		}
+	}
+	return s.font
+}
+
+// renderCacheKey 根据影响渲染结果的字段生成缓存键
+func renderCacheKey(postcard *models.Postcard, template *models.PostcardTemplate) string {
+	h := sha1.New()
+	if template != nil {
+		fmt.Fprintf(h, "%s|%d|", template.ID, template.UpdatedAt.UnixNano())
+	}
+	fmt.Fprintf(h, "%d|%d|%d|%s|%s|%s|%s|%s|%s|%s|%s",
+		renderVersion, postcard.ID, postcard.UpdatedAt.UnixNano(),
+		postcard.Content, postcard.ImageURL, postcard.AIGeneratedImageURL, postcard.PostcardTemplate,
+		postcard.Character.Name, postcard.Character.AvatarURL,
+		postcard.User.Nickname, postcard.User.Username)
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// postcardParties 返回明信片的寄件人与收件人名称
+func postcardParties(postcard *models.Postcard) (from, to string) {
+	userName := postcard.User.Nickname
+	if userName == "" {
+		userName = postcard.User.Username
+	}
+	if postcard.Type == "ai" {
+		return postcard.Character.Name, userName
+	}
+	return userName, postcard.Character.Name
+}
+
+// templateTheme 根据模板配色生成主题，未设置的颜色使用默认值
+func templateTheme(template *models.PostcardTemplate) renderTheme {
+	theme := defaultRenderTheme
+	if template == nil {
+		return theme
+	}
+	if c, err := utils.ParseHexColor(template.Layout.PaperColor); err == nil {
+		theme.Paper = c
+	}
+	if c, err := utils.ParseHexColor(template.Layout.InkColor); err == nil {
+		theme.Ink = c
+	}
+	if c, err := utils.ParseHexColor(template.Layout.AccentColor); err == nil {
+		theme.Accent = c
+	}
+	return theme
+}
+
+// textRegion 返回模板中指定名称的文字区域，未定义时使用默认排版
+func textRegion(template *models.PostcardTemplate, name string) models.TemplateTextRegion {
+	region := defaultTextRegions[name]
+	if template == nil {
+		return region
+	}
+	for _, r := range template.TextRegions {
+		if r.Name == name {
+			if r.FontSize == 0 {
+				r.FontSize = region.FontSize
+			}
+			return r
+		}
+	}
+	return region
+}
+
+// regionColor 文字区域的颜色，未设置时使用主题文字颜色
+func regionColor(region models.TemplateTextRegion, theme renderTheme) color.RGBA {
+	if c, err := utils.ParseHexColor(region.Color); err == nil {
+		return c
+	}
+	return theme.Ink
+}
+
+// alignedX 按对齐方式计算一行文本在区域内的起点
+func alignedX(region models.TemplateTextRegion, width int) int {
+	switch region.Align {
+	case "center":
+		return region.X + (region.Width-width)/2
+	case "right":
+		return region.X + region.Width - width
+	default:
+		return region.X
+	}
+}
+
+func withAlpha(c color.RGBA, a uint8) color.NRGBA {
+	return color.NRGBA{R: c.R, G: c.G, B: c.B, A: a}
+}
+
+// newRenderCanvas 创建画布，成品区域为 (0,0)-(renderWidth,renderHeight)，
+// 四周各留 bleed 像素出血，出血区域的坐标为负数或超出成品尺寸
+func newRenderCanvas(bleed int) *image.RGBA {
+	return image.NewRGBA(image.Rect(-bleed, -bleed, renderWidth+bleed, renderHeight+bleed))
+}
+
+// drawFront 正面：配图铺满画面并附署名；没有配图时以角色名作为画面
+func (s *RenderService) drawFront(postcard *models.Postcard, template *models.PostcardTemplate, bleed int) (*image.RGBA, error) {
+	theme := templateTheme(template)
+	canvas := newRenderCanvas(bleed)
+	trim := image.Rect(0, 0, renderWidth, renderHeight)
+	utils.FillRect(canvas, canvas.Bounds(), theme.Paper)
+
+	from, to := postcardParties(postcard)
+	date := postcard.CreatedAt.Format("2006.01.02")
+	inner := trim.Inset(60)
+
+	imageURL := postcard.ImageURL
+	if imageURL == "" {
+		imageURL = postcard.AIGeneratedImageURL
+	}
+
+	f := s.templateFont(template)
+	captionFace, err := utils.NewFontFace(f, 40)
+	if err != nil {
+		return nil, err
+	}
+	defer captionFace.Close()
+
+	if img := s.loadImage(imageURL); img != nil {
+		utils.DrawImageCover(canvas, inner, img)
+
+		strip := image.Rect(inner.Min.X, inner.Max.Y-110, inner.Max.X, inner.Max.Y)
+		utils.FillRect(canvas, strip, withAlpha(color.RGBA{0, 0, 0, 255}, 110))
+		caption := fmt.Sprintf("%s → %s · %s", from, to, date)
+		utils.DrawText(canvas, captionFace, color.White, strip.Min.X+40, strip.Min.Y+70, caption)
+	} else {
+		utils.FillRect(canvas, inner, theme.Accent)
+
+		titleFace, err := utils.NewFontFace(f, 120)
+		if err != nil {
+			return nil, err
+		}
+		defer titleFace.Close()
+
+		title := postcard.Character.Name
+		utils.DrawText(canvas, titleFace, theme.Paper, (renderWidth-utils.MeasureText(titleFace, title))/2, renderHeight/2, title)
+		caption := fmt.Sprintf("%s → %s · %s", from, to, date)
+		utils.DrawText(canvas, captionFace, theme.Paper, (renderWidth-utils.MeasureText(captionFace, caption))/2, renderHeight/2+110, caption)
+	}
+
+	utils.StrokeRect(canvas, inner, 4, theme.Paper)
+	return canvas, nil
+}
+
+// drawBack 背面：左侧正文，右侧邮票、邮戳和收寄件人
+func (s *RenderService) drawBack(postcard *models.Postcard, template *models.PostcardTemplate, bleed int) (*image.RGBA, error) {
+	theme := templateTheme(template)
+	canvas := newRenderCanvas(bleed)
+	trim := image.Rect(0, 0, renderWidth, renderHeight)
+	utils.FillRect(canvas, canvas.Bounds(), theme.Paper)
+	if template != nil {
+		if background := s.loadImage(template.BackgroundURL); background != nil {
+			utils.DrawImageCover(canvas, canvas.Bounds(), background)
+		}
+	}
+	utils.StrokeRect(canvas, trim.Inset(30), 4, theme.Accent)
+
+	from, to := postcardParties(postcard)
+	dividerX := renderWidth * 58 / 100
+	utils.FillRect(canvas, image.Rect(dividerX-2, 120, dividerX+1, renderHeight-120), withAlpha(theme.Accent, 160))
+
+	f := s.templateFont(template)
+	faces := make([]font.Face, 0, 3)
+	defer func() {
+		for _, face := range faces {
+			face.Close()
+		}
+	}()
+	newFace := func(size float64) (font.Face, error) {
+		face, err := utils.NewFontFace(f, size)
+		if err == nil {
+			faces = append(faces, face)
+		}
+		return face, err
+	}
+
+	markFace, err := newFace(26)
+	if err != nil {
+		return nil, err
+	}
+
+	// 正文
+	body := textRegion(template, "body")
+	bodyFace, err := newFace(body.FontSize)
+	if err != nil {
+		return nil, err
+	}
+	lineHeight := int(body.FontSize * 1.6)
+	lines := utils.TruncateLines(bodyFace, utils.WrapText(bodyFace, postcard.Content, body.Width), body.Height/lineHeight, body.Width)
+	for i, line := range lines {
+		utils.DrawText(canvas, bodyFace, regionColor(body, theme), alignedX(body, utils.MeasureText(bodyFace, line)), body.Y+int(body.FontSize)+i*lineHeight, line)
+	}
+
+	// 署名
+	signatureRegion := textRegion(template, "signature")
+	signatureFace, err := newFace(signatureRegion.FontSize)
+	if err != nil {
+		return nil, err
+	}
+	signature := "—— " + from
+	utils.DrawText(canvas, signatureFace, regionColor(signatureRegion, theme),
+		alignedX(signatureRegion, utils.MeasureText(signatureFace, signature)),
+		signatureRegion.Y+(signatureRegion.Height+int(signatureRegion.FontSize*0.7))/2, signature)
+
+	// 邮票：锯齿边框内放角色头像
+	stamp := image.Rect(renderWidth-100-250, 100, renderWidth-100, 100+300)
+	utils.FillRect(canvas, stamp, color.White)
+	for x := stamp.Min.X; x <= stamp.Max.X; x += 25 {
+		utils.FillCircle(canvas, image.Pt(x, stamp.Min.Y), 8, theme.Paper)
+		utils.FillCircle(canvas, image.Pt(x, stamp.Max.Y), 8, theme.Paper)
+	}
+	for y := stamp.Min.Y; y <= stamp.Max.Y; y += 25 {
+		utils.FillCircle(canvas, image.Pt(stamp.Min.X, y), 8, theme.Paper)
+		utils.FillCircle(canvas, image.Pt(stamp.Max.X, y), 8, theme.Paper)
+	}
+	stampInner := stamp.Inset(24)
+	if avatar := s.loadImage(postcard.Character.AvatarURL); avatar != nil {
+		utils.DrawImageCover(canvas, stampInner, avatar)
+	} else {
+		utils.FillRect(canvas, stampInner, theme.Accent)
+		initial := string([]rune(postcard.Character.Name + " ")[0])
+		initialFace, err := newFace(120)
+		if err != nil {
+			return nil, err
+		}
+		center := stampInner.Min.Add(image.Pt(stampInner.Dx()/2, stampInner.Dy()/2))
+		utils.DrawText(canvas, initialFace, theme.Paper, center.X-utils.MeasureText(initialFace, initial)/2, center.Y+40, initial)
+	}
+	utils.StrokeRect(canvas, stampInner, 3, theme.Accent)
+
+	// 邮戳：压在邮票左下角的双圆环和日期
+	postmarkInk := withAlpha(theme.Ink, 150)
+	postmark := image.Pt(stamp.Min.X-20, stamp.Max.Y-20)
+	utils.StrokeCircle(canvas, postmark, 115, 6, postmarkInk)
+	utils.StrokeCircle(canvas, postmark, 92, 3, postmarkInk)
+	for i := 0; i < 3; i++ {
+		y := postmark.Y - 30 + i*30
+		utils.FillRect(canvas, image.Rect(postmark.X-330, y, postmark.X-130, y+4), postmarkInk)
+	}
+	date := postcard.CreatedAt.Format("2006.01.02")
+	utils.DrawText(canvas, markFace, postmarkInk, postmark.X-utils.MeasureText(markFace, date)/2, postmark.Y+10, date)
+	label := "POSTCARD"
+	utils.DrawText(canvas, markFace, postmarkInk, postmark.X-utils.MeasureText(markFace, label)/2, postmark.Y-30, label)
+
+	// 收寄件人：区域内均分三行，每行下方画横线
+	address := textRegion(template, "address")
+	addressFace, err := newFace(address.FontSize)
+	if err != nil {
+		return nil, err
+	}
+	addressColor := regionColor(address, theme)
+	for i, text := range []string{"致：" + to, "寄自：" + from, fmt.Sprintf("No.%06d", postcard.ID)} {
+		y := address.Y + (i+1)*address.Height/3
+		utils.DrawText(canvas, addressFace, addressColor, alignedX(address, utils.MeasureText(addressFace, text)+20)+10, y-16, text)
+		utils.FillRect(canvas, image.Rect(address.X, y, address.X+address.Width, y+3), withAlpha(addressColor, 120))
+	}
+
+	return canvas, nil
+}
+
+// loadImage 下载并解码图片，本存储桶内的图片直接从 MinIO 读取，外部图片不允许指向内网地址；失败时返回 nil
+func (s *RenderService) loadImage(url string) image.Image {
+	if url == "" {
+		return nil
+	}
+
+	var data []byte
+	var err error
+	if objectName, ok := s.upload.ObjectNameFromURL(url); ok {
+		data, err = s.upload.ReadObject(objectName, renderImageMaxSize)
+	} else {
+		data, err = s.fetch(url)
+	}
+	if err != nil {
+		log.Printf("Failed to load image %s for rendering: %v", url, err)
+		return nil
+	}
+
+	// 解码前检查尺寸，避免体积小但尺寸极大的图片占用过多内存
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		log.Printf("Failed to decode image %s for rendering: %v", url, err)
+		return nil
+	}
+	if cfg.Width > maxUploadImageSide || cfg.Height > maxUploadImageSide || cfg.Width*cfg.Height > maxUploadPixels {
+		log.Printf("Image %s is too large to render: %dx%d", url, cfg.Width, cfg.Height)
+		return nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		log.Printf("Failed to decode image %s for rendering: %v", url, err)
+		return nil
+	}
+
+	// 统一转为 RGBA，避免调色板图片缩放时颜色失真
+	rgba := image.NewRGBA(img.Bounds())
+	draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	return rgba
+}
+
+func (s *RenderService) fetch(url string) ([]byte, error) {
+	resp, err := s.httpClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, renderImageMaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > renderImageMaxSize {
+		return nil, errors.New("image too large")
+	}
+	return data, nil
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"memory-postcard-backend/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ReviewService struct {
+	db               *gorm.DB
+	characterService *CharacterService
+}
+
+func NewReviewService(db *gorm.DB, characterService *CharacterService) *ReviewService {
+	return &ReviewService{
+		db:               db,
+		characterService: characterService,
+	}
+}
+
+// UpsertReview 创建或更新用户对角色的评价（每个用户对每个角色只有一条评价）
+func (s *ReviewService) UpsertReview(userID, characterID uint, req *models.ReviewUpsertRequest) (*models.CharacterReview, error) {
+	character, err := s.characterService.GetCharacter(characterID, &userID)
+	if err != nil {
+		return nil, err
+	}
+	if !character.IsActive || character.HiddenAt != nil {
+		return nil, errors.New("character not found")
+	}
+	if character.CreatorID == userID {
+		return nil, errors.New("cannot review your own character")
+	}
+
+	var review models.CharacterReview
+	err = s.db.Where("character_id = ? AND user_id = ?", characterID, userID).First(&review).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get review: %w", err)
+	}
+
+	review.CharacterID = characterID
+	review.UserID = userID
+	review.Rating = req.Rating
+	review.Content = req.Content
+
+	if err := s.db.Save(&review).Error; err != nil {
+		return nil, fmt.Errorf("failed to save review: %w", err)
+	}
+
+	if err := s.refreshRatingStats(characterID); err != nil {
+		return nil, err
+	}
+
+	s.db.Preload("User").First(&review, review.ID)
+
+	return &review, nil
+}
+
+// GetMyReview 获取当前用户对角色的评价
+func (s *ReviewService) GetMyReview(userID, characterID uint) (*models.CharacterReview, error) {
+	var review models.CharacterReview
+	if err := s.db.Preload("User").Where("character_id = ? AND user_id = ?", characterID, userID).First(&review).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("review not found")
+		}
+		return nil, fmt.Errorf("failed to get review: %w", err)
+	}
+
+	return &review, nil
+}
+
+// DeleteReview 删除当前用户对角色的评价
+func (s *ReviewService) DeleteReview(userID, characterID uint) error {
+	result := s.db.Where("character_id = ? AND user_id = ?", characterID, userID).Delete(&models.CharacterReview{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete review: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("review not found")
+	}
+
+	return s.refreshRatingStats(characterID)
+}
+
+// ListReviews 分页获取角色的评价列表
+func (s *ReviewService) ListReviews(characterID uint, userID *uint, query *models.ReviewListQuery) (*models.PaginatedResponse, error) {
+	if _, err := s.characterService.GetCharacter(characterID, userID); err != nil {
+		return nil, err
+	}
+
+	var reviews []models.CharacterReview
+	var total int64
+
+	db := s.db.Model(&models.CharacterReview{}).Where("character_id = ?", characterID)
+
+	if query.Rating != 0 {
+		db = db.Where("rating = ?", query.Rating)
+	}
+
+	// 计算总数
+	if err := db.Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count reviews: %w", err)
+	}
+
+	// 排序
+	orderBy := fmt.Sprintf("%s %s", query.SortBy, query.SortOrder)
+	db = db.Order(orderBy)
+
+	// 分页
+	offset := (query.Page - 1) * query.PageSize
+	// 评价列表公开访问，只返回评价者的公开信息
+	publicUser := func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "username", "nickname", "avatar_url")
+	}
+	if err := db.Preload("User", publicUser).Offset(offset).Limit(query.PageSize).Find(&reviews).Error; err != nil {
+		return nil, fmt.Errorf("failed to get reviews: %w", err)
+	}
+
+	return &models.PaginatedResponse{
+		Items:      reviews,
+		Total:      total,
+		Page:       query.Page,
+		PageSize:   query.PageSize,
+		TotalPages: int((total + int64(query.PageSize) - 1) / int64(query.PageSize)),
+	}, nil
+}
+
+// ReplyToReview 角色创建者回复评价
+func (s *ReviewService) ReplyToReview(userID, characterID, reviewID uint, req *models.ReviewReplyRequest) (*models.CharacterReview, error) {
+	var character models.Character
+	if err := s.db.First(&character, characterID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("character not found")
+		}
+		return nil, fmt.Errorf("failed to get character: %w", err)
+	}
+
+	// 检查权限
+	if character.CreatorID != userID {
+		return nil, errors.New("permission denied")
+	}
+
+	var review models.CharacterReview
+	if err := s.db.Where("id = ? AND character_id = ?", reviewID, characterID).First(&review).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("review not found")
+		}
+		return nil, fmt.Errorf("failed to get review: %w", err)
+	}
+
+	now := time.Now()
+	review.CreatorReply = req.Reply
+	review.RepliedAt = &now
+
+	if err := s.db.Save(&review).Error; err != nil {
+		return nil, fmt.Errorf("failed to reply to review: %w", err)
+	}
+
+	s.db.Preload("User").First(&review, review.ID)
+
+	return &review, nil
+}
+
+// refreshRatingStats 重新计算角色的平均评分和评价数量
+func (s *ReviewService) refreshRatingStats(characterID uint) error {
+	var stats struct {
+		Average float64
+		Count   int
+	}
+	if err := s.db.Model(&models.CharacterReview{}).
+		Select("COALESCE(AVG(rating), 0) AS average, COUNT(*) AS count").
+		Where("character_id = ?", characterID).
+		Scan(&stats).Error; err != nil {
+		return fmt.Errorf("failed to aggregate ratings: %w", err)
+	}
+
+	if err := s.db.Model(&models.Character{}).Where("id = ?", characterID).UpdateColumns(map[string]interface{}{
+		"rating_average": math.Round(stats.Average*100) / 100,
+		"rating_count":   stats.Count,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to update rating stats: %w", err)
+	}
+
+	// 清除缓存
+	s.characterService.clearCharacterCache(characterID)
+	s.characterService.clearCharacterListCache()
+
+	return nil
+}
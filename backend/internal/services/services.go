@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"memory-postcard-backend/config"
 
 	"github.com/go-redis/redis/v8"
@@ -9,27 +10,93 @@ import (
 )
 
 type Services struct {
-	User      *UserService
-	Character *CharacterService
-	Postcard  *PostcardService
-	Upload    *UploadService
-	AI        *AIService
-	MQ        *MQService
+	User         *UserService
+	Character    *CharacterService
+	Postcard     *PostcardService
+	Upload       *UploadService
+	AI           *AIService
+	MQ           *MQService
+	Popularity   *PopularityService
+	Recommend    *RecommendationService
+	Review       *ReviewService
+	Moderation   *ModerationService
+	Persona      *PersonaService
+	Lorebook     *LorebookService
+	Memory       *MemoryService
+	Conversation *ConversationService
+	Policy       *PolicyService
+	Render       *RenderService
+	Template     *TemplateService
+	Print        *PrintService
+	Export       *ExportService
+	Share        *ShareService
+	Timeline     *TimelineService
+	Emotion      *EmotionService
+	Reminder     *ReminderService
+	Notification *NotificationService
+	Webhook      *WebhookService
 }
 
 func NewServices(db *gorm.DB, redis *redis.Client, minio *minio.Client, cfg *config.Config) *Services {
-	uploadService := NewUploadService(minio, cfg)
+	uploadService := NewUploadService(db, minio, redis, cfg)
 	aiService := NewAIService(cfg)
 
-	// 创建 MQ 服务（只包含生产者）
+	// 创建 MQ 服务
 	mqService, _ := NewMQService(cfg) // 忽略错误，MQ 服务是可选的
 
+	notificationService := NewNotificationService(db)
+	var notifier Notifier = notificationService
+
+	userService := NewUserService(db, redis, uploadService, cfg)
+	webhookService := NewWebhookService(db, cfg)
+	characterService := NewCharacterService(db, redis, uploadService, webhookService, notifier)
+	popularityService := NewPopularityService(db, redis)
+	personaService := NewPersonaService(db, characterService)
+	lorebookService := NewLorebookService(db)
+	memoryService := NewMemoryService(db, NewEmbeddingProvider(cfg), cfg)
+	conversationService := NewConversationService(db)
+	templateService := NewTemplateService(db, cfg)
+	renderService := NewRenderService(db, uploadService, templateService, cfg)
+	emotionService := NewEmotionService(db, NewEmotionClassifier(cfg, aiService), userService)
+	postcardService := NewPostcardService(db, redis, aiService, mqService, personaService, lorebookService, memoryService, conversationService, templateService, emotionService, webhookService, uploadService, notifier)
+
 	return &Services{
-		User:      NewUserService(db, redis, cfg),
-		Character: NewCharacterService(db, redis),
-		Postcard:  NewPostcardService(db, redis, aiService, mqService),
-		Upload:    uploadService,
-		AI:        aiService,
-		MQ:        mqService,
+		User:         userService,
+		Character:    characterService,
+		Postcard:     postcardService,
+		Upload:       uploadService,
+		AI:           aiService,
+		MQ:           mqService,
+		Popularity:   popularityService,
+		Recommend:    NewRecommendationService(db, redis, popularityService),
+		Review:       NewReviewService(db, characterService),
+		Moderation:   NewModerationService(db, characterService, uploadService, notifier),
+		Persona:      personaService,
+		Lorebook:     lorebookService,
+		Memory:       memoryService,
+		Conversation: conversationService,
+		Policy:       NewPolicyService(db),
+		Render:       renderService,
+		Template:     templateService,
+		Print:        NewPrintService(db, renderService, uploadService, conversationService, notifier),
+		Export:       NewExportService(db, uploadService, conversationService),
+		Share:        NewShareService(db, renderService, conversationService),
+		Timeline:     NewTimelineService(db, userService),
+		Emotion:      emotionService,
+		Reminder:     NewReminderService(db, userService, postcardService, notifier, NewMailer(cfg)),
+		Notification: notificationService,
+		Webhook:      webhookService,
 	}
 }
+
+// StartBackgroundJobs 启动后台定时任务
+func (s *Services) StartBackgroundJobs(ctx context.Context, cfg *config.Config) {
+	s.Popularity.StartPopularityJob(ctx, cfg.PopularityJobInterval)
+	s.Print.ResumePendingJobs()
+	go s.Emotion.BackfillEmotions()
+	go s.Upload.BackfillUploadOwners()
+	s.Reminder.StartReminderJobs(ctx, cfg.ReminderJobInterval, cfg.CheckInJobInterval)
+	s.Webhook.StartWebhookJob(ctx, cfg.WebhookJobInterval)
+	s.Upload.StartUploadGCJob(ctx, cfg.UploadGCInterval, cfg.UploadGCDryRun)
+	s.Postcard.StartAIReplySavedConsumer(ctx)
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"memory-postcard-backend/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ShareService struct {
+	db                  *gorm.DB
+	render              *RenderService
+	conversationService *ConversationService
+}
+
+func NewShareService(db *gorm.DB, renderService *RenderService, conversationService *ConversationService) *ShareService {
+	return &ShareService{
+		db:                  db,
+		render:              renderService,
+		conversationService: conversationService,
+	}
+}
+
+// CreatePostcardShare 为单张明信片创建分享链接
+func (s *ShareService) CreatePostcardShare(postcardID, userID uint, req *models.ShareLinkCreateRequest) (*models.ShareLink, error) {
+	var postcard models.Postcard
+	if err := s.db.Where("id = ? AND user_id = ?", postcardID, userID).First(&postcard).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("postcard not found")
+		}
+		return nil, fmt.Errorf("failed to get postcard: %w", err)
+	}
+
+	return s.createShare(userID, postcard.ConversationID, []uint{postcard.ID}, req.Title, req.ExpiresInHours)
+}
+
+// CreateConversationShare 为对话中选定的若干明信片创建分享链接
+func (s *ShareService) CreateConversationShare(conversationID string, userID uint, req *models.ConversationShareCreateRequest) (*models.ShareLink, error) {
+	if _, err := s.conversationService.getOwnConversation(conversationID, userID); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[uint]bool, len(req.PostcardIDs))
+	ids := make([]uint, 0, len(req.PostcardIDs))
+	for _, id := range req.PostcardIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	var count int64
+	if err := s.db.Model(&models.Postcard{}).
+		Where("id IN ? AND conversation_id = ? AND user_id = ?", ids, conversationID, userID).
+		Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to check postcards: %w", err)
+	}
+	if int(count) != len(ids) {
+		return nil, errors.New("postcard not found")
+	}
+
+	return s.createShare(userID, conversationID, ids, req.Title, req.ExpiresInHours)
+}
+
+// createShare 生成随机 token 并保存分享链接
+func (s *ShareService) createShare(userID uint, conversationID string, postcardIDs []uint, title string, expiresInHours int) (*models.ShareLink, error) {
+	token, err := generateShareToken()
+	if err != nil {
+		return nil, err
+	}
+
+	share := &models.ShareLink{
+		Token:          token,
+		UserID:         userID,
+		ConversationID: conversationID,
+		PostcardIDs:    postcardIDs,
+		Title:          title,
+	}
+	if expiresInHours > 0 {
+		expiresAt := time.Now().Add(time.Duration(expiresInHours) * time.Hour)
+		share.ExpiresAt = &expiresAt
+	}
+
+	if err := s.db.Create(share).Error; err != nil {
+		return nil, fmt.Errorf("failed to create share link: %w", err)
+	}
+	return share, nil
+}
+
+// generateShareToken 生成不可猜测的分享 token
+func generateShareToken() (string, error) {
+	buf := make([]byte, 18)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate share token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// ListShareLinks 获取用户创建的分享链接，包括已撤销和已过期的
+func (s *ShareService) ListShareLinks(userID uint) ([]models.ShareLink, error) {
+	var shares []models.ShareLink
+	if err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&shares).Error; err != nil {
+		return nil, fmt.Errorf("failed to get share links: %w", err)
+	}
+	return shares, nil
+}
+
+// RevokeShareLink 撤销分享链接，撤销后分享页不再可访问
+func (s *ShareService) RevokeShareLink(id, userID uint) (*models.ShareLink, error) {
+	var share models.ShareLink
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&share).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("share link not found")
+		}
+		return nil, fmt.Errorf("failed to get share link: %w", err)
+	}
+
+	if share.RevokedAt == nil {
+		now := time.Now()
+		if err := s.db.Model(&share).Update("revoked_at", now).Error; err != nil {
+			return nil, fmt.Errorf("failed to revoke share link: %w", err)
+		}
+		share.RevokedAt = &now
+	}
+	return &share, nil
+}
+
+// ViewShare 根据 token 获取分享内容并记录一次浏览
+func (s *ShareService) ViewShare(token string) (*models.SharedView, error) {
+	var share models.ShareLink
+	if err := s.db.Where("token = ?", token).First(&share).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("share link not found")
+		}
+		return nil, fmt.Errorf("failed to get share link: %w", err)
+	}
+
+	now := time.Now()
+	if share.RevokedAt != nil || (share.ExpiresAt != nil && share.ExpiresAt.Before(now)) {
+		return nil, errors.New("share link expired")
+	}
+
+	// 角色被删除后分享页仍显示其名称和头像
+	var postcards []models.Postcard
+	if err := s.db.Preload("User").
+		Preload("Character", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Where("id IN ? AND user_id = ?", share.PostcardIDs, share.UserID).
+		Order("created_at ASC, id ASC").
+		Find(&postcards).Error; err != nil {
+		return nil, fmt.Errorf("failed to get postcards: %w", err)
+	}
+	// 分享的明信片已全部删除，或角色已被管理员隐藏
+	if len(postcards) == 0 || postcards[0].Character.HiddenAt != nil {
+		return nil, errors.New("share link expired")
+	}
+
+	// 不更新 updated_at，避免浏览计数影响分享链接的修改时间
+	if err := s.db.Model(&share).UpdateColumns(map[string]interface{}{
+		"view_count":     gorm.Expr("view_count + 1"),
+		"last_viewed_at": now,
+	}).Error; err != nil {
+		log.Printf("Failed to record view for share link %d: %v", share.ID, err)
+	}
+
+	view := &models.SharedView{
+		Title: share.Title,
+		Character: models.SharedCharacter{
+			Name:      postcards[0].Character.Name,
+			AvatarURL: postcards[0].Character.AvatarURL,
+		},
+		Postcards: make([]models.SharedPostcard, 0, len(postcards)),
+		ViewCount: share.ViewCount + 1,
+		SharedAt:  share.CreatedAt,
+		ExpiresAt: share.ExpiresAt,
+	}
+	if view.Title == "" {
+		view.Title = fmt.Sprintf("与%s的明信片", view.Character.Name)
+	}
+
+	for i := range postcards {
+		postcard := &postcards[i]
+		sender, _ := postcardParties(postcard)
+		shared := models.SharedPostcard{
+			Type:                postcard.Type,
+			Sender:              sender,
+			Content:             postcard.Content,
+			ImageURL:            postcard.ImageURL,
+			AIGeneratedImageURL: postcard.AIGeneratedImageURL,
+			CreatedAt:           postcard.CreatedAt,
+		}
+
+		// 渲染结果有缓存，只有首次浏览时才会实际绘制；渲染失败时分享页仍展示文字内容
+		template := s.render.templates.GetTemplateForRender(postcard.PostcardTemplate)
+		var err error
+		if shared.FrontImageURL, err = s.render.renderSide(postcard, template, "front", "png"); err != nil {
+			log.Printf("Failed to render shared postcard %d: %v", postcard.ID, err)
+		}
+		if shared.BackImageURL, err = s.render.renderSide(postcard, template, "back", "png"); err != nil {
+			log.Printf("Failed to render shared postcard %d: %v", postcard.ID, err)
+		}
+
+		view.Postcards = append(view.Postcards, shared)
+	}
+
+	return view, nil
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"memory-postcard-backend/config"
+	"memory-postcard-backend/internal/models"
+	"memory-postcard-backend/internal/utils"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"gorm.io/gorm"
+)
+
+// templateIDPattern 模板 ID 只能由小写字母、数字、下划线和连字符组成
+var templateIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+type TemplateService struct {
+	db      *gorm.DB
+	fontDir string
+}
+
+func NewTemplateService(db *gorm.DB, cfg *config.Config) *TemplateService {
+	return &TemplateService{db: db, fontDir: cfg.RenderFontDir}
+}
+
+// ListTemplates 获取模板列表，includeUnavailable 为 false 时只返回可用模板
+func (s *TemplateService) ListTemplates(includeUnavailable bool) ([]models.PostcardTemplate, error) {
+	query := s.db.Model(&models.PostcardTemplate{})
+	if !includeUnavailable {
+		query = query.Where("is_available = ?", true)
+	}
+
+	var templates []models.PostcardTemplate
+	if err := query.Order("sort_order ASC, created_at ASC").Find(&templates).Error; err != nil {
+		return nil, fmt.Errorf("failed to list templates: %w", err)
+	}
+	return templates, nil
+}
+
+// GetTemplate 获取模板详情，includeUnavailable 为 false 时下架模板视为不存在
+func (s *TemplateService) GetTemplate(id string, includeUnavailable bool) (*models.PostcardTemplate, error) {
+	query := s.db.Where("id = ?", id)
+	if !includeUnavailable {
+		query = query.Where("is_available = ?", true)
+	}
+
+	var template models.PostcardTemplate
+	if err := query.First(&template).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("template not found")
+		}
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+	return &template, nil
+}
+
+// CreateTemplate 创建模板（管理员）
+func (s *TemplateService) CreateTemplate(req *models.TemplateCreateRequest) (*models.PostcardTemplate, error) {
+	if !templateIDPattern.MatchString(req.ID) {
+		return nil, errors.New("invalid template id")
+	}
+	if err := validateTemplateLayout(req.Layout); err != nil {
+		return nil, err
+	}
+	if err := s.validateTemplateFonts(req.Fonts); err != nil {
+		return nil, err
+	}
+	if err := validateTextRegions(req.TextRegions); err != nil {
+		return nil, err
+	}
+
+	// 已删除的模板仍占用 ID，避免旧明信片指向新的同名模板
+	var count int64
+	if err := s.db.Unscoped().Model(&models.PostcardTemplate{}).Where("id = ?", req.ID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to check template: %w", err)
+	}
+	if count > 0 {
+		return nil, errors.New("template already exists")
+	}
+
+	template := models.PostcardTemplate{
+		ID:            req.ID,
+		Name:          req.Name,
+		Description:   req.Description,
+		Layout:        req.Layout,
+		BackgroundURL: req.BackgroundURL,
+		Fonts:         req.Fonts,
+		TextRegions:   req.TextRegions,
+		PreviewURL:    req.PreviewURL,
+		IsAvailable:   true,
+		SortOrder:     req.SortOrder,
+	}
+	if req.IsAvailable != nil {
+		template.IsAvailable = *req.IsAvailable
+	}
+
+	// 显式写入所有字段，避免 is_available=false 被数据库默认值覆盖
+	if err := s.db.Select("*").Create(&template).Error; err != nil {
+		return nil, fmt.Errorf("failed to create template: %w", err)
+	}
+	return &template, nil
+}
+
+// UpdateTemplate 更新模板（管理员）
+func (s *TemplateService) UpdateTemplate(id string, req *models.TemplateUpdateRequest) (*models.PostcardTemplate, error) {
+	template, err := s.GetTemplate(id, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != "" {
+		template.Name = req.Name
+	}
+	if req.Description != nil {
+		template.Description = *req.Description
+	}
+	if req.Layout != nil {
+		if err := validateTemplateLayout(*req.Layout); err != nil {
+			return nil, err
+		}
+		template.Layout = *req.Layout
+	}
+	if req.BackgroundURL != nil {
+		template.BackgroundURL = *req.BackgroundURL
+	}
+	if req.Fonts != nil {
+		if err := s.validateTemplateFonts(req.Fonts); err != nil {
+			return nil, err
+		}
+		template.Fonts = req.Fonts
+	}
+	if req.TextRegions != nil {
+		if err := validateTextRegions(req.TextRegions); err != nil {
+			return nil, err
+		}
+		template.TextRegions = req.TextRegions
+	}
+	if req.PreviewURL != nil {
+		template.PreviewURL = *req.PreviewURL
+	}
+	if req.IsAvailable != nil {
+		template.IsAvailable = *req.IsAvailable
+	}
+	if req.SortOrder != nil {
+		template.SortOrder = *req.SortOrder
+	}
+
+	if err := s.db.Save(template).Error; err != nil {
+		return nil, fmt.Errorf("failed to update template: %w", err)
+	}
+	return template, nil
+}
+
+// DeleteTemplate 删除模板（管理员），已使用该模板的明信片仍可按原模板渲染
+func (s *TemplateService) DeleteTemplate(id string) error {
+	result := s.db.Where("id = ?", id).Delete(&models.PostcardTemplate{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete template: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("template not found")
+	}
+	return nil
+}
+
+// ValidateTemplateID 检查明信片引用的模板是否存在且可用，空 ID 表示使用默认样式
+func (s *TemplateService) ValidateTemplateID(id string) error {
+	if id == "" {
+		return nil
+	}
+	_, err := s.GetTemplate(id, false)
+	return err
+}
+
+// GetTemplateForRender 获取渲染用的模板，包括已下架和已删除的模板；不存在时返回 nil
+func (s *TemplateService) GetTemplateForRender(id string) *models.PostcardTemplate {
+	if id == "" {
+		return nil
+	}
+
+	var template models.PostcardTemplate
+	if err := s.db.Unscoped().Where("id = ?", id).First(&template).Error; err != nil {
+		return nil
+	}
+	return &template
+}
+
+// validateTemplateLayout 校验模板配色，空值表示使用默认颜色
+func validateTemplateLayout(layout models.TemplateLayout) error {
+	for _, c := range []string{layout.PaperColor, layout.InkColor, layout.AccentColor} {
+		if c == "" {
+			continue
+		}
+		if _, err := utils.ParseHexColor(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateTemplateFonts 校验模板字体：必须是字体目录下存在的文件
+func (s *TemplateService) validateTemplateFonts(fonts []string) error {
+	for _, name := range fonts {
+		path, err := templateFontPath(s.fontDir, name)
+		if err != nil {
+			return err
+		}
+		if info, err := os.Stat(path); err != nil || info.IsDir() {
+			return fmt.Errorf("font not found: %s", name)
+		}
+	}
+	return nil
+}
+
+// templateFontPath 返回模板字体文件的路径，字体名只能是字体目录下的文件名
+func templateFontPath(fontDir, name string) (string, error) {
+	if fontDir == "" {
+		return "", errors.New("template fonts are not configured")
+	}
+	if name == "" || name != filepath.Base(name) || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid font: %s", name)
+	}
+	return filepath.Join(fontDir, name), nil
+}
+
+// validateTextRegions 校验文字区域：名称不能重复，且必须位于画布内
+func validateTextRegions(regions []models.TemplateTextRegion) error {
+	seen := make(map[string]bool, len(regions))
+	for _, region := range regions {
+		if seen[region.Name] {
+			return fmt.Errorf("duplicate text region: %s", region.Name)
+		}
+		seen[region.Name] = true
+
+		if region.X+region.Width > renderWidth || region.Y+region.Height > renderHeight {
+			return fmt.Errorf("text region out of bounds: %s", region.Name)
+		}
+		if region.Color != "" {
+			if _, err := utils.ParseHexColor(region.Color); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,255 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"memory-postcard-backend/internal/models"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const timelineDateLayout = "2006-01-02"
+
+// timelinePeriodLayouts 各分组方式的时间段标识格式
+var timelinePeriodLayouts = map[string]string{
+	"day":   "2006-01-02",
+	"month": "2006-01",
+	"year":  "2006",
+}
+
+type TimelineService struct {
+	db          *gorm.DB
+	userService *UserService
+}
+
+func NewTimelineService(db *gorm.DB, userService *UserService) *TimelineService {
+	return &TimelineService{
+		db:          db,
+		userService: userService,
+	}
+}
+
+// scope 时间线包含用户的所有对话中双方的明信片，备选回复除外
+func (s *TimelineService) scope(userID, characterID uint) *gorm.DB {
+	db := s.db.Model(&models.Postcard{}).Where("user_id = ? AND is_canonical = ?", userID, true)
+	if characterID != 0 {
+		db = db.Where("character_id = ?", characterID)
+	}
+	return db
+}
+
+// GetTimeline 按日、月或年分组获取明信片时间线，从新到旧排列
+func (s *TimelineService) GetTimeline(userID uint, query *models.TimelineQuery) (*models.TimelineResponse, error) {
+	loc := s.userService.GetLocation(userID)
+
+	if query.From != "" && query.To != "" && query.From > query.To {
+		return nil, errors.New("invalid date range")
+	}
+	filtered := func() *gorm.DB {
+		db := s.scope(userID, query.CharacterID)
+		if query.From != "" {
+			from, _ := time.ParseInLocation(timelineDateLayout, query.From, loc)
+			db = db.Where("created_at >= ?", from)
+		}
+		if query.To != "" {
+			to, _ := time.ParseInLocation(timelineDateLayout, query.To, loc)
+			db = db.Where("created_at < ?", to.AddDate(0, 0, 1))
+		}
+		return db
+	}
+
+	var total int64
+	if err := filtered().Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count postcards: %w", err)
+	}
+
+	var postcards []models.Postcard
+	offset := (query.Page - 1) * query.PageSize
+	if err := filtered().Preload("Character").
+		Order("created_at DESC, id DESC").
+		Offset(offset).Limit(query.PageSize).
+		Find(&postcards).Error; err != nil {
+		return nil, fmt.Errorf("failed to get postcards: %w", err)
+	}
+
+	resp := &models.TimelineResponse{
+		TimeZone: loc.String(),
+		GroupBy:  query.GroupBy,
+		Groups:   []models.TimelineGroup{},
+		Total:    total,
+		Page:     query.Page,
+		PageSize: query.PageSize,
+	}
+	if len(postcards) == 0 {
+		return resp, nil
+	}
+
+	layout := timelinePeriodLayouts[query.GroupBy]
+	for _, postcard := range postcards {
+		start := periodStart(postcard.CreatedAt.In(loc), query.GroupBy)
+		period := start.Format(layout)
+		if n := len(resp.Groups); n > 0 && resp.Groups[n-1].Period == period {
+			resp.Groups[n-1].Postcards = append(resp.Groups[n-1].Postcards, postcard)
+			continue
+		}
+		resp.Groups = append(resp.Groups, models.TimelineGroup{
+			Period:    period,
+			Start:     start,
+			Postcards: []models.Postcard{postcard},
+		})
+	}
+
+	// 各时间段的总数不受分页影响，一次取出本页覆盖范围内的时间再按时间段计数
+	rangeStart := resp.Groups[len(resp.Groups)-1].Start
+	rangeEnd := nextPeriod(resp.Groups[0].Start, query.GroupBy)
+	times, err := s.createdTimes(filtered(), rangeStart, rangeEnd)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64)
+	for _, t := range times {
+		counts[periodStart(t.In(loc), query.GroupBy).Format(layout)]++
+	}
+	for i := range resp.Groups {
+		resp.Groups[i].Count = counts[resp.Groups[i].Period]
+	}
+
+	return resp, nil
+}
+
+// GetOnThisDay 获取往年同月同日的明信片
+func (s *TimelineService) GetOnThisDay(userID uint, query *models.OnThisDayQuery) (*models.OnThisDayResponse, error) {
+	loc := s.userService.GetLocation(userID)
+
+	date := time.Now().In(loc)
+	if query.Date != "" {
+		date, _ = time.ParseInLocation(timelineDateLayout, query.Date, loc)
+	}
+
+	resp := &models.OnThisDayResponse{
+		Date:     date.Format(timelineDateLayout),
+		TimeZone: loc.String(),
+		Years:    []models.OnThisDayYear{},
+	}
+
+	// 从最早的明信片所在年份开始查找，避免逐年扫描到很久以前
+	var first models.Postcard
+	if err := s.scope(userID, query.CharacterID).Select("created_at").Order("created_at ASC").First(&first).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return resp, nil
+		}
+		return nil, fmt.Errorf("failed to get postcards: %w", err)
+	}
+
+	var conditions []string
+	var args []interface{}
+	for year := date.Year() - 1; year >= first.CreatedAt.In(loc).Year(); year-- {
+		start := time.Date(year, date.Month(), date.Day(), 0, 0, 0, 0, loc)
+		// 2 月 29 日在平年没有对应的日期
+		if start.Month() != date.Month() {
+			continue
+		}
+		conditions = append(conditions, "(created_at >= ? AND created_at < ?)")
+		args = append(args, start, start.AddDate(0, 0, 1))
+	}
+	if len(conditions) == 0 {
+		return resp, nil
+	}
+
+	var postcards []models.Postcard
+	if err := s.scope(userID, query.CharacterID).
+		Where("("+strings.Join(conditions, " OR ")+")", args...).
+		Preload("Character").
+		Order("created_at ASC, id ASC").
+		Find(&postcards).Error; err != nil {
+		return nil, fmt.Errorf("failed to get postcards: %w", err)
+	}
+
+	// 同一年内按时间先后排列，年份从近到远
+	for _, postcard := range postcards {
+		year := postcard.CreatedAt.In(loc).Year()
+		if n := len(resp.Years); n == 0 || resp.Years[n-1].Year != year {
+			resp.Years = append(resp.Years, models.OnThisDayYear{Year: year, YearsAgo: date.Year() - year})
+		}
+		resp.Years[len(resp.Years)-1].Postcards = append(resp.Years[len(resp.Years)-1].Postcards, postcard)
+	}
+	for i, j := 0, len(resp.Years)-1; i < j; i, j = i+1, j-1 {
+		resp.Years[i], resp.Years[j] = resp.Years[j], resp.Years[i]
+	}
+
+	return resp, nil
+}
+
+// GetCalendar 获取一年中每天的明信片数量，用于日历热力图
+func (s *TimelineService) GetCalendar(userID uint, query *models.CalendarQuery) (*models.CalendarResponse, error) {
+	loc := s.userService.GetLocation(userID)
+
+	year := query.Year
+	if year == 0 {
+		year = time.Now().In(loc).Year()
+	}
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+
+	times, err := s.createdTimes(s.scope(userID, query.CharacterID), start, start.AddDate(1, 0, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	for _, t := range times {
+		counts[t.In(loc).Format(timelineDateLayout)]++
+	}
+
+	resp := &models.CalendarResponse{
+		Year:     year,
+		TimeZone: loc.String(),
+		Total:    len(times),
+		Days:     make([]models.CalendarDay, 0, len(counts)),
+	}
+	for date, count := range counts {
+		resp.Days = append(resp.Days, models.CalendarDay{Date: date, Count: count})
+		if count > resp.MaxCount {
+			resp.MaxCount = count
+		}
+	}
+	sort.Slice(resp.Days, func(i, j int) bool { return resp.Days[i].Date < resp.Days[j].Date })
+
+	return resp, nil
+}
+
+// createdTimes 获取时间范围内明信片的创建时间
+func (s *TimelineService) createdTimes(db *gorm.DB, start, end time.Time) ([]time.Time, error) {
+	var times []time.Time
+	if err := db.Where("created_at >= ? AND created_at < ?", start, end).
+		Pluck("created_at", &times).Error; err != nil {
+		return nil, fmt.Errorf("failed to count postcards: %w", err)
+	}
+	return times, nil
+}
+
+// periodStart 时间所在时间段的起始时刻
+func periodStart(t time.Time, groupBy string) time.Time {
+	switch groupBy {
+	case "year":
+		return time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, t.Location())
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	}
+}
+
+// nextPeriod 下一个时间段的起始时刻
+func nextPeriod(start time.Time, groupBy string) time.Time {
+	switch groupBy {
+	case "year":
+		return start.AddDate(1, 0, 0)
+	case "month":
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
@@ -1,32 +1,119 @@
 package services
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"image"
+	"io"
+	"log"
 	"memory-postcard-backend/config"
 	"memory-postcard-backend/internal/models"
 	"memory-postcard-backend/internal/utils"
 	"mime/multipart"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
 	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+const (
+	presignExpiry      = 15 * time.Minute
+	pendingUploadTTL   = presignExpiry + 15*time.Minute // 上传完成后留给客户端确认的时间
+	maxUploadImageSide = 10000
+	maxUploadPixels    = 50_000_000 // 防止解码时占用过多内存的超大图片
+)
+
+// uploadKind 一类直传文件的存储位置、大小上限和允许的类型
+type uploadKind struct {
+	prefix       string
+	maxSize      int64
+	contentTypes []string
+	image        bool
+}
+
+var uploadKinds = map[string]uploadKind{
+	"image":            {prefix: "images", maxSize: 50 * 1024 * 1024, contentTypes: []string{"image/jpeg", "image/png", "image/gif", "image/webp"}, image: true},
+	"avatar":           {prefix: "avatars", maxSize: 10 * 1024 * 1024, contentTypes: []string{"image/jpeg", "image/png", "image/gif", "image/webp"}, image: true},
+	"character-avatar": {prefix: "avatars", maxSize: 10 * 1024 * 1024, contentTypes: []string{"image/jpeg", "image/png", "image/gif", "image/webp"}, image: true},
+	"audio":            {prefix: "audio", maxSize: 50 * 1024 * 1024, contentTypes: []string{"audio/mpeg", "audio/wav", "audio/ogg"}},
+}
+
+// detectedContentTypes 将 http.DetectContentType 的结果对应到允许上传的类型
+var detectedContentTypes = map[string]string{
+	"image/jpeg":      "image/jpeg",
+	"image/png":       "image/png",
+	"image/gif":       "image/gif",
+	"image/webp":      "image/webp",
+	"audio/mpeg":      "audio/mpeg",
+	"audio/wave":      "audio/wav",
+	"application/ogg": "audio/ogg",
+}
+
+// pendingUpload 已签发直传地址、等待确认的上传
+type pendingUpload struct {
+	UserID      uint   `json:"user_id"`
+	Kind        string `json:"kind"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+}
+
 type UploadService struct {
-	minio  *minio.Client
-	config *config.Config
+	db      *gorm.DB
+	minio   *minio.Client
+	presign *minio.Client // 生成直传地址用的客户端，地址需能从客户端访问
+	redis   *redis.Client
+	config  *config.Config
 }
 
-func NewUploadService(minio *minio.Client, cfg *config.Config) *UploadService {
+func NewUploadService(db *gorm.DB, minioClient *minio.Client, redis *redis.Client, cfg *config.Config) *UploadService {
 	return &UploadService{
-		minio:  minio,
-		config: cfg,
+		db:      db,
+		minio:   minioClient,
+		presign: newPresignClient(minioClient, cfg),
+		redis:   redis,
+		config:  cfg,
 	}
 }
 
+// newPresignClient 配置了 MinIOPresignURL 时创建使用该地址签名的客户端，否则沿用内部客户端
+func newPresignClient(minioClient *minio.Client, cfg *config.Config) *minio.Client {
+	if cfg.MinIOPresignURL == "" {
+		return minioClient
+	}
+
+	u, err := url.Parse(cfg.MinIOPresignURL)
+	if err != nil || u.Host == "" {
+		log.Printf("Invalid MINIO_PRESIGN_URL %q, using MINIO_ENDPOINT for presigned uploads", cfg.MinIOPresignURL)
+		return minioClient
+	}
+
+	// 设置 Region 后签名不需要访问存储查询存储桶位置
+	client, err := minio.New(u.Host, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.MinIOAccessKey, cfg.MinIOSecretKey, ""),
+		Secure: u.Scheme == "https",
+		Region: cfg.MinIORegion,
+	})
+	if err != nil {
+		log.Printf("Failed to create MinIO presign client, using MINIO_ENDPOINT for presigned uploads: %v", err)
+		return minioClient
+	}
+	return client
+}
+
 // UploadImage 上传图片（带压缩功能）
-func (s *UploadService) UploadImage(file *multipart.FileHeader) (*models.UploadResponse, error) {
+func (s *UploadService) UploadImage(userID uint, file *multipart.FileHeader) (*models.UploadResponse, error) {
 	// 检查文件类型
 	src, err := file.Open()
 	if err != nil {
@@ -79,15 +166,16 @@ func (s *UploadService) UploadImage(file *multipart.FileHeader) (*models.UploadR
 	// 上传到 MinIO
 	ctx := context.Background()
 
+	var info minio.UploadInfo
 	if needsCompression {
 		// 上传压缩后的图片数据
 		reader := bytes.NewReader(compressedData)
-		_, err = s.minio.PutObject(ctx, s.config.MinIOBucketName, objectName, reader, finalSize, minio.PutObjectOptions{
+		info, err = s.minio.PutObject(ctx, s.config.MinIOBucketName, objectName, reader, finalSize, minio.PutObjectOptions{
 			ContentType: finalContentType,
 		})
 	} else {
 		// 上传原文件
-		_, err = s.minio.PutObject(ctx, s.config.MinIOBucketName, objectName, src, finalSize, minio.PutObjectOptions{
+		info, err = s.minio.PutObject(ctx, s.config.MinIOBucketName, objectName, src, finalSize, minio.PutObjectOptions{
 			ContentType: finalContentType,
 		})
 	}
@@ -95,6 +183,9 @@ func (s *UploadService) UploadImage(file *multipart.FileHeader) (*models.UploadR
 	if err != nil {
 		return nil, fmt.Errorf("failed to upload file: %w", err)
 	}
+	if err := s.registerUpload(userID, objectName, "image", finalContentType, finalSize, info.ETag); err != nil {
+		return nil, err
+	}
 
 	// 生成访问 URL
 	url := s.generateURL(objectName)
@@ -108,7 +199,7 @@ func (s *UploadService) UploadImage(file *multipart.FileHeader) (*models.UploadR
 }
 
 // UploadAudio 上传音频文件
-func (s *UploadService) UploadAudio(file *multipart.FileHeader) (*models.UploadResponse, error) {
+func (s *UploadService) UploadAudio(userID uint, file *multipart.FileHeader) (*models.UploadResponse, error) {
 	// 检查文件类型
 	src, err := file.Open()
 	if err != nil {
@@ -137,12 +228,15 @@ func (s *UploadService) UploadAudio(file *multipart.FileHeader) (*models.UploadR
 
 	// 上传到 MinIO
 	ctx := context.Background()
-	_, err = s.minio.PutObject(ctx, s.config.MinIOBucketName, objectName, src, file.Size, minio.PutObjectOptions{
+	info, err := s.minio.PutObject(ctx, s.config.MinIOBucketName, objectName, src, file.Size, minio.PutObjectOptions{
 		ContentType: contentType,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to upload file: %w", err)
 	}
+	if err := s.registerUpload(userID, objectName, "audio", contentType, file.Size, info.ETag); err != nil {
+		return nil, err
+	}
 
 	// 生成访问 URL
 	url := s.generateURL(objectName)
@@ -155,7 +249,7 @@ func (s *UploadService) UploadAudio(file *multipart.FileHeader) (*models.UploadR
 }
 
 // UploadAvatar 上传头像
-func (s *UploadService) UploadAvatar(file *multipart.FileHeader) (*models.UploadResponse, error) {
+func (s *UploadService) UploadAvatar(userID uint, file *multipart.FileHeader) (*models.UploadResponse, error) {
 	// 检查文件大小（限制为 10MB）
 	if file.Size > 10*1024*1024 {
 		return nil, fmt.Errorf("file size too large, maximum 10MB allowed")
@@ -189,12 +283,15 @@ func (s *UploadService) UploadAvatar(file *multipart.FileHeader) (*models.Upload
 
 	// 上传到 MinIO
 	ctx := context.Background()
-	_, err = s.minio.PutObject(ctx, s.config.MinIOBucketName, objectName, src, file.Size, minio.PutObjectOptions{
+	info, err := s.minio.PutObject(ctx, s.config.MinIOBucketName, objectName, src, file.Size, minio.PutObjectOptions{
 		ContentType: contentType,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to upload file: %w", err)
 	}
+	if err := s.registerUpload(userID, objectName, "avatar", contentType, file.Size, info.ETag); err != nil {
+		return nil, err
+	}
 
 	// 生成访问 URL
 	url := s.generateURL(objectName)
@@ -206,13 +303,254 @@ func (s *UploadService) UploadAvatar(file *multipart.FileHeader) (*models.Upload
 	}, nil
 }
 
-// DeleteFile 删除文件
+// PresignUpload 签发直传存储的 PUT 地址，Content-Type 和 Content-Length 参与签名，上传的文件必须与申请时一致
+func (s *UploadService) PresignUpload(userID uint, req *models.UploadPresignRequest) (*models.UploadPresignResponse, error) {
+	kind := uploadKinds[req.Kind]
+
+	contentType := strings.ToLower(req.ContentType)
+	if contentType == "image/jpg" {
+		contentType = "image/jpeg"
+	}
+	if contentType == "audio/mp3" {
+		contentType = "audio/mpeg"
+	}
+	allowed := false
+	for _, t := range kind.contentTypes {
+		if t == contentType {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return nil, errors.New("invalid content type")
+	}
+	if req.Size > kind.maxSize {
+		return nil, errors.New("file size too large")
+	}
+
+	objectKey := fmt.Sprintf("%s/%s%s", kind.prefix, uuid.New().String(), utils.GetFileExtension(contentType))
+	headers := http.Header{}
+	headers.Set("Content-Type", contentType)
+	headers.Set("Content-Length", strconv.FormatInt(req.Size, 10))
+
+	ctx := context.Background()
+	u, err := s.presign.PresignHeader(ctx, http.MethodPut, s.config.MinIOBucketName, objectKey, presignExpiry, nil, headers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to presign upload: %w", err)
+	}
+
+	pending, err := json.Marshal(pendingUpload{
+		UserID:      userID,
+		Kind:        req.Kind,
+		ContentType: contentType,
+		Size:        req.Size,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal pending upload: %w", err)
+	}
+	if err := s.redis.Set(ctx, pendingUploadKey(objectKey), pending, pendingUploadTTL).Err(); err != nil {
+		return nil, fmt.Errorf("failed to save pending upload: %w", err)
+	}
+
+	return &models.UploadPresignResponse{
+		UploadURL: u.String(),
+		Method:    http.MethodPut,
+		Headers:   map[string]string{"Content-Type": contentType},
+		ObjectKey: objectKey,
+		MaxSize:   kind.maxSize,
+		ExpiresAt: time.Now().Add(presignExpiry),
+	}, nil
+}
+
+// ConfirmUpload 确认直传完成：检查文件大小、文件头和图片尺寸，不符合要求的文件会被删除
+// 只读取文件开头用于识别类型和尺寸，文件内容不经过 API 服务
+func (s *UploadService) ConfirmUpload(userID uint, req *models.UploadConfirmRequest) (*models.UploadResponse, error) {
+	ctx := context.Background()
+	key := pendingUploadKey(req.ObjectKey)
+
+	data, err := s.redis.Get(ctx, key).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, errors.New("upload not found")
+		}
+		return nil, fmt.Errorf("failed to get pending upload: %w", err)
+	}
+	var pending pendingUpload
+	if err := json.Unmarshal(data, &pending); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal pending upload: %w", err)
+	}
+	if pending.UserID != userID {
+		return nil, errors.New("upload not found")
+	}
+
+	info, err := s.minio.StatObject(ctx, s.config.MinIOBucketName, req.ObjectKey, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, errors.New("upload not completed")
+		}
+		return nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+
+	response, rejected, err := s.inspectUpload(ctx, req.ObjectKey, &pending, info.Size)
+	if err != nil {
+		if rejected {
+			if removeErr := s.DeleteFile(req.ObjectKey); removeErr != nil {
+				log.Printf("Failed to delete rejected upload %s: %v", req.ObjectKey, removeErr)
+			}
+			s.redis.Del(ctx, key)
+		}
+		return nil, err
+	}
+
+	if err := s.registerUpload(userID, req.ObjectKey, pending.Kind, pending.ContentType, info.Size, info.ETag); err != nil {
+		return nil, err
+	}
+
+	s.redis.Del(ctx, key)
+	return response, nil
+}
+
+// inspectUpload 校验已上传的对象是否与申请时一致，rejected 表示文件不符合要求（而不是读取失败）
+func (s *UploadService) inspectUpload(ctx context.Context, objectKey string, pending *pendingUpload, size int64) (response *models.UploadResponse, rejected bool, err error) {
+	kind := uploadKinds[pending.Kind]
+	if size != pending.Size || size > kind.maxSize {
+		return nil, true, errors.New("file size does not match")
+	}
+
+	obj, err := s.minio.GetObject(ctx, s.config.MinIOBucketName, objectKey, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get file: %w", err)
+	}
+	defer obj.Close()
+
+	reader := bufio.NewReaderSize(obj, 4096)
+	head, err := reader.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, false, fmt.Errorf("failed to read file: %w", err)
+	}
+	if detectedContentTypes[http.DetectContentType(head)] != pending.ContentType {
+		return nil, true, errors.New("file content does not match content type")
+	}
+
+	response = &models.UploadResponse{
+		URL:         s.generateURL(objectKey),
+		Filename:    objectKey[strings.LastIndex(objectKey, "/")+1:],
+		Size:        size,
+		ContentType: pending.ContentType,
+	}
+
+	if kind.image {
+		cfg, _, err := image.DecodeConfig(reader)
+		if err != nil {
+			return nil, true, errors.New("invalid image")
+		}
+		if cfg.Width > maxUploadImageSide || cfg.Height > maxUploadImageSide || cfg.Width*cfg.Height > maxUploadPixels {
+			return nil, true, errors.New("image dimensions too large")
+		}
+		response.Width = cfg.Width
+		response.Height = cfg.Height
+	}
+
+	return response, false, nil
+}
+
+func pendingUploadKey(objectKey string) string {
+	return "upload:pending:" + objectKey
+}
+
+// PutBytes 将内存中的数据写入存储桶，返回访问 URL
+func (s *UploadService) PutBytes(objectName string, data []byte, contentType string) (string, error) {
+	ctx := context.Background()
+	_, err := s.minio.PutObject(ctx, s.config.MinIOBucketName, objectName, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload file: %w", err)
+	}
+	return s.generateURL(objectName), nil
+}
+
+// PutFile 将本地文件上传到存储桶，返回访问 URL
+func (s *UploadService) PutFile(objectName, filePath, contentType string) (string, error) {
+	ctx := context.Background()
+	_, err := s.minio.FPutObject(ctx, s.config.MinIOBucketName, objectName, filePath, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload file: %w", err)
+	}
+	return s.generateURL(objectName), nil
+}
+
+// ReadObject 读取存储桶中的对象，超过 maxSize 字节时返回错误
+func (s *UploadService) ReadObject(objectName string, maxSize int64) ([]byte, error) {
+	ctx := context.Background()
+	obj, err := s.minio.GetObject(ctx, s.config.MinIOBucketName, objectName, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file: %w", err)
+	}
+	defer obj.Close()
+
+	data, err := io.ReadAll(io.LimitReader(obj, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("file size too large")
+	}
+	return data, nil
+}
+
+// DeleteFile 删除文件及其上传记录
 func (s *UploadService) DeleteFile(objectName string) error {
 	ctx := context.Background()
 	err := s.minio.RemoveObject(ctx, s.config.MinIOBucketName, objectName, minio.RemoveObjectOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to delete file: %w", err)
 	}
+	if err := s.db.Where("object_key = ?", objectName).Delete(&models.Upload{}).Error; err != nil {
+		return fmt.Errorf("failed to delete upload record: %w", err)
+	}
+	return nil
+}
+
+// registerUpload 记录上传的文件及上传者
+func (s *UploadService) registerUpload(userID uint, objectKey, kind, contentType string, size int64, hash string) error {
+	upload := models.Upload{
+		UserID:      userID,
+		ObjectKey:   objectKey,
+		Kind:        kind,
+		ContentType: contentType,
+		Size:        size,
+		Hash:        strings.Trim(hash, "\""),
+	}
+	if err := s.db.Create(&upload).Error; err != nil {
+		return fmt.Errorf("failed to register upload: %w", err)
+	}
+	return nil
+}
+
+// VerifyOwnership 检查引用的文件是否由该用户上传：指向本存储桶的 URL 必须有该用户的上传记录，其他站点的 URL 不检查
+func (s *UploadService) VerifyOwnership(userID uint, urls ...string) error {
+	for _, url := range urls {
+		if url == "" {
+			continue
+		}
+		objectName, ok := s.ObjectNameFromURL(url)
+		if !ok {
+			continue
+		}
+
+		var count int64
+		if err := s.db.Model(&models.Upload{}).
+			Where("object_key = ? AND user_id = ?", objectName, userID).
+			Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to check upload: %w", err)
+		}
+		if count == 0 {
+			return errors.New("invalid media url")
+		}
+	}
 	return nil
 }
 
@@ -231,6 +569,22 @@ func (s *UploadService) generateURL(objectName string) string {
 	return fmt.Sprintf("%s://%s/%s/%s", protocol, s.config.MinIOEndpoint, s.config.MinIOBucketName, objectName)
 }
 
+// ObjectNameFromURL 从访问 URL 中解析出存储桶内的对象名称，非本存储桶的 URL 返回 false
+func (s *UploadService) ObjectNameFromURL(url string) (string, bool) {
+	prefixes := []string{
+		s.generateURL(""),
+		fmt.Sprintf("http://%s/%s/", s.config.MinIOEndpoint, s.config.MinIOBucketName),
+		fmt.Sprintf("https://%s/%s/", s.config.MinIOEndpoint, s.config.MinIOBucketName),
+	}
+
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(url, prefix) && len(url) > len(prefix) {
+			return strings.TrimPrefix(url, prefix), true
+		}
+	}
+	return "", false
+}
+
 // GetFileInfo 获取文件信息
 func (s *UploadService) GetFileInfo(objectName string) (*minio.ObjectInfo, error) {
 	ctx := context.Background()
@@ -240,3 +594,180 @@ func (s *UploadService) GetFileInfo(objectName string) (*minio.ObjectInfo, error
 	}
 	return &info, nil
 }
+
+// uploadPrefixes 用户上传文件所在的目录，清理只处理这些目录，渲染图、导出文件等系统生成的文件不受影响
+var uploadPrefixes = []string{"images/", "avatars/", "audio/"}
+
+// uploadReferences 可能引用上传文件的字段
+var uploadReferences = []struct {
+	model  interface{}
+	column string
+}{
+	{&models.User{}, "avatar_url"},
+	{&models.Character{}, "avatar_url"},
+	{&models.Character{}, "voice_url"},
+	{&models.Postcard{}, "image_url"},
+	{&models.Postcard{}, "ai_generated_image_url"},
+	{&models.Postcard{}, "voice_url"},
+	{&models.Draft{}, "landscape_image_url"},
+	{&models.PostcardTemplate{}, "background_url"},
+	{&models.PostcardTemplate{}, "preview_url"},
+	{&models.Report{}, "target_url"}, // 举报处理前保留被举报的文件
+}
+
+const (
+	uploadGCLockKey     = "upload:gc:lock"
+	maxUploadGCListings = 1000
+)
+
+// StartUploadGCJob 定期清理超过保留期且无引用的上传文件，多个实例同时运行时只有一个实例执行
+func (s *UploadService) StartUploadGCJob(ctx context.Context, interval time.Duration, dryRun bool) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				locked, err := s.redis.SetNX(ctx, uploadGCLockKey, 1, interval/2).Result()
+				if err != nil || !locked {
+					continue
+				}
+				report, err := s.CollectGarbage(dryRun)
+				if err != nil {
+					log.Printf("Failed to collect unreferenced uploads: %v", err)
+					continue
+				}
+				log.Printf("Upload GC finished: dry_run=%v, scanned=%d, orphaned=%d, deleted=%d, failed=%d, orphan_size=%d",
+					report.DryRun, report.Scanned, report.Orphaned, report.Deleted, report.Failed, report.OrphanSize)
+			}
+		}
+	}()
+}
+
+// CollectGarbage 删除上传超过保留期且没有被任何记录引用的文件，dryRun 时只统计不删除
+// 引用按数据库中保存的 URL 判断；已删除的角色仍会在对话和导出中显示，软删除的记录也视为引用
+func (s *UploadService) CollectGarbage(dryRun bool) (*models.UploadGCReport, error) {
+	report := &models.UploadGCReport{DryRun: dryRun, StartedAt: time.Now(), Objects: []models.UploadGCObject{}}
+
+	referenced, err := s.referencedObjects()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	cutoff := report.StartedAt.Add(-s.config.UploadGCGracePeriod)
+	for _, prefix := range uploadPrefixes {
+		for obj := range s.minio.ListObjects(ctx, s.config.MinIOBucketName, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+			if obj.Err != nil {
+				return nil, fmt.Errorf("failed to list files: %w", obj.Err)
+			}
+			report.Scanned++
+			if referenced[obj.Key] || obj.LastModified.After(cutoff) {
+				continue
+			}
+
+			report.Orphaned++
+			report.OrphanSize += obj.Size
+			if len(report.Objects) < maxUploadGCListings {
+				item := models.UploadGCObject{ObjectKey: obj.Key, Size: obj.Size, LastModified: obj.LastModified}
+				var upload models.Upload
+				if err := s.db.Select("user_id").Where("object_key = ?", obj.Key).First(&upload).Error; err == nil {
+					item.OwnerID = &upload.UserID
+				}
+				report.Objects = append(report.Objects, item)
+			}
+
+			if dryRun {
+				continue
+			}
+			if err := s.DeleteFile(obj.Key); err != nil {
+				log.Printf("Failed to delete unreferenced upload %s: %v", obj.Key, err)
+				report.Failed++
+				continue
+			}
+			report.Deleted++
+		}
+	}
+
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// uploadOwners 记录上传者之前保存的文件引用，以及引用记录中表示上传者的字段
+var uploadOwners = []struct {
+	table       string
+	column      string
+	ownerColumn string
+	kind        string
+}{
+	{"users", "avatar_url", "id", "avatar"},
+	{"characters", "avatar_url", "creator_id", "avatar"},
+	{"characters", "voice_url", "creator_id", "audio"},
+	{"postcards", "image_url", "user_id", "image"},
+	{"postcards", "voice_url", "user_id", "audio"},
+	{"drafts", "landscape_image_url", "user_id", "image"},
+}
+
+// BackfillUploadOwners 为引入上传记录之前保存的文件补建上传记录，上传者按引用该文件的记录确定
+// 否则这些文件的所有者再次保存引用它们的记录时会因没有上传记录而被拒绝
+func (s *UploadService) BackfillUploadOwners() {
+	created := 0
+	for _, owner := range uploadOwners {
+		var rows []struct {
+			URL     string
+			OwnerID uint
+		}
+		if err := s.db.Table(owner.table).
+			Select(fmt.Sprintf("%s AS url, MIN(%s) AS owner_id", owner.column, owner.ownerColumn)).
+			Where(owner.column + " <> ''").
+			Group(owner.column).
+			Scan(&rows).Error; err != nil {
+			log.Printf("Failed to load %s.%s for upload backfill: %v", owner.table, owner.column, err)
+			continue
+		}
+
+		for _, row := range rows {
+			objectName, ok := s.ObjectNameFromURL(row.URL)
+			if !ok {
+				continue
+			}
+			result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.Upload{
+				UserID:    row.OwnerID,
+				ObjectKey: objectName,
+				Kind:      owner.kind,
+			})
+			if result.Error != nil {
+				log.Printf("Failed to backfill upload %s: %v", objectName, result.Error)
+				continue
+			}
+			created += int(result.RowsAffected)
+		}
+	}
+
+	if created > 0 {
+		log.Printf("Backfilled %d upload records for existing files", created)
+	}
+}
+
+// referencedObjects 收集所有被引用的存储桶对象名称
+func (s *UploadService) referencedObjects() (map[string]bool, error) {
+	referenced := make(map[string]bool)
+	for _, ref := range uploadReferences {
+		var urls []string
+		if err := s.db.Unscoped().Model(ref.model).
+			Where(ref.column+" <> ''").
+			Distinct().
+			Pluck(ref.column, &urls).Error; err != nil {
+			return nil, fmt.Errorf("failed to get referenced files: %w", err)
+		}
+		for _, url := range urls {
+			if objectName, ok := s.ObjectNameFromURL(url); ok {
+				referenced[objectName] = true
+			}
+		}
+	}
+	return referenced, nil
+}
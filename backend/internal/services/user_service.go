@@ -14,17 +14,22 @@ import (
 	"gorm.io/gorm"
 )
 
+// defaultTimeZone 用户未设置时区时使用的时区
+const defaultTimeZone = "Asia/Shanghai"
+
 type UserService struct {
-	db     *gorm.DB
-	redis  *redis.Client
-	config *config.Config
+	db            *gorm.DB
+	redis         *redis.Client
+	uploadService *UploadService
+	config        *config.Config
 }
 
-func NewUserService(db *gorm.DB, redis *redis.Client, cfg *config.Config) *UserService {
+func NewUserService(db *gorm.DB, redis *redis.Client, uploadService *UploadService, cfg *config.Config) *UserService {
 	return &UserService{
-		db:     db,
-		redis:  redis,
-		config: cfg,
+		db:            db,
+		redis:         redis,
+		uploadService: uploadService,
+		config:        cfg,
 	}
 }
 
@@ -123,7 +128,10 @@ func (s *UserService) UpdateProfile(userID uint, req *models.UserUpdateRequest)
 	if req.Nickname != "" {
 		user.Nickname = req.Nickname
 	}
-	if req.AvatarURL != "" {
+	if req.AvatarURL != "" && req.AvatarURL != user.AvatarURL {
+		if err := s.uploadService.VerifyOwnership(userID, req.AvatarURL); err != nil {
+			return nil, err
+		}
 		user.AvatarURL = req.AvatarURL
 	}
 	if req.Signature != "" {
@@ -132,6 +140,12 @@ func (s *UserService) UpdateProfile(userID uint, req *models.UserUpdateRequest)
 	if req.Language != "" {
 		user.Language = req.Language
 	}
+	if req.TimeZone != "" {
+		if _, err := time.LoadLocation(req.TimeZone); err != nil || req.TimeZone == "Local" {
+			return nil, errors.New("invalid time zone")
+		}
+		user.TimeZone = req.TimeZone
+	}
 	if req.FontSize != "" {
 		user.FontSize = req.FontSize
 	}
@@ -149,6 +163,28 @@ func (s *UserService) UpdateProfile(userID uint, req *models.UserUpdateRequest)
 	return s.toUserResponse(&user), nil
 }
 
+// GetLocation 获取用户所在时区，未设置或无法识别时使用默认时区
+func (s *UserService) GetLocation(userID uint) *time.Location {
+	name := defaultTimeZone
+	if user, err := s.GetProfile(userID); err == nil && user.TimeZone != "" {
+		name = user.TimeZone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		loc, _ = time.LoadLocation(defaultTimeZone)
+	}
+	return loc
+}
+
+// IsAdmin 检查用户是否为管理员
+func (s *UserService) IsAdmin(userID uint) bool {
+	var user models.User
+	if err := s.db.Select("id", "role").First(&user, userID).Error; err != nil {
+		return false
+	}
+	return user.Role == "admin"
+}
+
 // cacheUser 缓存用户信息到 Redis
 func (s *UserService) cacheUser(user *models.User) {
 	ctx := context.Background()
@@ -186,8 +222,10 @@ func (s *UserService) toUserResponse(user *models.User) *models.UserResponse {
 		AvatarURL: user.AvatarURL,
 		Signature: user.Signature,
 		Language:  user.Language,
+		TimeZone:  user.TimeZone,
 		FontSize:  user.FontSize,
 		DarkMode:  user.DarkMode,
+		Role:      user.Role,
 		CreatedAt: user.CreatedAt,
 		UpdatedAt: user.UpdatedAt,
 	}
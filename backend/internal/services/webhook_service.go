@@ -0,0 +1,559 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"memory-postcard-backend/config"
+	"memory-postcard-backend/internal/models"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Webhook 事件
+const (
+	WebhookPostcardCreated   = "postcard.created"    // 用户寄出明信片
+	WebhookPostcardAIReplied = "postcard.ai_replied" // 角色回信
+	WebhookCharacterUpdated  = "character.updated"   // 用户创建的角色被修改
+	WebhookPing              = "ping"                // 测试推送，不需要订阅
+)
+
+// WebhookEvents 用户可以订阅的事件
+var WebhookEvents = []string{WebhookPostcardCreated, WebhookPostcardAIReplied, WebhookCharacterUpdated}
+
+const (
+	maxWebhooksPerUser       = 10
+	webhookWorkers           = 4
+	webhookBatchSize         = 100
+	webhookRetryBaseDelay    = 30 * time.Second
+	webhookRetryMaxDelay     = 6 * time.Hour
+	webhookResponseBodyLimit = 2048
+	webhookSignatureHeader   = "X-Webhook-Signature"
+)
+
+var errWebhookDisabled = errors.New("webhook disabled")
+
+// webhookEnvelope 推送的请求体
+type webhookEnvelope struct {
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+type WebhookService struct {
+	db          *gorm.DB
+	client      *http.Client
+	timeout     time.Duration
+	maxAttempts int
+	workers     chan struct{}
+}
+
+func NewWebhookService(db *gorm.DB, cfg *config.Config) *WebhookService {
+	maxAttempts := cfg.WebhookMaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return &WebhookService{
+		db:          db,
+		client:      newOutboundClient(cfg.WebhookTimeout, cfg.WebhookAllowPrivateNetworks),
+		timeout:     cfg.WebhookTimeout,
+		maxAttempts: maxAttempts,
+		workers:     make(chan struct{}, webhookWorkers),
+	}
+}
+
+// ListWebhooks 获取用户的 Webhook
+func (s *WebhookService) ListWebhooks(userID uint) ([]models.WebhookEndpoint, error) {
+	var endpoints []models.WebhookEndpoint
+	if err := s.db.Where("user_id = ?", userID).Order("id ASC").Find(&endpoints).Error; err != nil {
+		return nil, fmt.Errorf("failed to get webhooks: %w", err)
+	}
+	return endpoints, nil
+}
+
+// CreateWebhook 注册 Webhook，返回的签名密钥只在创建时提供
+func (s *WebhookService) CreateWebhook(userID uint, req *models.WebhookCreateRequest) (*models.WebhookCreateResponse, error) {
+	if err := validateWebhookURL(req.URL); err != nil {
+		return nil, err
+	}
+	events, err := normalizeWebhookEvents(req.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	var count int64
+	if err := s.db.Model(&models.WebhookEndpoint{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to count webhooks: %w", err)
+	}
+	if count >= maxWebhooksPerUser {
+		return nil, errors.New("too many webhooks")
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := models.WebhookEndpoint{
+		UserID:      userID,
+		URL:         req.URL,
+		Secret:      secret,
+		Events:      events,
+		Description: req.Description,
+		Enabled:     true,
+	}
+	if err := s.db.Create(&endpoint).Error; err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	return &models.WebhookCreateResponse{WebhookEndpoint: endpoint, Secret: secret}, nil
+}
+
+// UpdateWebhook 更新 Webhook，未提供的字段保持不变
+func (s *WebhookService) UpdateWebhook(id, userID uint, req *models.WebhookUpdateRequest) (*models.WebhookEndpoint, error) {
+	endpoint, err := s.getWebhook(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.URL != nil {
+		if err := validateWebhookURL(*req.URL); err != nil {
+			return nil, err
+		}
+		endpoint.URL = *req.URL
+	}
+	if req.Events != nil {
+		events, err := normalizeWebhookEvents(req.Events)
+		if err != nil {
+			return nil, err
+		}
+		endpoint.Events = events
+	}
+	if req.Description != nil {
+		endpoint.Description = *req.Description
+	}
+	if req.Enabled != nil {
+		endpoint.Enabled = *req.Enabled
+	}
+
+	if err := s.db.Save(endpoint).Error; err != nil {
+		return nil, fmt.Errorf("failed to update webhook: %w", err)
+	}
+	return endpoint, nil
+}
+
+// DeleteWebhook 删除 Webhook 及其推送记录
+func (s *WebhookService) DeleteWebhook(id, userID uint) error {
+	endpoint, err := s.getWebhook(id, userID)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("endpoint_id = ?", endpoint.ID).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return fmt.Errorf("failed to delete webhook deliveries: %w", err)
+		}
+		if err := tx.Delete(endpoint).Error; err != nil {
+			return fmt.Errorf("failed to delete webhook: %w", err)
+		}
+		return nil
+	})
+}
+
+// RotateSecret 重新生成签名密钥，旧密钥立即失效
+func (s *WebhookService) RotateSecret(id, userID uint) (*models.WebhookCreateResponse, error) {
+	endpoint, err := s.getWebhook(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(endpoint).Update("secret", secret).Error; err != nil {
+		return nil, fmt.Errorf("failed to rotate webhook secret: %w", err)
+	}
+
+	return &models.WebhookCreateResponse{WebhookEndpoint: *endpoint, Secret: secret}, nil
+}
+
+// PingWebhook 向 Webhook 发送一次测试推送，同步返回推送结果
+func (s *WebhookService) PingWebhook(id, userID uint) (*models.WebhookDelivery, error) {
+	endpoint, err := s.getWebhook(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, eventID, err := buildWebhookPayload(WebhookPing, map[string]interface{}{"webhook_id": endpoint.ID})
+	if err != nil {
+		return nil, err
+	}
+	delivery, err := s.createDelivery(endpoint, WebhookPing, eventID, payload, nil)
+	if err != nil {
+		return nil, err
+	}
+	return s.deliverNow(delivery.ID)
+}
+
+// ListDeliveries 获取 Webhook 的推送记录，从新到旧排列
+func (s *WebhookService) ListDeliveries(id, userID uint, query *models.WebhookDeliveryListQuery) (*models.PaginatedResponse, error) {
+	endpoint, err := s.getWebhook(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	db := s.db.Model(&models.WebhookDelivery{}).Where("endpoint_id = ?", endpoint.ID)
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+	if query.Event != "" {
+		db = db.Where("event = ?", query.Event)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count webhook deliveries: %w", err)
+	}
+
+	var deliveries []models.WebhookDelivery
+	offset := (query.Page - 1) * query.PageSize
+	if err := db.Order("id DESC").Offset(offset).Limit(query.PageSize).Find(&deliveries).Error; err != nil {
+		return nil, fmt.Errorf("failed to get webhook deliveries: %w", err)
+	}
+
+	return &models.PaginatedResponse{
+		Items:      deliveries,
+		Total:      total,
+		Page:       query.Page,
+		PageSize:   query.PageSize,
+		TotalPages: int((total + int64(query.PageSize) - 1) / int64(query.PageSize)),
+	}, nil
+}
+
+// Redeliver 以原事件 ID 和请求体重新推送，生成新的推送记录并同步返回结果
+func (s *WebhookService) Redeliver(id, deliveryID, userID uint) (*models.WebhookDelivery, error) {
+	endpoint, err := s.getWebhook(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var original models.WebhookDelivery
+	if err := s.db.Where("id = ? AND endpoint_id = ?", deliveryID, endpoint.ID).First(&original).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("webhook delivery not found")
+		}
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+
+	delivery, err := s.createDelivery(endpoint, original.Event, original.EventID, original.Payload, &original.ID)
+	if err != nil {
+		return nil, err
+	}
+	return s.deliverNow(delivery.ID)
+}
+
+// Publish 向用户订阅了该事件的 Webhook 推送事件，推送在后台进行
+func (s *WebhookService) Publish(userID uint, event string, data interface{}) {
+	var endpoints []models.WebhookEndpoint
+	if err := s.db.Where("user_id = ? AND enabled = ?", userID, true).Find(&endpoints).Error; err != nil {
+		log.Printf("Failed to get webhooks for user %d: %v", userID, err)
+		return
+	}
+
+	var subscribed []models.WebhookEndpoint
+	for _, endpoint := range endpoints {
+		for _, e := range endpoint.Events {
+			if e == event {
+				subscribed = append(subscribed, endpoint)
+				break
+			}
+		}
+	}
+	if len(subscribed) == 0 {
+		return
+	}
+
+	payload, eventID, err := buildWebhookPayload(event, data)
+	if err != nil {
+		log.Printf("Failed to build webhook payload for %s: %v", event, err)
+		return
+	}
+
+	for i := range subscribed {
+		delivery, err := s.createDelivery(&subscribed[i], event, eventID, payload, nil)
+		if err != nil {
+			log.Printf("Failed to create webhook delivery: %v", err)
+			continue
+		}
+		go s.deliver(delivery.ID)
+	}
+}
+
+// StartWebhookJob 定期重试到期的推送
+func (s *WebhookService) StartWebhookJob(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.RunDueDeliveries()
+			}
+		}
+	}()
+}
+
+// RunDueDeliveries 推送所有到期的待推送记录
+func (s *WebhookService) RunDueDeliveries() {
+	var ids []uint
+	if err := s.db.Model(&models.WebhookDelivery{}).
+		Where("status = ? AND next_attempt_at <= ?", "pending", time.Now()).
+		Order("next_attempt_at ASC").
+		Limit(webhookBatchSize).
+		Pluck("id", &ids).Error; err != nil {
+		log.Printf("Failed to get due webhook deliveries: %v", err)
+		return
+	}
+
+	for _, id := range ids {
+		go s.deliver(id)
+	}
+}
+
+func (s *WebhookService) getWebhook(id, userID uint) (*models.WebhookEndpoint, error) {
+	var endpoint models.WebhookEndpoint
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&endpoint).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("webhook not found")
+		}
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	return &endpoint, nil
+}
+
+func (s *WebhookService) createDelivery(endpoint *models.WebhookEndpoint, event, eventID, payload string, redeliveryOf *uint) (*models.WebhookDelivery, error) {
+	now := time.Now()
+	delivery := models.WebhookDelivery{
+		EndpointID:    endpoint.ID,
+		UserID:        endpoint.UserID,
+		EventID:       eventID,
+		Event:         event,
+		Payload:       payload,
+		Status:        "pending",
+		NextAttemptAt: &now,
+		RedeliveryOf:  redeliveryOf,
+	}
+	if err := s.db.Create(&delivery).Error; err != nil {
+		return nil, fmt.Errorf("failed to create webhook delivery: %w", err)
+	}
+	return &delivery, nil
+}
+
+// deliverNow 立即推送并返回推送后的记录
+func (s *WebhookService) deliverNow(deliveryID uint) (*models.WebhookDelivery, error) {
+	s.deliver(deliveryID)
+
+	var delivery models.WebhookDelivery
+	if err := s.db.First(&delivery, deliveryID).Error; err != nil {
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+	return &delivery, nil
+}
+
+// deliver 推送一次，失败时安排下次重试；先占用记录，避免多个实例重复推送
+func (s *WebhookService) deliver(deliveryID uint) {
+	s.workers <- struct{}{}
+	defer func() { <-s.workers }()
+
+	// 数据库时间精度为毫秒，比较时留出余量，避免刚创建的记录因舍入无法被占用
+	now := time.Now()
+	lease := now.Add(2*s.timeout + time.Minute)
+	result := s.db.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", deliveryID, "pending", now.Add(time.Second)).
+		UpdateColumn("next_attempt_at", lease)
+	if result.Error != nil {
+		log.Printf("Failed to claim webhook delivery %d: %v", deliveryID, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		return
+	}
+
+	var delivery models.WebhookDelivery
+	if err := s.db.First(&delivery, deliveryID).Error; err != nil {
+		log.Printf("Failed to load webhook delivery %d: %v", deliveryID, err)
+		return
+	}
+
+	var endpoint models.WebhookEndpoint
+	var status int
+	var body string
+	var err error
+	start := time.Now()
+	if err = s.db.First(&endpoint, delivery.EndpointID).Error; err == nil {
+		if endpoint.Enabled || delivery.Event == WebhookPing || delivery.RedeliveryOf != nil {
+			status, body, err = s.send(&endpoint, &delivery)
+		} else {
+			err = errWebhookDisabled
+		}
+	}
+	duration := time.Since(start)
+
+	attempts := delivery.Attempts + 1
+	updates := map[string]interface{}{
+		"attempts":        attempts,
+		"response_status": status,
+		"response_body":   body,
+		"duration_ms":     duration.Milliseconds(),
+	}
+	switch {
+	case err == nil:
+		finished := time.Now()
+		updates["status"] = "succeeded"
+		updates["error"] = ""
+		updates["next_attempt_at"] = nil
+		updates["delivered_at"] = &finished
+	case attempts >= s.maxAttempts || errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, errWebhookDisabled):
+		updates["status"] = "failed"
+		updates["error"] = truncateRunes(err.Error(), 500)
+		updates["next_attempt_at"] = nil
+	default:
+		next := time.Now().Add(webhookRetryDelay(attempts))
+		updates["error"] = truncateRunes(err.Error(), 500)
+		updates["next_attempt_at"] = &next
+	}
+
+	if err := s.db.Model(&delivery).Updates(updates).Error; err != nil {
+		log.Printf("Failed to update webhook delivery %d: %v", deliveryID, err)
+	}
+}
+
+// send 发送签名后的推送请求，返回响应状态码和响应内容，非 2xx 响应视为失败
+func (s *WebhookService) send(endpoint *models.WebhookEndpoint, delivery *models.WebhookDelivery) (int, string, error) {
+	req, err := http.NewRequest(http.MethodPost, endpoint.URL, bytes.NewBufferString(delivery.Payload))
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "MemoryPostcard-Webhook/1.0")
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-ID", delivery.EventID)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(webhookSignatureHeader, signWebhookPayload(endpoint.Secret, timestamp, []byte(delivery.Payload)))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseBodyLimit))
+	text := string(body)
+	if !utf8.ValidString(text) {
+		text = ""
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, text, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, text, nil
+}
+
+// signWebhookPayload 生成签名头：t=时间戳,v1=HMAC-SHA256(密钥, "时间戳.请求体") 的十六进制
+// 接收方应使用相同方式计算并比较，同时检查时间戳以防止重放
+func signWebhookPayload(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(payload)
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// webhookRetryDelay 第 attempts 次推送失败后的重试间隔，从 30 秒开始每次翻倍，最长 6 小时
+func webhookRetryDelay(attempts int) time.Duration {
+	delay := webhookRetryBaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= webhookRetryMaxDelay {
+			return webhookRetryMaxDelay
+		}
+	}
+	return delay
+}
+
+func buildWebhookPayload(event string, data interface{}) (string, string, error) {
+	eventID := uuid.New().String()
+	payload, err := json.Marshal(webhookEnvelope{
+		ID:        eventID,
+		Event:     event,
+		CreatedAt: time.Now(),
+		Data:      data,
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+	return string(payload), eventID, nil
+}
+
+func validateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("invalid webhook url")
+	}
+	return nil
+}
+
+// normalizeWebhookEvents 校验订阅的事件并去重
+func normalizeWebhookEvents(events []string) ([]string, error) {
+	known := make(map[string]bool, len(WebhookEvents))
+	for _, e := range WebhookEvents {
+		known[e] = true
+	}
+
+	seen := make(map[string]bool, len(events))
+	var result []string
+	for _, e := range events {
+		if !known[e] {
+			return nil, errors.New("invalid webhook event")
+		}
+		if !seen[e] {
+			seen[e] = true
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) > n {
+		return string([]rune(s)[:n])
+	}
+	return s
+}
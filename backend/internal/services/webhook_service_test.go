@@ -0,0 +1,163 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"memory-postcard-backend/internal/models"
+)
+
+// verifySignature 按接收方的方式校验签名头
+func verifySignature(secret, header string, body []byte) bool {
+	var timestamp, signature string
+	for _, part := range strings.Split(header, ",") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return false
+		}
+		switch kv[0] {
+		case "t":
+			timestamp = kv[1]
+		case "v1":
+			signature = kv[1]
+		}
+	}
+	if _, err := strconv.ParseInt(timestamp, 10, 64); err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s.", timestamp)
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+func TestWebhookSendSignsPayload(t *testing.T) {
+	const secret = "whsec_test"
+	payload := `{"id":"evt","event":"postcard.created","data":{"id":1}}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != payload {
+			t.Errorf("body = %q, want %q", body, payload)
+		}
+		if got := r.Header.Get("X-Webhook-Event"); got != "postcard.created" {
+			t.Errorf("X-Webhook-Event = %q", got)
+		}
+		if got := r.Header.Get("X-Webhook-ID"); got != "evt" {
+			t.Errorf("X-Webhook-ID = %q", got)
+		}
+		if !verifySignature(secret, r.Header.Get(webhookSignatureHeader), body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	s := &WebhookService{client: newOutboundClient(time.Second, true)}
+	endpoint := &models.WebhookEndpoint{URL: server.URL, Secret: secret}
+	delivery := &models.WebhookDelivery{ID: 7, EventID: "evt", Event: "postcard.created", Payload: payload}
+
+	status, body, err := s.send(endpoint, delivery)
+	if err != nil {
+		t.Fatalf("send() error = %v", err)
+	}
+	if status != http.StatusOK || body != "ok" {
+		t.Errorf("send() = %d %q, want 200 \"ok\"", status, body)
+	}
+
+	// 密钥不一致时接收方拒绝，视为推送失败
+	endpoint.Secret = "whsec_wrong"
+	status, _, err = s.send(endpoint, delivery)
+	if err == nil || status != http.StatusUnauthorized {
+		t.Errorf("send() with wrong secret = %d, %v; want 401 and an error", status, err)
+	}
+}
+
+func TestWebhookSendFailures(t *testing.T) {
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://example.com/", http.StatusFound)
+	}))
+	defer redirect.Close()
+
+	serverError := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer serverError.Close()
+
+	s := &WebhookService{client: newOutboundClient(time.Second, true)}
+	delivery := &models.WebhookDelivery{Event: "ping", Payload: "{}"}
+
+	tests := []struct {
+		name   string
+		url    string
+		status int
+	}{
+		{"redirect is not followed", redirect.URL, http.StatusFound},
+		{"server error", serverError.URL, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, _, err := s.send(&models.WebhookEndpoint{URL: tt.url, Secret: "s"}, delivery)
+			if err == nil || status != tt.status {
+				t.Errorf("send() = %d, %v; want %d and an error", status, err, tt.status)
+			}
+		})
+	}
+}
+
+func TestWebhookClientBlocksPrivateNetworks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request to loopback address should be blocked")
+	}))
+	defer server.Close()
+
+	s := &WebhookService{client: newOutboundClient(time.Second, false)}
+	_, _, err := s.send(&models.WebhookEndpoint{URL: server.URL, Secret: "s"}, &models.WebhookDelivery{Payload: "{}"})
+	if err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Errorf("send() error = %v, want address not allowed", err)
+	}
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{7, 32 * time.Minute},
+		{20, 6 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := webhookRetryDelay(tt.attempts); got != tt.want {
+			t.Errorf("webhookRetryDelay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeWebhookEvents(t *testing.T) {
+	events, err := normalizeWebhookEvents([]string{WebhookPostcardCreated, WebhookCharacterUpdated, WebhookPostcardCreated})
+	if err != nil {
+		t.Fatalf("normalizeWebhookEvents() error = %v", err)
+	}
+	if len(events) != 2 {
+		t.Errorf("normalizeWebhookEvents() = %v, want duplicates removed", events)
+	}
+
+	if _, err := normalizeWebhookEvents([]string{WebhookPing}); err == nil {
+		t.Error("normalizeWebhookEvents() should reject events that cannot be subscribed")
+	}
+}
@@ -0,0 +1,245 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// MMToPt 毫米转换为 PDF 点（1/72 英寸）
+func MMToPt(mm float64) float64 {
+	return mm * 72 / 25.4
+}
+
+// PDFBox PDF 中的矩形区域（单位为点，原点在左下角）
+type PDFBox struct {
+	X, Y, W, H float64
+}
+
+func (b PDFBox) String() string {
+	return fmt.Sprintf("[%.2f %.2f %.2f %.2f]", b.X, b.Y, b.X+b.W, b.Y+b.H)
+}
+
+// PDFImage 放置在页面上的 JPEG 图片
+type PDFImage struct {
+	JPEG          []byte
+	Width, Height int // 像素尺寸
+	Box           PDFBox
+}
+
+// PDFLine 页面上的线段，用于裁切线等标记
+type PDFLine struct {
+	X1, Y1, X2, Y2 float64
+	Width          float64
+}
+
+// PDFLink 页面内指向其他页的链接，用于目录
+type PDFLink struct {
+	Box        PDFBox
+	TargetPage int // 从 0 开始的页码
+}
+
+// PDFPage 单个页面，TrimBox/BleedBox 为空时与页面尺寸一致
+type PDFPage struct {
+	Width, Height float64
+	TrimBox       *PDFBox
+	BleedBox      *PDFBox
+	Images        []PDFImage
+	Lines         []PDFLine
+	Links         []PDFLink
+}
+
+type pdfPageRecord struct {
+	page    PDFPage
+	content int
+	images  []int
+}
+
+// PDFWriter 生成只包含图片和线段的 PDF。
+// 图片和内容流在 AddPage 时立即写出，页面对象在 Close 时统一写出，因此目录链接可以指向后面的页面
+type PDFWriter struct {
+	w       *bufio.Writer
+	offset  int64
+	offsets map[int]int64
+	nextID  int
+	pages   []pdfPageRecord
+}
+
+// NewPDFWriter 创建 PDF 写入器并写出文件头
+func NewPDFWriter(w io.Writer) (*PDFWriter, error) {
+	p := &PDFWriter{
+		w:       bufio.NewWriter(w),
+		offsets: make(map[int]int64),
+		nextID:  1,
+	}
+	if err := p.write("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n"); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// PageCount 已添加的页数
+func (p *PDFWriter) PageCount() int {
+	return len(p.pages)
+}
+
+func (p *PDFWriter) write(s string) error {
+	n, err := p.w.WriteString(s)
+	p.offset += int64(n)
+	return err
+}
+
+func (p *PDFWriter) writeBytes(b []byte) error {
+	n, err := p.w.Write(b)
+	p.offset += int64(n)
+	return err
+}
+
+func (p *PDFWriter) allocID() int {
+	id := p.nextID
+	p.nextID++
+	return id
+}
+
+// writeObject 写出一个字典对象
+func (p *PDFWriter) writeObject(id int, dict string) error {
+	p.offsets[id] = p.offset
+	return p.write(fmt.Sprintf("%d 0 obj\n%s\nendobj\n", id, dict))
+}
+
+// writeStream 写出一个流对象
+func (p *PDFWriter) writeStream(id int, dict string, data []byte) error {
+	p.offsets[id] = p.offset
+	if err := p.write(fmt.Sprintf("%d 0 obj\n<< %s /Length %d >>\nstream\n", id, dict, len(data))); err != nil {
+		return err
+	}
+	if err := p.writeBytes(data); err != nil {
+		return err
+	}
+	return p.write("\nendstream\nendobj\n")
+}
+
+// AddPage 添加页面
+func (p *PDFWriter) AddPage(page PDFPage) error {
+	record := pdfPageRecord{page: page}
+
+	var content strings.Builder
+	for i, img := range page.Images {
+		id := p.allocID()
+		dict := fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /DCTDecode", img.Width, img.Height)
+		if err := p.writeStream(id, dict, img.JPEG); err != nil {
+			return err
+		}
+		record.images = append(record.images, id)
+		fmt.Fprintf(&content, "q %.2f 0 0 %.2f %.2f %.2f cm /Im%d Do Q\n", img.Box.W, img.Box.H, img.Box.X, img.Box.Y, i)
+	}
+	for _, line := range page.Lines {
+		fmt.Fprintf(&content, "0 G %.2f w %.2f %.2f m %.2f %.2f l S\n", line.Width, line.X1, line.Y1, line.X2, line.Y2)
+	}
+
+	record.content = p.allocID()
+	if err := p.writeStream(record.content, "", []byte(content.String())); err != nil {
+		return err
+	}
+
+	p.pages = append(p.pages, record)
+	return nil
+}
+
+// Close 写出页面树、目录和交叉引用表
+func (p *PDFWriter) Close(title string) error {
+	pagesID := p.allocID()
+	pageIDs := make([]int, len(p.pages))
+	for i := range p.pages {
+		pageIDs[i] = p.allocID()
+	}
+
+	for i, record := range p.pages {
+		page := record.page
+		mediaBox := PDFBox{W: page.Width, H: page.Height}
+
+		var dict strings.Builder
+		fmt.Fprintf(&dict, "<< /Type /Page /Parent %d 0 R /MediaBox %s", pagesID, mediaBox)
+		if page.TrimBox != nil {
+			fmt.Fprintf(&dict, " /TrimBox %s", page.TrimBox)
+		}
+		if page.BleedBox != nil {
+			fmt.Fprintf(&dict, " /BleedBox %s", page.BleedBox)
+		}
+		dict.WriteString(" /Resources << /XObject <<")
+		for j, id := range record.images {
+			fmt.Fprintf(&dict, " /Im%d %d 0 R", j, id)
+		}
+		fmt.Fprintf(&dict, " >> >> /Contents %d 0 R", record.content)
+
+		var annots []string
+		for _, link := range page.Links {
+			if link.TargetPage < 0 || link.TargetPage >= len(pageIDs) {
+				continue
+			}
+			id := p.allocID()
+			annot := fmt.Sprintf("<< /Type /Annot /Subtype /Link /Rect %s /Border [0 0 0] /Dest [%d 0 R /Fit] >>", link.Box, pageIDs[link.TargetPage])
+			if err := p.writeObject(id, annot); err != nil {
+				return err
+			}
+			annots = append(annots, fmt.Sprintf("%d 0 R", id))
+		}
+		if len(annots) > 0 {
+			fmt.Fprintf(&dict, " /Annots [%s]", strings.Join(annots, " "))
+		}
+		dict.WriteString(" >>")
+
+		if err := p.writeObject(pageIDs[i], dict.String()); err != nil {
+			return err
+		}
+	}
+
+	kids := make([]string, len(pageIDs))
+	for i, id := range pageIDs {
+		kids[i] = fmt.Sprintf("%d 0 R", id)
+	}
+	if err := p.writeObject(pagesID, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pageIDs))); err != nil {
+		return err
+	}
+
+	catalogID := p.allocID()
+	if err := p.writeObject(catalogID, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesID)); err != nil {
+		return err
+	}
+	infoID := p.allocID()
+	if err := p.writeObject(infoID, fmt.Sprintf("<< /Title %s /Producer (Memory Postcard) >>", pdfTextString(title))); err != nil {
+		return err
+	}
+
+	// 交叉引用表
+	xrefOffset := p.offset
+	if err := p.write(fmt.Sprintf("xref\n0 %d\n0000000000 65535 f \n", p.nextID)); err != nil {
+		return err
+	}
+	for id := 1; id < p.nextID; id++ {
+		if err := p.write(fmt.Sprintf("%010d 00000 n \n", p.offsets[id])); err != nil {
+			return err
+		}
+	}
+	if err := p.write(fmt.Sprintf("trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", p.nextID, catalogID, infoID, xrefOffset)); err != nil {
+		return err
+	}
+	return p.w.Flush()
+}
+
+// pdfTextString 将文本编码为 UTF-16BE 十六进制字符串，支持中文标题
+func pdfTextString(s string) string {
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, r := range s {
+		if r > 0xFFFF {
+			r -= 0x10000
+			fmt.Fprintf(&b, "%04X%04X", 0xD800+(r>>10), 0xDC00+(r&0x3FF))
+			continue
+		}
+		fmt.Fprintf(&b, "%04X", r)
+	}
+	b.WriteString(">")
+	return b.String()
+}
@@ -0,0 +1,277 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// LoadFont 加载 TTF/OTF/TTC 字体，路径为空时使用内置的 Go Regular 字体（不含中文字形）
+func LoadFont(path string) (*opentype.Font, error) {
+	if path == "" {
+		return opentype.Parse(goregular.TTF)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read font: %w", err)
+	}
+
+	if f, err := opentype.Parse(data); err == nil {
+		return f, nil
+	}
+
+	// 字体集合（如 NotoSansCJK-Regular.ttc）取第一个字体
+	collection, err := opentype.ParseCollection(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse font: %w", err)
+	}
+	return collection.Font(0)
+}
+
+// NewFontFace 按像素大小创建字体外观，返回的 Face 不能并发使用
+func NewFontFace(f *opentype.Font, size float64) (font.Face, error) {
+	return opentype.NewFace(f, &opentype.FaceOptions{
+		Size:    size,
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+}
+
+// MeasureText 计算文本绘制宽度（像素）
+func MeasureText(face font.Face, text string) int {
+	return font.MeasureString(face, text).Ceil()
+}
+
+// DrawText 以 (x, y) 为基线起点绘制单行文本
+func DrawText(dst draw.Image, face font.Face, c color.Color, x, y int, text string) {
+	d := &font.Drawer{
+		Dst:  dst,
+		Src:  image.NewUniform(c),
+		Face: face,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(text)
+}
+
+// lineStartForbidden 不能出现在行首的标点（避头规则）
+const lineStartForbidden = "，。！？、；：）》」』】〉”’…—·,.!?;:)]}%"
+
+// isCJK 判断字符是否按单字断行
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		(r >= 0x3000 && r <= 0x303F) || (r >= 0xFF00 && r <= 0xFFEF)
+}
+
+// splitWrapTokens 将段落切分为可断行单元：中日韩字符单字成词，西文按空格分词
+func splitWrapTokens(paragraph string) []string {
+	var tokens []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, string(word))
+			word = word[:0]
+		}
+	}
+
+	for _, r := range paragraph {
+		switch {
+		case unicode.IsSpace(r):
+			flush()
+			tokens = append(tokens, " ")
+		case isCJK(r):
+			flush()
+			tokens = append(tokens, string(r))
+		default:
+			word = append(word, r)
+		}
+	}
+	flush()
+	return tokens
+}
+
+// WrapText 按最大宽度折行，保留原有换行；中日韩文字可在任意字间断行，
+// 避头标点允许悬挂在行尾，超长的西文单词按字符拆分
+func WrapText(face font.Face, text string, maxWidth int) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		var line string
+		for _, token := range splitWrapTokens(paragraph) {
+			if MeasureText(face, line+token) <= maxWidth {
+				line += token
+				continue
+			}
+			if token == " " {
+				lines = append(lines, strings.TrimRight(line, " "))
+				line = ""
+				continue
+			}
+			if line != "" && strings.Contains(lineStartForbidden, token) {
+				line += token
+				continue
+			}
+			if line != "" {
+				lines = append(lines, strings.TrimRight(line, " "))
+				line = ""
+			}
+			// 单个单词超过整行宽度时逐字符拆分
+			for _, r := range token {
+				if line != "" && MeasureText(face, line+string(r)) > maxWidth {
+					lines = append(lines, line)
+					line = ""
+				}
+				line += string(r)
+			}
+		}
+		lines = append(lines, strings.TrimRight(line, " "))
+	}
+	return lines
+}
+
+// TruncateText 将单行文本截断到最大宽度以内，被截断时末尾加省略号
+func TruncateText(face font.Face, text string, maxWidth int) string {
+	if MeasureText(face, text) <= maxWidth {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 && MeasureText(face, string(runes)+"…") > maxWidth {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…"
+}
+
+// TruncateLines 限制行数，被截断时在最后一行末尾加省略号
+func TruncateLines(face font.Face, lines []string, maxLines, maxWidth int) []string {
+	if maxLines <= 0 {
+		return nil
+	}
+	if len(lines) <= maxLines {
+		return lines
+	}
+
+	lines = lines[:maxLines]
+	last := []rune(lines[maxLines-1])
+	for len(last) > 0 && MeasureText(face, string(last)+"…") > maxWidth {
+		last = last[:len(last)-1]
+	}
+	lines[maxLines-1] = string(last) + "…"
+	return lines
+}
+
+// ParseHexColor 解析 #RRGGBB 格式的颜色
+func ParseHexColor(s string) (color.RGBA, error) {
+	if len(s) != 7 || s[0] != '#' {
+		return color.RGBA{}, fmt.Errorf("invalid color: %s", s)
+	}
+	v, err := strconv.ParseUint(s[1:], 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid color: %s", s)
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 255}, nil
+}
+
+// FillRect 填充矩形区域
+func FillRect(dst draw.Image, r image.Rectangle, c color.Color) {
+	draw.Draw(dst, r, image.NewUniform(c), image.Point{}, draw.Over)
+}
+
+// StrokeRect 绘制矩形边框，边框向内绘制
+func StrokeRect(dst draw.Image, r image.Rectangle, width int, c color.Color) {
+	FillRect(dst, image.Rect(r.Min.X, r.Min.Y, r.Max.X, r.Min.Y+width), c)
+	FillRect(dst, image.Rect(r.Min.X, r.Max.Y-width, r.Max.X, r.Max.Y), c)
+	FillRect(dst, image.Rect(r.Min.X, r.Min.Y+width, r.Min.X+width, r.Max.Y-width), c)
+	FillRect(dst, image.Rect(r.Max.X-width, r.Min.Y+width, r.Max.X, r.Max.Y-width), c)
+}
+
+// ringMask 圆环形状的遮罩，Inner 为 0 时为实心圆，边缘做 1 像素抗锯齿
+type ringMask struct {
+	Center       image.Point
+	Outer, Inner float64
+}
+
+func (m *ringMask) ColorModel() color.Model { return color.AlphaModel }
+
+func (m *ringMask) Bounds() image.Rectangle {
+	r := int(math.Ceil(m.Outer)) + 1
+	return image.Rect(m.Center.X-r, m.Center.Y-r, m.Center.X+r, m.Center.Y+r)
+}
+
+func (m *ringMask) At(x, y int) color.Color {
+	d := math.Hypot(float64(x-m.Center.X)+0.5, float64(y-m.Center.Y)+0.5)
+	coverage := math.Min(clamp01(m.Outer-d+0.5), clamp01(d-m.Inner+0.5))
+	if m.Inner <= 0 {
+		coverage = clamp01(m.Outer - d + 0.5)
+	}
+	return color.Alpha{A: uint8(coverage * 255)}
+}
+
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+// FillCircle 绘制实心圆
+func FillCircle(dst draw.Image, center image.Point, radius int, c color.Color) {
+	mask := &ringMask{Center: center, Outer: float64(radius)}
+	draw.DrawMask(dst, mask.Bounds(), image.NewUniform(c), image.Point{}, mask, mask.Bounds().Min, draw.Over)
+}
+
+// StrokeCircle 绘制圆环，width 为向内的线宽
+func StrokeCircle(dst draw.Image, center image.Point, radius, width int, c color.Color) {
+	mask := &ringMask{Center: center, Outer: float64(radius), Inner: float64(radius - width)}
+	draw.DrawMask(dst, mask.Bounds(), image.NewUniform(c), image.Point{}, mask, mask.Bounds().Min, draw.Over)
+}
+
+// coverRect 计算源图中与目标区域宽高比一致的居中裁剪区域
+func coverRect(src image.Rectangle, dstW, dstH int) image.Rectangle {
+	sw, sh := src.Dx(), src.Dy()
+	if sw*dstH > sh*dstW {
+		w := sh * dstW / dstH
+		x := src.Min.X + (sw-w)/2
+		return image.Rect(x, src.Min.Y, x+w, src.Max.Y)
+	}
+	h := sw * dstH / dstW
+	y := src.Min.Y + (sh-h)/2
+	return image.Rect(src.Min.X, y, src.Max.X, y+h)
+}
+
+// DrawImageCover 将图片等比缩放铺满目标区域，多余部分居中裁剪
+func DrawImageCover(dst draw.Image, r image.Rectangle, src image.Image) {
+	if r.Empty() || src.Bounds().Empty() {
+		return
+	}
+	xdraw.CatmullRom.Scale(dst, r, src, coverRect(src.Bounds(), r.Dx(), r.Dy()), draw.Over, nil)
+}
+
+// EncodeImage 将图片编码为 png 或 jpeg，返回数据和 Content-Type
+func EncodeImage(img image.Image, format string, quality int) ([]byte, string, error) {
+	var buf bytes.Buffer
+	switch format {
+	case "png":
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, "", fmt.Errorf("failed to encode png: %w", err)
+		}
+		return buf.Bytes(), "image/png", nil
+	case "jpeg", "jpg":
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+			return nil, "", fmt.Errorf("failed to encode jpeg: %w", err)
+		}
+		return buf.Bytes(), "image/jpeg", nil
+	default:
+		return nil, "", fmt.Errorf("unsupported image format: %s", format)
+	}
+}
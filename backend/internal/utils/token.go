@@ -0,0 +1,18 @@
+package utils
+
+import "unicode"
+
+// EstimateTokens 粗略估算文本的 token 数
+// 中日韩字符按每字 1 个 token 计算，其余字符按约 4 个字符 1 个 token 计算
+func EstimateTokens(text string) int {
+	cjk := 0
+	other := 0
+	for _, r := range text {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			cjk++
+		} else {
+			other++
+		}
+	}
+	return cjk + (other+3)/4
+}
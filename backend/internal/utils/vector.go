@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"sort"
+	"sync"
+)
+
+// VectorHit 向量检索结果
+type VectorHit struct {
+	ID    uint
+	Score float32 // 余弦相似度
+}
+
+// VectorIndex 向量索引接口
+type VectorIndex interface {
+	Add(id uint, vector []float32)
+	Remove(id uint)
+	Search(query []float32, k int) []VectorHit
+	Len() int
+}
+
+// BruteForceIndex 暴力检索的内存向量索引，适合单个用户规模的数据量
+type BruteForceIndex struct {
+	mu      sync.RWMutex
+	vectors map[uint][]float32
+}
+
+func NewBruteForceIndex() *BruteForceIndex {
+	return &BruteForceIndex{
+		vectors: make(map[uint][]float32),
+	}
+}
+
+// Add 添加或替换向量，向量会先归一化
+func (idx *BruteForceIndex) Add(id uint, vector []float32) {
+	normalized := Normalize(vector)
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.vectors[id] = normalized
+}
+
+// Remove 移除向量
+func (idx *BruteForceIndex) Remove(id uint) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	delete(idx.vectors, id)
+}
+
+// Search 返回与查询向量最相似的 k 个结果，按相似度从高到低排序
+func (idx *BruteForceIndex) Search(query []float32, k int) []VectorHit {
+	query = Normalize(query)
+
+	idx.mu.RLock()
+	hits := make([]VectorHit, 0, len(idx.vectors))
+	for id, vector := range idx.vectors {
+		if len(vector) != len(query) {
+			continue
+		}
+		hits = append(hits, VectorHit{ID: id, Score: dot(query, vector)})
+	}
+	idx.mu.RUnlock()
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score == hits[j].Score {
+			return hits[i].ID < hits[j].ID
+		}
+		return hits[i].Score > hits[j].Score
+	})
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits
+}
+
+// Len 返回索引中的向量数量
+func (idx *BruteForceIndex) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.vectors)
+}
+
+// Normalize 返回 L2 归一化后的向量副本
+func Normalize(vector []float32) []float32 {
+	var norm float64
+	for _, v := range vector {
+		norm += float64(v) * float64(v)
+	}
+	result := make([]float32, len(vector))
+	if norm == 0 {
+		return result
+	}
+	norm = math.Sqrt(norm)
+	for i, v := range vector {
+		result[i] = float32(float64(v) / norm)
+	}
+	return result
+}
+
+// EncodeVector 将向量编码为小端序 float32 字节串，用于数据库存储
+func EncodeVector(vector []float32) []byte {
+	buf := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(v))
+	}
+	return buf
+}
+
+// DecodeVector 解码 EncodeVector 编码的向量
+func DecodeVector(data []byte) ([]float32, error) {
+	if len(data)%4 != 0 {
+		return nil, errors.New("invalid vector data length")
+	}
+	vector := make([]float32, len(data)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:]))
+	}
+	return vector, nil
+}
+
+func dot(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
@@ -21,6 +21,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"memory-postcard-backend/config"
 	_ "memory-postcard-backend/docs"
@@ -28,6 +29,7 @@ import (
 	"memory-postcard-backend/internal/middleware"
 	"memory-postcard-backend/internal/routes"
 	"memory-postcard-backend/internal/services"
+	_ "time/tzdata" // 运行镜像中没有时区数据库，内嵌以支持用户时区
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -64,6 +66,9 @@ func main() {
 	// 初始化服务
 	services := services.NewServices(db, redisClient, minioClient, cfg)
 
+	// 启动后台任务
+	services.StartBackgroundJobs(context.Background(), cfg)
+
 	// 设置 Gin 模式
 	if cfg.Environment == "production" {
 		gin.SetMode(gin.ReleaseMode)